@@ -0,0 +1,231 @@
+package worldmap
+
+import (
+	"slices"
+
+	"github.com/GooLuck/WorldMap/internal/worldmap/geo"
+)
+
+// AoiEventType AOI事件类型
+type AoiEventType int32
+
+const (
+	AoiEventType_None   AoiEventType = iota
+	AoiEventType_Enter               // 单位进入视野
+	AoiEventType_Leave               // 单位离开视野
+	AoiEventType_Update              // 视野内的单位移动或状态变化
+)
+
+// AoiEvent 推送给观察者的AOI事件
+type AoiEvent struct {
+	Type AoiEventType // 事件类型
+	Unit Unit         // 单位（离开事件中的单位可能已从地图移除）
+}
+
+// SetViewWindow 移动观察窗口，按新旧窗口的差异产生进入和离开事件
+func (om *ObserverManager) SetViewWindow(playerId int64, viewWindow *geo.Rectangle) bool {
+	observer := om.observers[playerId]
+	if observer == nil {
+		return false
+	}
+
+	om.unsubscribe(observer)
+	observer.ViewWindow = viewWindow
+	om.subscribe(observer)
+	om.refreshObserver(observer)
+	return true
+}
+
+// ChangeLod 修改观察等级，按新旧等级的可见差异产生进入和离开事件
+func (om *ObserverManager) ChangeLod(playerId int64, lod int32) bool {
+	observer := om.observers[playerId]
+	if observer == nil {
+		return false
+	}
+	if observer.Lod == lod {
+		return true
+	}
+
+	observer.ChangeLod(lod)
+	om.refreshObserver(observer)
+	return true
+}
+
+// RefreshObserver 重新计算观察者的AOI（迷雾和关系变化由地图自动刷新，其他可见性条件变化后调用）
+func (om *ObserverManager) RefreshObserver(playerId int64) {
+	if observer := om.observers[playerId]; observer != nil {
+		om.refreshObserver(observer)
+	}
+}
+
+// OnFogChanged 玩家迷雾中有六边形进入或离开视野，为这些六边形中的单位产生进入和离开事件
+func (om *ObserverManager) OnFogChanged(playerId int64, hexes map[uint64]bool) {
+	observer := om.observers[playerId]
+	if observer == nil {
+		return
+	}
+
+	units := make(map[int64]Unit)
+	for key := range hexes {
+		grid := om.worldMap.hexGridMgr.grids[key]
+		if grid == nil {
+			continue
+		}
+		grid.RangeUnits(func(unit Unit) bool {
+			units[unit.GetId()] = unit
+			return true
+		})
+	}
+	// 按id顺序产生事件，保证推送顺序稳定
+	for _, unitId := range sortedUnitIds(units) {
+		unit := units[unitId]
+		view := om.getViewByCoord(unit.GetCoord())
+		inAoi := view != nil && view.HasObserver(playerId) && observer.IsVisible(unit)
+		watching := observer.IsWatching(unitId)
+		switch {
+		case inAoi && !watching:
+			om.enter(observer, unit)
+		case !inAoi && watching:
+			om.leave(observer, unit)
+		}
+	}
+}
+
+// OnUnitAdded 单位加入地图，通知所在view的观察者
+func (om *ObserverManager) OnUnitAdded(unit Unit) {
+	om.syncUnit(unit, om.getViewByCoord(unit.GetCoord()))
+}
+
+// OnUnitRemoved 单位离开地图，已推送过该单位的观察者收到离开事件
+func (om *ObserverManager) OnUnitRemoved(unit Unit) {
+	view := om.getViewByCoord(unit.GetCoord())
+	if view == nil {
+		return
+	}
+	for playerId := range view.observers {
+		observer := om.observers[playerId]
+		if observer != nil && observer.IsWatching(unit.GetId()) {
+			om.leave(observer, unit)
+		}
+	}
+}
+
+// OnUnitMoved 单位移动，通知旧位置和新位置所在view的观察者
+func (om *ObserverManager) OnUnitMoved(unit Unit, from *geo.Coord) {
+	om.syncUnit(unit, om.getViewByCoord(from), om.getViewByCoord(unit.GetCoord()))
+}
+
+// NotifyUnitUpdate 单位状态变化（资源量、驻军等），通知所在view的观察者
+func (om *ObserverManager) NotifyUnitUpdate(unit Unit) {
+	om.syncUnit(unit, om.getViewByCoord(unit.GetCoord()))
+}
+
+// subscribe 订阅观察窗口覆盖的view
+func (om *ObserverManager) subscribe(observer *Observer) {
+	observer.views = nil
+	if observer.ViewWindow == nil {
+		return
+	}
+	observer.views = om.GetCoverViews(observer.ViewWindow)
+	for _, view := range observer.views {
+		view.AddObserver(observer.Id)
+	}
+}
+
+// unsubscribe 取消订阅所有view
+func (om *ObserverManager) unsubscribe(observer *Observer) {
+	for _, view := range observer.views {
+		view.RemoveObserver(observer.Id)
+	}
+	observer.views = nil
+}
+
+// refreshObserver 重新收集订阅view中的可见单位，与已推送的单位比较产生进入和离开事件
+func (om *ObserverManager) refreshObserver(observer *Observer) {
+	current := make(map[int64]Unit)
+	for _, view := range observer.views {
+		grid := om.worldMap.gridMgr.GetGridByPos(view.X, view.Y)
+		if grid == nil {
+			continue
+		}
+		grid.RangeUnits(func(unit Unit) bool {
+			if observer.IsVisible(unit) {
+				current[unit.GetId()] = unit
+			}
+			return true
+		})
+	}
+
+	// 按id顺序产生事件，保证推送顺序稳定
+	for _, unitId := range sortedUnitIds(observer.known) {
+		if _, exists := current[unitId]; !exists {
+			om.leave(observer, observer.known[unitId])
+		}
+	}
+	for _, unitId := range sortedUnitIds(current) {
+		if !observer.IsWatching(unitId) {
+			om.enter(observer, current[unitId])
+		}
+	}
+}
+
+// syncUnit 按单位当前位置和可见性，为相关view的观察者产生进入、离开或更新事件
+func (om *ObserverManager) syncUnit(unit Unit, views ...*ObserverView) {
+	current := om.getViewByCoord(unit.GetCoord())
+	notified := make(map[int64]bool)
+	for _, view := range views {
+		if view == nil {
+			continue
+		}
+		for playerId := range view.observers {
+			if notified[playerId] {
+				continue
+			}
+			notified[playerId] = true
+
+			observer := om.observers[playerId]
+			if observer == nil {
+				continue
+			}
+			inAoi := current != nil && current.HasObserver(playerId) && observer.IsVisible(unit)
+			watching := observer.IsWatching(unit.GetId())
+			switch {
+			case inAoi && !watching:
+				om.enter(observer, unit)
+			case inAoi && watching:
+				observer.events = append(observer.events, &AoiEvent{Type: AoiEventType_Update, Unit: unit})
+			case !inAoi && watching:
+				om.leave(observer, unit)
+			}
+		}
+	}
+}
+
+func (om *ObserverManager) enter(observer *Observer, unit Unit) {
+	observer.known[unit.GetId()] = unit
+	observer.events = append(observer.events, &AoiEvent{Type: AoiEventType_Enter, Unit: unit})
+}
+
+func (om *ObserverManager) leave(observer *Observer, unit Unit) {
+	delete(observer.known, unit.GetId())
+	observer.events = append(observer.events, &AoiEvent{Type: AoiEventType_Leave, Unit: unit})
+}
+
+// getViewByCoord 获取坐标所在的view
+func (om *ObserverManager) getViewByCoord(coord *geo.Coord) *ObserverView {
+	if !om.worldMap.isCoordInMap(coord) {
+		return nil
+	}
+	x, y := om.CoordToViewIndex(coord)
+	view, _ := om.GetObserverViewByIndex(x, y)
+	return view
+}
+
+func sortedUnitIds(units map[int64]Unit) []int64 {
+	ids := make([]int64, 0, len(units))
+	for unitId := range units {
+		ids = append(ids, unitId)
+	}
+	slices.Sort(ids)
+	return ids
+}
@@ -0,0 +1,235 @@
+package worldmap
+
+import (
+	"testing"
+
+	"github.com/GooLuck/WorldMap/internal/worldmap/config"
+	"github.com/GooLuck/WorldMap/internal/worldmap/geo"
+)
+
+// TestObserverAoi 测试观察窗口订阅以及进入、离开、更新事件
+func TestObserverAoi(t *testing.T) {
+	wm := NewWorldMap(newTestMapConfig())
+	observerMgr := wm.GetObserverManager()
+
+	if view, ok := observerMgr.GetObserverViewByIndex(9, 9); !ok || view.X != 900 || view.Y != 900 {
+		t.Fatal("最后一个view应该存在")
+	}
+
+	observer := observerMgr.AddObserver(1, geo.NewRectangle(0, 0, 200, 200), 0)
+	if view, _ := observerMgr.GetObserverViewByIndex(1, 1); !view.HasObserver(1) {
+		t.Error("观察窗口覆盖的view应该被订阅")
+	}
+	if view, _ := observerMgr.GetObserverViewByIndex(2, 0); view.HasObserver(1) {
+		t.Error("观察窗口外的view不应该被订阅")
+	}
+	if views := observerMgr.GetCoverViews(observer.ViewWindow); len(views) != 4 {
+		t.Errorf("对齐的观察窗口覆盖的view数量错误：期望 4, 得到 %d", len(views))
+	}
+
+	checkEvents := func(step string, expects ...AoiEventType) []*AoiEvent {
+		events := observer.PopEvents()
+		if len(events) != len(expects) {
+			t.Fatalf("%s事件数量错误：期望 %d, 得到 %d", step, len(expects), len(events))
+		}
+		for i, event := range events {
+			if event.Type != expects[i] {
+				t.Errorf("%s第 %d 个事件类型错误：期望 %d, 得到 %d", step, i, expects[i], event.Type)
+			}
+		}
+		return events
+	}
+
+	near := &TestUnit{id: 1, coord: *geo.NewCoord(50, 50), unitType: MapUnitType_PlayerTroop}
+	far := &TestUnit{id: 2, coord: *geo.NewCoord(550, 550), unitType: MapUnitType_PlayerCity}
+	wm.AddUnit(near)
+	wm.AddUnit(far)
+	checkEvents("添加单位", AoiEventType_Enter)
+
+	wm.MoveUnit(near, geo.NewCoord(150, 50))
+	checkEvents("窗口内移动", AoiEventType_Update)
+
+	wm.MoveUnit(near, geo.NewCoord(450, 450))
+	checkEvents("移出窗口", AoiEventType_Leave)
+	if observer.IsWatching(near.GetId()) {
+		t.Error("离开后不应该继续关注")
+	}
+
+	// 移动观察窗口，两个单位都进入视野
+	observerMgr.SetViewWindow(1, geo.NewRectangle(400, 400, 200, 200))
+	events := checkEvents("移动窗口", AoiEventType_Enter, AoiEventType_Enter)
+	if events[0].Unit.GetId() != 1 || events[1].Unit.GetId() != 2 {
+		t.Error("进入事件应该按id排序")
+	}
+
+	wm.RemoveUnit(far)
+	checkEvents("移除单位", AoiEventType_Leave)
+
+	observerMgr.RemoveObserver(1)
+	if view, _ := observerMgr.GetObserverViewByIndex(4, 4); view.HasObserver(1) {
+		t.Error("移除观察者后应该取消订阅")
+	}
+}
+
+// TestObserverLod 测试观察等级的可见规则以及切换等级时的进入和离开事件
+func TestObserverLod(t *testing.T) {
+	mapConfig := newTestMapConfig()
+	mapConfig.LodRules = []config.LodRuleConfig{
+		{Lod: 2, UnitTypes: []config.LodUnitConfig{
+			{UnitType: int32(MapUnitType_PlayerCity)},
+			{UnitType: int32(MapUnitType_Npc), MinLevel: 10},
+		}},
+		{Lod: 4, UnitTypes: []config.LodUnitConfig{
+			{UnitType: int32(MapUnitType_PlayerCity)},
+		}},
+	}
+	wm := NewWorldMap(mapConfig)
+	observerMgr := wm.GetObserverManager()
+
+	city := &TestUnit{id: 1, coord: *geo.NewCoord(50, 50), unitType: MapUnitType_PlayerCity}
+	troop := &TestUnit{id: 2, coord: *geo.NewCoord(60, 60), unitType: MapUnitType_PlayerTroop}
+	smallNpc := NewNpcUnit(3, *geo.NewCoord(70, 70), nil, 5, &config.NpcConfig{})
+	bigNpc := NewNpcUnit(4, *geo.NewCoord(80, 80), nil, 20, &config.NpcConfig{})
+	for _, unit := range []Unit{city, troop, smallNpc, bigNpc} {
+		wm.AddUnit(unit)
+	}
+
+	// 没有适用规则时全部可见
+	observer := observerMgr.AddObserver(1, geo.NewRectangle(0, 0, 99, 99), 1)
+	if events := observer.PopEvents(); len(events) != 4 {
+		t.Fatalf("观察等级1可见单位数量错误：期望 4, 得到 %d", len(events))
+	}
+
+	collect := func() map[int64]AoiEventType {
+		result := make(map[int64]AoiEventType)
+		for _, event := range observer.PopEvents() {
+			result[event.Unit.GetId()] = event.Type
+		}
+		return result
+	}
+
+	observerMgr.ChangeLod(1, 3)
+	diff := collect()
+	if len(diff) != 2 || diff[2] != AoiEventType_Leave || diff[3] != AoiEventType_Leave {
+		t.Errorf("切换到观察等级3的差异错误：得到 %v", diff)
+	}
+	if observer.IsVisible(troop) || !observer.IsVisible(bigNpc) {
+		t.Error("观察等级3只能看到主城和高等级NPC")
+	}
+
+	observerMgr.ChangeLod(1, 5)
+	diff = collect()
+	if len(diff) != 1 || diff[4] != AoiEventType_Leave {
+		t.Errorf("切换到观察等级5的差异错误：得到 %v", diff)
+	}
+
+	observerMgr.ChangeLod(1, 0)
+	diff = collect()
+	if len(diff) != 3 || diff[2] != AoiEventType_Enter || diff[3] != AoiEventType_Enter || diff[4] != AoiEventType_Enter {
+		t.Errorf("切换到观察等级0的差异错误：得到 %v", diff)
+	}
+}
+
+// TestObserverFogEvents 测试迷雾视野变化时产生进入和离开事件
+func TestObserverFogEvents(t *testing.T) {
+	mapConfig := newTestMapConfig()
+	mapConfig.Fog = config.FogConfig{Enabled: true, TroopVision: 1}
+	wm := NewWorldMap(mapConfig)
+	observer := wm.GetObserverManager().AddObserver(1, geo.NewRectangle(0, 0, 1000, 1000), 0)
+
+	enemyHex := geo.NewHexCoord(2, 1)
+	enemy := &TestUnit{id: 1, coord: *wm.hexToCoord(enemyHex), unitType: MapUnitType_PlayerCity, owner: NewPlayerOwner(2)}
+	wm.AddUnit(enemy)
+	if events := observer.PopEvents(); len(events) != 0 {
+		t.Fatalf("视野外的单位不应该产生事件：得到 %d", len(events))
+	}
+
+	// 部队加入后看到相邻的敌方主城
+	troop := &TestUnit{id: 2, coord: *wm.hexToCoord(geo.NewHexCoord(1, 1)), unitType: MapUnitType_PlayerTroop, owner: NewPlayerOwner(1)}
+	wm.AddUnit(troop)
+	events := observer.PopEvents()
+	if len(events) != 2 || events[0].Type != AoiEventType_Enter || events[0].Unit != troop || events[1].Type != AoiEventType_Enter || events[1].Unit != enemy {
+		t.Fatalf("部队加入后应该依次收到部队和敌方主城的进入事件：得到 %d 个事件", len(events))
+	}
+
+	// 视野来源离开后，静止的敌方主城离开视野
+	wm.MoveUnitToHex(troop, geo.NewHexCoord(5, 1))
+	events = observer.PopEvents()
+	if len(events) != 2 || events[0].Type != AoiEventType_Leave || events[0].Unit != enemy || events[1].Type != AoiEventType_Update {
+		t.Fatalf("视野来源离开后敌方主城应该离开视野：得到 %d 个事件", len(events))
+	}
+	if observer.IsWatching(enemy.GetId()) {
+		t.Error("离开视野后不应该继续关注")
+	}
+
+	// 视野来源回来，静止的敌方主城重新进入视野
+	wm.MoveUnitToHex(troop, geo.NewHexCoord(1, 1))
+	events = observer.PopEvents()
+	if len(events) != 2 || events[0].Type != AoiEventType_Enter || events[0].Unit != enemy {
+		t.Fatalf("视野来源回来后敌方主城应该进入视野：得到 %d 个事件", len(events))
+	}
+
+	// 视野来源移除后，敌方主城离开视野
+	wm.RemoveUnit(troop)
+	events = observer.PopEvents()
+	if len(events) != 2 || events[0].Type != AoiEventType_Leave || events[0].Unit != troop || events[1].Unit != enemy {
+		t.Fatalf("视野来源移除后应该收到部队和敌方主城的离开事件：得到 %d 个事件", len(events))
+	}
+}
+
+// TestObserverAllianceFogEvents 测试同盟共享视野变化时产生进入和离开事件
+func TestObserverAllianceFogEvents(t *testing.T) {
+	mapConfig := newTestMapConfig()
+	mapConfig.Fog = config.FogConfig{Enabled: true, ShareAlliance: true, TroopVision: 1}
+	wm := NewWorldMap(mapConfig)
+	registry := wm.GetRelationRegistry()
+	registry.SetPlayerAlliance(1, 100)
+	observer := wm.GetObserverManager().AddObserver(1, geo.NewRectangle(0, 0, 1000, 1000), 0)
+
+	enemy := &TestUnit{id: 1, coord: *wm.hexToCoord(geo.NewHexCoord(6, 1)), unitType: MapUnitType_PlayerCity, owner: NewPlayerOwner(3)}
+	wm.AddUnit(enemy)
+	troop := &TestUnit{id: 2, coord: *wm.hexToCoord(geo.NewHexCoord(5, 1)), unitType: MapUnitType_PlayerTroop, owner: NewPlayerOwner(2)}
+	wm.AddUnit(troop)
+	if events := observer.PopEvents(); len(events) != 0 {
+		t.Fatalf("没有共享视野时不应该产生事件：得到 %d", len(events))
+	}
+
+	// 加入联盟后获得共享视野
+	registry.SetPlayerAlliance(2, 100)
+	events := observer.PopEvents()
+	if len(events) != 2 || events[0].Type != AoiEventType_Enter || events[0].Unit != enemy || events[1].Type != AoiEventType_Enter || events[1].Unit != troop {
+		t.Fatalf("加入联盟后应该收到敌方主城和同盟部队的进入事件：得到 %d 个事件", len(events))
+	}
+
+	// 同盟部队离开后，静止的敌方主城离开共享视野
+	wm.MoveUnitToHex(troop, geo.NewHexCoord(2, 1))
+	events = observer.PopEvents()
+	if len(events) != 2 || events[0].Type != AoiEventType_Leave || events[0].Unit != enemy || events[1].Type != AoiEventType_Update || events[1].Unit != troop {
+		t.Fatalf("同盟部队离开后敌方主城应该离开视野：得到 %d 个事件", len(events))
+	}
+	wm.MoveUnitToHex(troop, geo.NewHexCoord(5, 1))
+	events = observer.PopEvents()
+	if len(events) != 2 || events[0].Type != AoiEventType_Enter || events[0].Unit != enemy {
+		t.Fatalf("同盟部队回来后敌方主城应该进入视野：得到 %d 个事件", len(events))
+	}
+
+	// 退出联盟后失去共享视野
+	registry.SetPlayerAlliance(2, 0)
+	events = observer.PopEvents()
+	if len(events) != 2 || events[0].Type != AoiEventType_Leave || events[0].Unit != enemy || events[1].Type != AoiEventType_Leave || events[1].Unit != troop {
+		t.Fatalf("退出联盟后应该收到敌方主城和同盟部队的离开事件：得到 %d 个事件", len(events))
+	}
+
+	// 关闭共享视野同样失去同盟成员的视野
+	registry.SetPlayerAlliance(2, 100)
+	observer.PopEvents()
+	wm.GetFogManager().SetShareAlliance(false)
+	events = observer.PopEvents()
+	if len(events) != 2 || events[0].Type != AoiEventType_Leave || events[1].Type != AoiEventType_Leave {
+		t.Fatalf("关闭共享视野后应该收到离开事件：得到 %d 个事件", len(events))
+	}
+	wm.GetFogManager().SetShareAlliance(true)
+	if events := observer.PopEvents(); len(events) != 2 || events[0].Type != AoiEventType_Enter || events[1].Type != AoiEventType_Enter {
+		t.Fatalf("开启共享视野后应该收到进入事件：得到 %d 个事件", len(events))
+	}
+}
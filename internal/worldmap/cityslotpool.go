@@ -0,0 +1,350 @@
+package worldmap
+
+import (
+	"math"
+	"math/rand"
+	"slices"
+	"time"
+
+	"github.com/GooLuck/WorldMap/internal/worldmap/config"
+	"github.com/GooLuck/WorldMap/internal/worldmap/geo"
+)
+
+// CitySlotReservation 主城位置预留
+type CitySlotReservation struct {
+	Id       int64     // 预留id
+	ZoneID   int32     // 所属主城区域id
+	Coord    geo.Coord // 主城坐标
+	Radius   int32     // 主城占地半径
+	ExpireAt time.Time // 过期时间（零值表示不过期）
+}
+
+// minCandidateStep 候选位置的最小间距，占地半径很小时不逐个坐标枚举
+const minCandidateStep int32 = 10
+
+// citySlotZone 单个主城区域的位置池
+type citySlotZone struct {
+	area       *CityZoneArea
+	candidates []geo.Coord // 预计算的候选位置（按扇区交错排列）
+	cursor     int         // 下一个待检查的候选位置
+	free       []geo.Coord // 已校验的空闲位置
+	reserved   int32       // 当前预留数量
+}
+
+// CitySlotPool 主城位置池
+// 地图创建时按主城区域预计算候选位置，注册时直接从池中取出，
+// 空闲位置不足时由地图 Tick 逐步补充
+type CitySlotPool struct {
+	worldMap         *WorldMap
+	poolConfig       *config.CitySlotPoolConfig
+	radius           int32
+	zones            []*citySlotZone
+	reservations     map[int64]*CitySlotReservation // 预留id -> 预留
+	reservationGrids [][]*CitySlotReservation       // 网格索引 -> 坐标在该网格内的预留
+}
+
+// NewCitySlotPool 创建主城位置池并完成首次填充
+func NewCitySlotPool(worldMap *WorldMap) *CitySlotPool {
+	mapConfig := worldMap.GetConfig()
+	pool := &CitySlotPool{
+		worldMap:     worldMap,
+		poolConfig:   &mapConfig.CitySlotPool,
+		radius:       mapConfig.CityRadius,
+		zones:        make([]*citySlotZone, 0, len(worldMap.cityZones)),
+		reservations: make(map[int64]*CitySlotReservation),
+	}
+	pool.reservationGrids = make([][]*CitySlotReservation, worldMap.gridMgr.gridCols*worldMap.gridMgr.gridRows)
+
+	for _, area := range worldMap.cityZones {
+		zoneConfig := worldMap.getCityZoneConfig(area.ZoneID)
+		zone := &citySlotZone{
+			area:       area,
+			candidates: pool.buildCandidates(zoneConfig),
+			free:       make([]geo.Coord, 0, pool.poolConfig.PoolSize),
+		}
+		pool.zones = append(pool.zones, zone)
+		pool.refillZone(zone, len(zone.candidates))
+	}
+	return pool
+}
+
+// buildCandidates 按主城占地大小在环形区域内生成候选位置，相邻候选位置占地互不重叠（间距不小于 minCandidateStep）
+func (pool *CitySlotPool) buildCandidates(zoneConfig *config.CityZoneConfig) []geo.Coord {
+	sectors := zoneConfig.Sectors
+	if sectors <= 0 {
+		sectors = 1
+	}
+	sectorAngle := 2 * math.Pi / float64(sectors)
+	step := max(2*pool.radius+1, minCandidateStep)
+	center := pool.worldMap.GetMapCenter()
+	minR2 := int64(zoneConfig.MinRadius) * int64(zoneConfig.MinRadius)
+	maxR2 := int64(zoneConfig.MaxRadius) * int64(zoneConfig.MaxRadius)
+
+	bySector := make([][]geo.Coord, sectors)
+	for dy := -zoneConfig.MaxRadius; dy <= zoneConfig.MaxRadius; dy += step {
+		for dx := -zoneConfig.MaxRadius; dx <= zoneConfig.MaxRadius; dx += step {
+			dist2 := int64(dx)*int64(dx) + int64(dy)*int64(dy)
+			if dist2 < minR2 || dist2 > maxR2 {
+				continue
+			}
+			angle := math.Atan2(float64(dy), float64(dx))
+			if angle < 0 {
+				angle += 2 * math.Pi
+			}
+			sector := int32(angle/sectorAngle) % sectors
+			bySector[sector] = append(bySector[sector], geo.Coord{X: center.X + dx, Y: center.Y + dy})
+		}
+	}
+
+	// 扇区内打乱，扇区间交错，保证连续取出的位置分布在不同方向
+	total := 0
+	for _, coords := range bySector {
+		rand.Shuffle(len(coords), func(i, j int) {
+			coords[i], coords[j] = coords[j], coords[i]
+		})
+		total += len(coords)
+	}
+	candidates := make([]geo.Coord, 0, total)
+	for i := 0; len(candidates) < total; i++ {
+		for _, coords := range bySector {
+			if i < len(coords) {
+				candidates = append(candidates, coords[i])
+			}
+		}
+	}
+	return candidates
+}
+
+// refillZone 从候选位置中补充空闲位置，最多检查 budget 个候选位置，返回实际检查数量
+func (pool *CitySlotPool) refillZone(zone *citySlotZone, budget int) int {
+	checked := 0
+	for checked < budget && len(zone.candidates) > 0 && int32(len(zone.free)) < pool.poolConfig.PoolSize {
+		if zone.cursor >= len(zone.candidates) {
+			// 候选位置检查完一轮，从头重新扫描已释放的空间
+			zone.cursor = 0
+		}
+		coord := zone.candidates[zone.cursor]
+		zone.cursor++
+		checked++
+
+		if pool.isInFree(zone, &coord) {
+			continue
+		}
+		if pool.worldMap.CanPlaceCity(&coord, pool.radius) {
+			zone.free = append(zone.free, coord)
+		}
+	}
+	return checked
+}
+
+// isInFree 坐标是否已在空闲列表中
+func (pool *CitySlotPool) isInFree(zone *citySlotZone, coord *geo.Coord) bool {
+	for i := range zone.free {
+		if zone.free[i] == *coord {
+			return true
+		}
+	}
+	return false
+}
+
+// gridRange 范围覆盖的网格索引区间（超出地图的部分归入边缘网格）
+func (pool *CitySlotPool) gridRange(minX, minY, maxX, maxY int32) (minCol, maxCol, minRow, maxRow int32) {
+	gridMgr := pool.worldMap.gridMgr
+	clamp := func(v, limit int32) int32 {
+		return min(max(v, 0), limit-1)
+	}
+	minCol = clamp(floorDiv(minX, gridMgr.mapSize.GridWidth), gridMgr.gridCols)
+	maxCol = clamp(floorDiv(maxX, gridMgr.mapSize.GridWidth), gridMgr.gridCols)
+	minRow = clamp(floorDiv(minY, gridMgr.mapSize.GridHeight), gridMgr.gridRows)
+	maxRow = clamp(floorDiv(maxY, gridMgr.mapSize.GridHeight), gridMgr.gridRows)
+	return
+}
+
+// gridIndex 坐标所在网格的索引
+func (pool *CitySlotPool) gridIndex(coord *geo.Coord) int32 {
+	col, _, row, _ := pool.gridRange(coord.X, coord.Y, coord.X, coord.Y)
+	return row*pool.worldMap.gridMgr.gridCols + col
+}
+
+// addReservation 登记预留，按坐标所在网格分桶
+func (pool *CitySlotPool) addReservation(reservation *CitySlotReservation) {
+	pool.reservations[reservation.Id] = reservation
+	index := pool.gridIndex(&reservation.Coord)
+	pool.reservationGrids[index] = append(pool.reservationGrids[index], reservation)
+}
+
+// removeReservation 删除预留
+func (pool *CitySlotPool) removeReservation(reservation *CitySlotReservation) {
+	delete(pool.reservations, reservation.Id)
+	index := pool.gridIndex(&reservation.Coord)
+	pool.reservationGrids[index] = slices.DeleteFunc(pool.reservationGrids[index], func(r *CitySlotReservation) bool {
+		return r == reservation
+	})
+}
+
+// isOverlapReservation 占地范围是否与已预留的位置重叠，只检查可能重叠的预留所在的网格
+func (pool *CitySlotPool) isOverlapReservation(coord *geo.Coord, radius int32) bool {
+	// 预留的占地半径都是 pool.radius
+	reach := radius + pool.radius
+	minCol, maxCol, minRow, maxRow := pool.gridRange(coord.X-reach, coord.Y-reach, coord.X+reach, coord.Y+reach)
+	for row := minRow; row <= maxRow; row++ {
+		for col := minCol; col <= maxCol; col++ {
+			for _, reservation := range pool.reservationGrids[row*pool.worldMap.gridMgr.gridCols+col] {
+				limit := radius + reservation.Radius
+				if absInt32(coord.X-reservation.Coord.X) <= limit && absInt32(coord.Y-reservation.Coord.Y) <= limit {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+// isZoneFull 区域已有主城与预留数量之和是否达到上限
+func (pool *CitySlotPool) isZoneFull(zone *citySlotZone) bool {
+	zoneConfig := pool.worldMap.getCityZoneConfig(zone.area.ZoneID)
+	if zoneConfig == nil {
+		return true
+	}
+	return zoneConfig.MaxCities > 0 && zone.area.CurCount+zone.reserved >= zoneConfig.MaxCities
+}
+
+// Reserve 按区域顺序预留一个主城位置
+func (pool *CitySlotPool) Reserve(now time.Time) (*CitySlotReservation, bool) {
+	for _, zone := range pool.zones {
+		if reservation, ok := pool.reserveInZone(zone, now); ok {
+			return reservation, true
+		}
+	}
+	return nil, false
+}
+
+// ReserveInZone 在指定区域预留一个主城位置
+func (pool *CitySlotPool) ReserveInZone(zoneId int32, now time.Time) (*CitySlotReservation, bool) {
+	for _, zone := range pool.zones {
+		if zone.area.ZoneID == zoneId {
+			return pool.reserveInZone(zone, now)
+		}
+	}
+	return nil, false
+}
+
+func (pool *CitySlotPool) reserveInZone(zone *citySlotZone, now time.Time) (*CitySlotReservation, bool) {
+	if pool.isZoneFull(zone) {
+		return nil, false
+	}
+
+	for len(zone.free) > 0 {
+		coord := zone.free[0]
+		zone.free = zone.free[1:]
+
+		// 位置在入池后可能已被占用，取出时再校验一次
+		if !pool.worldMap.CanPlaceCity(&coord, pool.radius) {
+			continue
+		}
+
+		reservation := &CitySlotReservation{
+			Id:     GetIDGenerator().GenerateNewID(),
+			ZoneID: zone.area.ZoneID,
+			Coord:  coord,
+			Radius: pool.radius,
+		}
+		if pool.poolConfig.ReserveTimeout > 0 {
+			reservation.ExpireAt = now.Add(time.Duration(pool.poolConfig.ReserveTimeout) * time.Second)
+		}
+		pool.addReservation(reservation)
+		zone.reserved++
+		return reservation, true
+	}
+	return nil, false
+}
+
+// Commit 确认预留，把主城放到预留坐标并加入地图，加入成功后才释放预留，区域数量由 AddUnit 计入
+// 主城加入失败时预留保留不变，调用方可以重试或 Release
+func (pool *CitySlotPool) Commit(reservationId int64, city Unit) bool {
+	reservation, exists := pool.reservations[reservationId]
+	if !exists {
+		return false
+	}
+
+	coord := reservation.Coord
+	city.SetCoord(&coord)
+	if !pool.worldMap.AddUnit(city) {
+		return false
+	}
+
+	pool.removeReservation(reservation)
+	if zone := pool.getZone(reservation.ZoneID); zone != nil {
+		zone.reserved--
+	}
+	return true
+}
+
+// Release 取消预留，位置放回池中
+func (pool *CitySlotPool) Release(reservationId int64) bool {
+	reservation, exists := pool.reservations[reservationId]
+	if !exists {
+		return false
+	}
+
+	pool.removeReservation(reservation)
+	if zone := pool.getZone(reservation.ZoneID); zone != nil {
+		zone.reserved--
+		zone.free = append([]geo.Coord{reservation.Coord}, zone.free...)
+	}
+	return true
+}
+
+// GetReservation 获取预留
+func (pool *CitySlotPool) GetReservation(reservationId int64) *CitySlotReservation {
+	return pool.reservations[reservationId]
+}
+
+// GetFreeCount 获取指定区域当前空闲位置数量
+func (pool *CitySlotPool) GetFreeCount(zoneId int32) int32 {
+	if zone := pool.getZone(zoneId); zone != nil {
+		return int32(len(zone.free))
+	}
+	return 0
+}
+
+// OnCityRemoved 主城迁走或删除后释放出空间，按区域把坐标重新加入池中（地图移除主城时自动调用，区域计数由地图维护）
+func (pool *CitySlotPool) OnCityRemoved(coord *geo.Coord) {
+	area := pool.worldMap.cityZoneAreaOf(coord)
+	if area == nil {
+		return
+	}
+	zone := pool.getZone(area.ZoneID)
+	if zone != nil && int32(len(zone.free)) < pool.poolConfig.PoolSize && !pool.isInFree(zone, coord) {
+		zone.free = append(zone.free, *coord)
+	}
+}
+
+// Update 处理过期预留并补充空闲位置
+func (pool *CitySlotPool) Update(now time.Time) {
+	for id, reservation := range pool.reservations {
+		if !reservation.ExpireAt.IsZero() && !now.Before(reservation.ExpireAt) {
+			pool.Release(id)
+		}
+	}
+
+	budget := int(pool.poolConfig.RefillPerTick)
+	for _, zone := range pool.zones {
+		if budget <= 0 {
+			break
+		}
+		if pool.isZoneFull(zone) {
+			continue
+		}
+		budget -= pool.refillZone(zone, budget)
+	}
+}
+
+func (pool *CitySlotPool) getZone(zoneId int32) *citySlotZone {
+	for _, zone := range pool.zones {
+		if zone.area.ZoneID == zoneId {
+			return zone
+		}
+	}
+	return nil
+}
@@ -20,6 +20,14 @@ const (
 	RefreshStrategy_Random                             // 随机恢复
 )
 
+// 地图拓扑类型
+type TopologyType int32
+
+const (
+	TopologyType_Hex    TopologyType = iota // 六边形格子（大小和朝向由 HexRadius、HexPointy 决定）
+	TopologyType_Square                     // 正方形格子（以 MapSize 的 grid 为格子，地形按正方形格子保存）
+)
+
 type MapSize struct {
 	Width      int32 // 地图宽度（世界单位）
 	Height     int32 // 地图高度（世界单位）
@@ -35,9 +43,30 @@ type MapConfig struct {
 	DefaultVisionRange int32    // 默认视野范围（网格数）
 	MaxPlayers         int32    // 最大玩家数量
 
+	// 地图拓扑配置
+	Topology  TopologyType // 地图拓扑（默认六边形），决定 WorldMap.GetTopology 使用的空间后端和地形格子，行军、迷雾和领土的移动始终使用六边形
+	HexRadius float64      // 六边形半径（世界单位，0表示使用grid宽度）
+	HexPointy bool         // 六边形是否为顶点朝上（false为平边朝上）
+
 	// 出生点配置
 	SpawnPoints []SpawnPointConfig // 出生点列表
 
+	// 主城配置
+	CityRadius int32            // 主城占地半径（世界单位）
+	CityZones  []CityZoneConfig // 主城区域列表（按顺序由内向外填充）
+
+	// 主城位置池配置
+	CitySlotPool CitySlotPoolConfig // 主城位置池（用于开服大量注册）
+
+	// 联盟领土配置
+	Territory TerritoryConfig // 联盟领土半径
+
+	// 战争迷雾配置
+	Fog FogConfig // 战争迷雾
+
+	// 观察等级配置
+	LodRules []LodRuleConfig // 观察等级对应的可见规则（为空表示全部可见）
+
 	// 资源点配置（兼容旧版）
 	ResourcePoints []ResourcePointConfig // 资源点列表
 
@@ -47,6 +76,10 @@ type MapConfig struct {
 	// 资源区域配置
 	ResourceZones []ResourceZoneConfig // 资源区域列表
 
+	// NPC配置
+	Npcs     []NpcConfig    // NPC配置列表
+	NpcSpawn NpcSpawnConfig // NPC刷新配置
+
 	// 障碍物配置
 	Obstacles []ObstacleConfig // 障碍物列表
 
@@ -66,6 +99,50 @@ type SpawnPointConfig struct {
 	ForPlayer bool  // 是否为玩家出生点（true为玩家，false为NPC）
 }
 
+// 主城区域配置（以地图中心为圆心的环形区域）
+type CityZoneConfig struct {
+	ZoneID    int32 // 区域ID
+	MinRadius int32 // 环内半径（世界单位）
+	MaxRadius int32 // 环外半径（世界单位）
+	MaxCities int32 // 区域最大主城数量（0表示不限）
+	Sectors   int32 // 扇区数量（新主城依次落在不同扇区，保证均匀分布，0表示不分扇区）
+}
+
+// 主城位置池配置
+type CitySlotPoolConfig struct {
+	PoolSize       int32 // 每个主城区域缓存的空闲位置数量（0表示不启用）
+	RefillPerTick  int32 // 每Tick最多检查的候选位置数量
+	ReserveTimeout int32 // 预留超时时间（秒，0表示不超时）
+}
+
+// 联盟领土配置（半径均为六边形步数）
+type TerritoryConfig struct {
+	CityRadius     int32 // 联盟成员主城的领土半径（主城加入地图、迁城和成员变化时自动更新）
+	FlagRadius     int32 // 联盟旗帜的领土半径
+	FortressRadius int32 // 联盟要塞的领土半径
+}
+
+// 战争迷雾配置（视野半径为六边形步数，0表示使用默认视野范围）
+type FogConfig struct {
+	Enabled          bool  // 是否启用战争迷雾
+	ShareAlliance    bool  // 是否共享联盟成员的视野
+	CityVision       int32 // 主城视野半径
+	TroopVision      int32 // 部队视野半径
+	WatchtowerVision int32 // 瞭望塔视野半径
+}
+
+// 观察等级规则配置：观察等级不低于 Lod 时（取最接近的一条规则），只有列出的单位类型可见
+type LodRuleConfig struct {
+	Lod       int32           // 观察等级
+	UnitTypes []LodUnitConfig // 可见的单位类型
+}
+
+// 观察等级下单位类型的可见条件
+type LodUnitConfig struct {
+	UnitType int32 // 单位类型（MapUnitType）
+	MinLevel int32 // 最小等级（0表示不限，没有等级的单位不受限制）
+}
+
 // 资源点配置（基础版，保持向后兼容）
 type ResourcePointConfig struct {
 	PointID      int32   // 资源点ID
@@ -83,6 +160,7 @@ type EnhancedResourcePointConfig struct {
 	X               int32             // X坐标（世界单位）
 	Y               int32             // Y坐标（世界单位）
 	ResourceType    string            // 资源类型
+	Level           int32             // 资源点等级（用于缩放视图的可见性）
 	PointType       ResourcePointType // 资源点类型
 	RefreshStrategy RefreshStrategy   // 刷新策略
 
@@ -155,6 +233,37 @@ type GlobalRefreshConfig struct {
 	RefreshTickInterval int32 // 刷新Tick间隔（秒）
 }
 
+// NPC配置
+type NpcConfig struct {
+	NpcID         int32  // NPC配置ID
+	Name          string // NPC名称
+	MinLevel      int32  // 最小等级
+	MaxLevel      int32  // 最大等级（0表示不限）
+	BaseHp        int32  // 1级生命值
+	HpPerLevel    int32  // 每级增加生命值
+	BasePower     int32  // 1级战力
+	PowerPerLevel int32  // 每级增加战力
+}
+
+// NPC等级段
+type NpcLevelBand struct {
+	MinLevel     int32 // 最小等级（离地图中心最远处）
+	MaxLevel     int32 // 最大等级（地图中心处）
+	CountPerGrid int32 // 每个grid的目标数量
+}
+
+// NPC刷新配置（只在在线玩家视野附近维持NPC密度）
+type NpcSpawnConfig struct {
+	NpcID          int32          // 刷新使用的NPC配置ID
+	LevelBands     []NpcLevelBand // 等级段列表
+	ActiveRange    int32          // 玩家视野外扩的grid数量
+	DespawnTimeout int32          // 无玩家区域NPC消失时间（秒）
+
+	// 性能限制
+	MaxSpawnPerTick   int32 // 每Tick最大刷新数量
+	SpawnTickInterval int32 // 刷新Tick间隔（秒）
+}
+
 // 障碍物配置
 type ObstacleConfig struct {
 	ObstacleID   int32  // 障碍物ID
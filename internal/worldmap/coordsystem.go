@@ -0,0 +1,64 @@
+package worldmap
+
+import (
+	"math"
+
+	"github.com/GooLuck/WorldMap/internal/worldmap/config"
+	"github.com/GooLuck/WorldMap/internal/worldmap/geo"
+)
+
+// CoordSystem 地图坐标系：矩形坐标和六边形坐标都通过六边形布局换算
+// 单位加入地图和移动时由它换算另一种坐标，矩形网格和六边形网格描述的是同一个位置
+type CoordSystem struct {
+	layout  *geo.HexLayout
+	mapSize *config.MapSize
+}
+
+// NewCoordSystem 创建地图坐标系
+func NewCoordSystem(mapSize *config.MapSize, layout *geo.HexLayout) *CoordSystem {
+	return &CoordSystem{
+		layout:  layout,
+		mapSize: mapSize,
+	}
+}
+
+// GetLayout 获取六边形布局
+func (cs *CoordSystem) GetLayout() *geo.HexLayout {
+	return cs.layout
+}
+
+// CoordToHex 矩形坐标转所在六边形
+func (cs *CoordSystem) CoordToHex(coord *geo.Coord) *geo.HexCoord {
+	return cs.WorldToHex(float64(coord.X), float64(coord.Y))
+}
+
+// WorldToHex 浮点世界坐标转所在六边形
+func (cs *CoordSystem) WorldToHex(x, y float64) *geo.HexCoord {
+	q, r := cs.layout.WorldToHex(x, y)
+	return geo.RoundToHex(q, r)
+}
+
+// HexToCoord 六边形中心转矩形坐标（限制在地图范围内）
+func (cs *CoordSystem) HexToCoord(hex *geo.HexCoord) *geo.Coord {
+	return cs.WorldToCoord(cs.layout.HexToWorld(hex))
+}
+
+// WorldToCoord 浮点世界坐标转矩形坐标（限制在地图范围内）
+func (cs *CoordSystem) WorldToCoord(x, y float64) *geo.Coord {
+	return geo.NewCoord(
+		int32(math.Max(0, math.Min(math.Round(x), float64(cs.mapSize.Width-1)))),
+		int32(math.Max(0, math.Min(math.Round(y), float64(cs.mapSize.Height-1)))),
+	)
+}
+
+// HexRect 六边形的外接矩形（矩形坐标，左闭右开）
+func (cs *CoordSystem) HexRect(hex *geo.HexCoord) *geo.Rectangle {
+	minX, minY := math.Inf(1), math.Inf(1)
+	maxX, maxY := math.Inf(-1), math.Inf(-1)
+	for _, corner := range cs.layout.GetHexCorners(hex) {
+		minX, maxX = math.Min(minX, corner[0]), math.Max(maxX, corner[0])
+		minY, maxY = math.Min(minY, corner[1]), math.Max(maxY, corner[1])
+	}
+	x, y := int32(math.Floor(minX)), int32(math.Floor(minY))
+	return geo.NewRectangle(x, y, int32(math.Ceil(maxX))-x, int32(math.Ceil(maxY))-y)
+}
@@ -0,0 +1,101 @@
+package worldmap
+
+import (
+	"testing"
+	"time"
+
+	"github.com/GooLuck/WorldMap/internal/worldmap/config"
+	"github.com/GooLuck/WorldMap/internal/worldmap/geo"
+)
+
+// TestCoordSystem 测试矩形坐标和六边形坐标通过坐标系保持一致
+func TestCoordSystem(t *testing.T) {
+	mapConfig := newTestMapConfig()
+	mapConfig.Obstacles = []config.ObstacleConfig{
+		{ObstacleID: 1, X: 300, Y: 450, Width: 20, Height: 20},
+	}
+	wm := NewWorldMap(mapConfig)
+	coords := wm.GetCoordSystem()
+
+	// 地图内的六边形中心换算后回到同一个六边形
+	hex := geo.NewHexCoord(2, 3)
+	if got := coords.CoordToHex(coords.HexToCoord(hex)); !got.Equal(hex) {
+		t.Errorf("六边形往返换算错误：期望 %v, 得到 %v", hex, got)
+	}
+
+	// 资源点和障碍物的六边形坐标按矩形坐标换算
+	resource := NewResourceUnit(1, 1, *geo.NewCoord(420, 330), &config.EnhancedResourcePointConfig{})
+	wm.GetResourceManager().addResource(resource)
+	if expect := coords.CoordToHex(resource.GetCoord()); !resource.GetHexCoord().Equal(expect) {
+		t.Errorf("资源点六边形错误：期望 %v, 得到 %v", expect, resource.GetHexCoord())
+	}
+	obstacle := wm.GetObstacleManager().GetObstaclesInArea(300, 450, 300, 450)[0]
+	if expect := coords.CoordToHex(obstacle.GetCoord()); !obstacle.GetHexCoord().Equal(expect) {
+		t.Errorf("障碍物六边形错误：期望 %v, 得到 %v", expect, obstacle.GetHexCoord())
+	}
+
+	// 统一的移动接口同时更新矩形网格和六边形网格
+	unit := &TestUnit{id: 2, coord: *geo.NewCoord(50, 50), unitType: MapUnitType_PlayerTroop}
+	wm.AddUnit(unit)
+	if expect := coords.CoordToHex(unit.GetCoord()); !unit.GetHexCoord().Equal(expect) {
+		t.Errorf("加入地图后六边形错误：期望 %v, 得到 %v", expect, unit.GetHexCoord())
+	}
+
+	// 已设置但与矩形坐标不一致的六边形坐标在加入地图时被纠正
+	stale := &TestUnit{id: 3, coord: *geo.NewCoord(50, 50), hexCoord: geo.NewHexCoord(3, 4), unitType: MapUnitType_PlayerTroop}
+	wm.AddUnit(stale)
+	if !stale.GetHexCoord().Equal(unit.GetHexCoord()) || wm.GetHexGridManager().GetGrid(geo.NewHexCoord(3, 4)).IsExistUnit(stale) {
+		t.Errorf("加入地图后应该按矩形坐标纠正六边形：得到 %v", stale.GetHexCoord())
+	}
+	wm.RemoveUnit(stale)
+
+	oldHex := unit.GetHexCoord()
+	target := coords.HexToCoord(geo.NewHexCoord(3, 2))
+	if !wm.MoveUnit(unit, target) {
+		t.Fatal("移动单位失败")
+	}
+	if !unit.GetHexCoord().Equal(geo.NewHexCoord(3, 2)) || *unit.GetCoord() != *target {
+		t.Errorf("移动后坐标错误：得到 %v, %v", unit.GetCoord(), unit.GetHexCoord())
+	}
+	hexGridMgr := wm.GetHexGridManager()
+	if hexGridMgr.GetGrid(oldHex).IsExistUnit(unit) || !hexGridMgr.GetGrid(unit.GetHexCoord()).IsExistUnit(unit) {
+		t.Error("移动后六边形网格未更新")
+	}
+	if !wm.GetGridManager().GetGridByCoord(target).IsExistUnit(unit) {
+		t.Error("移动后矩形网格未更新")
+	}
+	if wm.MoveUnit(unit, geo.NewCoord(-1, 0)) {
+		t.Error("地图外的坐标不能移动")
+	}
+}
+
+// TestCoordSystemCorners 测试地图四个角上的单位都能加入六边形网格并从那里出发行军
+func TestCoordSystemCorners(t *testing.T) {
+	for _, pointy := range []bool{true, false} {
+		mapConfig := newTestMapConfig()
+		mapConfig.HexPointy = pointy
+		wm := NewWorldMap(mapConfig)
+		hexGridMgr := wm.GetHexGridManager()
+		troopMgr := wm.GetTroopManager()
+		center := wm.coordToHex(wm.GetMapCenter())
+
+		for i, corner := range []*geo.Coord{geo.NewCoord(0, 0), geo.NewCoord(999, 0), geo.NewCoord(0, 999), geo.NewCoord(999, 999), geo.NewCoord(10, 990)} {
+			unit := &TestUnit{id: int64(i + 1), coord: *corner, unitType: MapUnitType_PlayerCity}
+			if !wm.AddUnit(unit) {
+				t.Fatalf("角落 %v 的单位加入地图失败", corner)
+			}
+			if grid := hexGridMgr.GetGrid(unit.GetHexCoord()); grid == nil || !grid.IsExistUnit(unit) {
+				t.Errorf("角落 %v 的单位应该在六边形网格中：得到 %v", corner, unit.GetHexCoord())
+			}
+
+			troop := troopMgr.CreateTroop(1, NewPlayerOwner(1), corner)
+			if troop == nil || !troopMgr.MarchTo(troop, center, 100, time.Now()) {
+				t.Errorf("从角落 %v 出发行军失败", corner)
+			}
+		}
+
+		if wm.AddUnit(&TestUnit{id: 100, coord: *geo.NewCoord(1000, 0), unitType: MapUnitType_PlayerCity}) {
+			t.Error("地图外的单位不应该加入地图")
+		}
+	}
+}
@@ -11,6 +11,7 @@ const (
 	MapUnitType_Npc                      // NPC
 	MapUnitType_Resource                 // 资源点
 	MapUnitType_ResourceZone             // 资源区域
+	MapUnitType_Watchtower               // 瞭望塔
 )
 
 // 资源类型
@@ -48,11 +49,25 @@ const (
 type Relation int32
 
 const (
-	Relation_Self   Relation = iota + 1 // 自己
-	Relation_Union                      // 同盟
-	Relation_Enemy                      // 敌人
-	Relation_System                     // 系统
-	Relation_All                        // 任意关系
+	Relation_Self    Relation = iota + 1 // 自己
+	Relation_Union                       // 同盟
+	Relation_Enemy                       // 敌人
+	Relation_System                      // 系统
+	Relation_All                         // 任意关系
+	Relation_Ally                        // 友盟（联盟间结盟）
+	Relation_Nap                         // 互不侵犯
+	Relation_Neutral                     // 中立
+)
+
+// 联盟之间的外交立场
+type AllianceStance int32
+
+const (
+	AllianceStance_None    AllianceStance = iota // 未设置（使用默认立场）
+	AllianceStance_Ally                          // 结盟
+	AllianceStance_Nap                           // 互不侵犯
+	AllianceStance_War                           // 宣战
+	AllianceStance_Neutral                       // 中立
 )
 
 // 地图单位所有者类型
@@ -0,0 +1,485 @@
+package worldmap
+
+import (
+	"slices"
+
+	"github.com/GooLuck/WorldMap/internal/worldmap/config"
+	"github.com/GooLuck/WorldMap/internal/worldmap/geo"
+)
+
+// UnitSnapshot 单位的静态快照，用于迷雾中已探索但当前不可见的六边形
+type UnitSnapshot struct {
+	Id       int64        // 单位id
+	ConfigId int32        // 配置id
+	Type     MapUnitType  // 单位类型
+	Owner    Owner        // 快照时的所属者
+	Coord    geo.Coord    // 矩形坐标
+	Hex      geo.HexCoord // 所在六边形
+}
+
+// NewUnitSnapshot 记录单位当前的静态状态
+func NewUnitSnapshot(unit Unit, hex *geo.HexCoord) *UnitSnapshot {
+	snapshot := &UnitSnapshot{
+		Id:       unit.GetId(),
+		ConfigId: unit.GetConfigId(),
+		Type:     unit.GetType(),
+		Coord:    *unit.GetCoord(),
+		Hex:      *hex,
+	}
+	if owner := unit.GetOwner(); owner != nil {
+		snapshot.Owner = *owner
+	}
+	return snapshot
+}
+
+// isStaticUnitType 是否为静态单位（迷雾中保留最后已知状态），部队和NPC会移动，不保留
+func isStaticUnitType(unitType MapUnitType) bool {
+	switch unitType {
+	case MapUnitType_PlayerCity, MapUnitType_Resource, MapUnitType_ResourceZone, MapUnitType_Obstacle:
+		return true
+	}
+	return false
+}
+
+// VisionUnit 自带视野半径的单位（优先于按单位类型配置的视野）
+type VisionUnit interface {
+	GetVisionRange() int32
+}
+
+// PlayerFog 玩家的战争迷雾：已探索的六边形和当前可见的六边形
+type PlayerFog struct {
+	playerId  int64
+	fogMgr    *FogManager
+	explored  map[uint64]bool
+	visible   map[uint64]int32           // 六边形 -> 能看到该六边形的视野来源数量
+	lastKnown map[uint64][]*UnitSnapshot // 六边形离开视野时的静态单位
+	changed   map[uint64]bool            // 进入或离开视野、尚未通知观察者的六边形
+}
+
+func newPlayerFog(fogMgr *FogManager, playerId int64) *PlayerFog {
+	return &PlayerFog{
+		playerId:  playerId,
+		fogMgr:    fogMgr,
+		explored:  make(map[uint64]bool),
+		visible:   make(map[uint64]int32),
+		lastKnown: make(map[uint64][]*UnitSnapshot),
+		changed:   make(map[uint64]bool),
+	}
+}
+
+// GetPlayerId 获取玩家id
+func (pf *PlayerFog) GetPlayerId() int64 {
+	return pf.playerId
+}
+
+// IsExplored 六边形是否已探索
+func (pf *PlayerFog) IsExplored(hex *geo.HexCoord) bool {
+	return pf.explored[hex.Hash()]
+}
+
+// IsHexVisible 六边形当前是否可见
+func (pf *PlayerFog) IsHexVisible(hex *geo.HexCoord) bool {
+	return pf.visible[hex.Hash()] > 0
+}
+
+// IsUnitVisible 单位是否可见：自己的单位始终可见，其他单位所在六边形必须可见
+func (pf *PlayerFog) IsUnitVisible(unit Unit) bool {
+	if owner := unit.GetOwner(); owner != nil && owner.Type == OwnerType_Player && owner.Id == pf.playerId {
+		return true
+	}
+	return pf.IsHexVisible(pf.fogMgr.unitHex(unit))
+}
+
+// GetLastKnown 获取已探索但不可见六边形中最后已知的静态单位，可见或未探索时返回nil
+func (pf *PlayerFog) GetLastKnown(hex *geo.HexCoord) []*UnitSnapshot {
+	key := hex.Hash()
+	if pf.visible[key] > 0 || !pf.explored[key] {
+		return nil
+	}
+	return pf.lastKnown[key]
+}
+
+// RangeLastKnown 遍历所有已探索但不可见六边形中最后已知的静态单位
+func (pf *PlayerFog) RangeLastKnown(f func(snapshot *UnitSnapshot) bool) {
+	for _, snapshots := range pf.lastKnown {
+		for _, snapshot := range snapshots {
+			if !f(snapshot) {
+				return
+			}
+		}
+	}
+}
+
+// GetExploredCount 获取已探索的六边形数量
+func (pf *PlayerFog) GetExploredCount() int32 {
+	return int32(len(pf.explored))
+}
+
+// GetVisibleCount 获取当前可见的六边形数量
+func (pf *PlayerFog) GetVisibleCount() int32 {
+	return int32(len(pf.visible))
+}
+
+// addVision 视野来源看到六边形，第一个来源看到时进入视野
+func (pf *PlayerFog) addVision(key uint64) {
+	pf.visible[key]++
+	if pf.visible[key] == 1 {
+		pf.explored[key] = true
+		delete(pf.lastKnown, key)
+		pf.changed[key] = true
+	}
+}
+
+// removeVision 视野来源不再看到六边形，最后一个来源离开时记录最后已知的静态单位
+func (pf *PlayerFog) removeVision(key uint64) {
+	if pf.visible[key] > 1 {
+		pf.visible[key]--
+		return
+	}
+	delete(pf.visible, key)
+	pf.lastKnown[key] = pf.fogMgr.snapshotHex(key)
+	pf.changed[key] = true
+}
+
+// visionSource 视野来源（主城、部队、瞭望塔）
+type visionSource struct {
+	unit      Unit
+	playerId  int64
+	hex       *geo.HexCoord // 计算视野时所在六边形
+	hexes     []uint64      // 可见的六边形
+	receivers []*PlayerFog  // 获得该视野的迷雾（所有者，以及共享视野的联盟成员）
+}
+
+// FogManager 战争迷雾管理器
+// 视野来源加入、移动、离开时增量更新可见六边形，每个六边形记录能看到它的来源数量
+type FogManager struct {
+	worldMap      *WorldMap
+	fogConfig     *config.FogConfig
+	shareAlliance bool                              // 是否共享联盟成员的视野
+	fogs          map[int64]*PlayerFog              // 玩家id -> 迷雾
+	sources       map[int64]*visionSource           // 单位id -> 视野来源
+	playerSources map[int64]map[int64]*visionSource // 玩家id -> 视野来源
+}
+
+// NewFogManager 创建战争迷雾管理器
+func NewFogManager(worldMap *WorldMap, fogConfig *config.FogConfig) *FogManager {
+	return &FogManager{
+		worldMap:      worldMap,
+		fogConfig:     fogConfig,
+		shareAlliance: fogConfig.ShareAlliance,
+		fogs:          make(map[int64]*PlayerFog),
+		sources:       make(map[int64]*visionSource),
+		playerSources: make(map[int64]map[int64]*visionSource),
+	}
+}
+
+// IsEnabled 是否启用战争迷雾
+func (fm *FogManager) IsEnabled() bool {
+	return fm.fogConfig.Enabled
+}
+
+// SetShareAlliance 设置是否共享联盟成员的视野
+func (fm *FogManager) SetShareAlliance(share bool) {
+	if fm.shareAlliance == share {
+		return
+	}
+	fm.shareAlliance = share
+	fm.Rebuild()
+}
+
+// AddPlayer 为玩家创建迷雾，已存在时返回原迷雾（保留探索记录）
+func (fm *FogManager) AddPlayer(playerId int64) *PlayerFog {
+	if fog, exists := fm.fogs[playerId]; exists {
+		return fog
+	}
+	fog := newPlayerFog(fm, playerId)
+	fm.fogs[playerId] = fog
+	for _, source := range fm.getSharedSources(playerId) {
+		fm.attach(source, fog)
+	}
+	fm.notifyChanges()
+	return fog
+}
+
+// RemovePlayer 删除玩家的迷雾
+func (fm *FogManager) RemovePlayer(playerId int64) {
+	fog, exists := fm.fogs[playerId]
+	if !exists {
+		return
+	}
+	delete(fm.fogs, playerId)
+	for _, source := range fm.sources {
+		source.receivers = slices.DeleteFunc(source.receivers, func(receiver *PlayerFog) bool {
+			return receiver == fog
+		})
+	}
+}
+
+// GetFog 获取玩家的迷雾
+func (fm *FogManager) GetFog(playerId int64) *PlayerFog {
+	return fm.fogs[playerId]
+}
+
+// GetVisionRange 获取单位的视野半径（含所在地形的加成），不提供视野的单位返回-1
+func (fm *FogManager) GetVisionRange(unit Unit) int32 {
+	var vision int32
+	if visionUnit, ok := unit.(VisionUnit); ok && visionUnit.GetVisionRange() > 0 {
+		vision = visionUnit.GetVisionRange()
+	} else {
+		switch unit.GetType() {
+		case MapUnitType_PlayerCity:
+			vision = fm.fogConfig.CityVision
+		case MapUnitType_PlayerTroop:
+			vision = fm.fogConfig.TroopVision
+		case MapUnitType_Watchtower:
+			vision = fm.fogConfig.WatchtowerVision
+		default:
+			return -1
+		}
+		if vision <= 0 {
+			vision = fm.worldMap.GetConfig().DefaultVisionRange
+		}
+	}
+
+	vision += fm.worldMap.getTerrainConfig(fm.unitHex(unit)).VisionBonus
+	return max(vision, 0)
+}
+
+// OnUnitAdded 单位加入地图，玩家的主城、部队、瞭望塔成为视野来源
+func (fm *FogManager) OnUnitAdded(unit Unit) {
+	if !fm.IsEnabled() || fm.GetVisionRange(unit) < 0 {
+		return
+	}
+	owner := unit.GetOwner()
+	if owner == nil || owner.Type != OwnerType_Player {
+		return
+	}
+
+	source := &visionSource{unit: unit, playerId: owner.Id}
+	fm.sources[unit.GetId()] = source
+	sources, exists := fm.playerSources[owner.Id]
+	if !exists {
+		sources = make(map[int64]*visionSource)
+		fm.playerSources[owner.Id] = sources
+	}
+	sources[unit.GetId()] = source
+
+	fm.computeVision(source)
+	for _, fog := range fm.getReceivers(owner.Id) {
+		fm.attach(source, fog)
+	}
+	fm.notifyChanges()
+}
+
+// OnUnitRemoved 视野来源离开地图
+func (fm *FogManager) OnUnitRemoved(unit Unit) {
+	source, exists := fm.sources[unit.GetId()]
+	if !exists {
+		return
+	}
+	fm.detachAll(source)
+	delete(fm.sources, unit.GetId())
+	delete(fm.playerSources[source.playerId], unit.GetId())
+	if len(fm.playerSources[source.playerId]) == 0 {
+		delete(fm.playerSources, source.playerId)
+	}
+	fm.notifyChanges()
+}
+
+// OnUnitMoved 视野来源移动到其他六边形时只更新进出视野的六边形
+func (fm *FogManager) OnUnitMoved(unit Unit) {
+	source, exists := fm.sources[unit.GetId()]
+	if !exists || fm.unitHex(unit).Equal(source.hex) {
+		return
+	}
+	fm.UpdateSource(unit)
+}
+
+// UpdateSource 重新计算视野来源（移动、视野半径变化后调用）
+func (fm *FogManager) UpdateSource(unit Unit) {
+	source, exists := fm.sources[unit.GetId()]
+	if !exists {
+		return
+	}
+
+	oldHexes := source.hexes
+	fm.computeVision(source)
+	// 先加后减，仍然可见的六边形不会短暂离开视野
+	for _, fog := range source.receivers {
+		for _, key := range source.hexes {
+			fog.addVision(key)
+		}
+		for _, key := range oldHexes {
+			fog.removeVision(key)
+		}
+	}
+	fm.notifyChanges()
+}
+
+// OnRelationChanged 联盟成员变化时重新分配共享视野
+// 只有变化的玩家和新旧联盟成员的视野来源需要重新分配，视野范围不变，不重新计算
+func (fm *FogManager) OnRelationChanged(change *RelationChange) {
+	if !fm.shareAlliance || change.PlayerId == 0 {
+		return
+	}
+	for playerId, sources := range fm.playerSources {
+		if playerId != change.PlayerId && !slices.Contains(change.AllianceIds, fm.worldMap.relations.GetPlayerAlliance(playerId)) {
+			continue
+		}
+		for _, source := range sources {
+			fm.reattach(source)
+		}
+	}
+	fm.notifyChanges()
+}
+
+// Rebuild 重新分配所有视野来源（地形变化、共享设置变化后调用）
+func (fm *FogManager) Rebuild() {
+	for _, source := range fm.sources {
+		fm.detachAll(source)
+	}
+	for _, source := range fm.sources {
+		fm.computeVision(source)
+		for _, fog := range fm.getReceivers(source.playerId) {
+			fm.attach(source, fog)
+		}
+	}
+	fm.notifyChanges()
+}
+
+// notifyChanges 通知观察者进入和离开视野的六边形，观察者按这些六边形中的单位产生进入和离开事件
+func (fm *FogManager) notifyChanges() {
+	for _, fog := range fm.fogs {
+		if len(fog.changed) == 0 {
+			continue
+		}
+		changed := fog.changed
+		fog.changed = make(map[uint64]bool)
+		if observerMgr := fm.worldMap.observerMgr; observerMgr != nil {
+			observerMgr.OnFogChanged(fog.playerId, changed)
+		}
+	}
+}
+
+// attach 迷雾获得视野来源的视野
+func (fm *FogManager) attach(source *visionSource, fog *PlayerFog) {
+	source.receivers = append(source.receivers, fog)
+	for _, key := range source.hexes {
+		fog.addVision(key)
+	}
+}
+
+// reattach 按当前的联盟关系重新分配视野来源的迷雾，只更新失去和获得该视野的迷雾
+func (fm *FogManager) reattach(source *visionSource) {
+	receivers := fm.getReceivers(source.playerId)
+	for _, fog := range source.receivers {
+		if !slices.Contains(receivers, fog) {
+			for _, key := range source.hexes {
+				fog.removeVision(key)
+			}
+		}
+	}
+	for _, fog := range receivers {
+		if !slices.Contains(source.receivers, fog) {
+			for _, key := range source.hexes {
+				fog.addVision(key)
+			}
+		}
+	}
+	source.receivers = receivers
+}
+
+// detachAll 所有迷雾失去视野来源的视野
+func (fm *FogManager) detachAll(source *visionSource) {
+	for _, fog := range source.receivers {
+		for _, key := range source.hexes {
+			fog.removeVision(key)
+		}
+	}
+	source.receivers = nil
+}
+
+// getReceivers 获取能获得玩家视野的迷雾：玩家本人，共享视野时还包括同联盟成员
+func (fm *FogManager) getReceivers(playerId int64) []*PlayerFog {
+	receivers := make([]*PlayerFog, 0, 1)
+	if fog, exists := fm.fogs[playerId]; exists {
+		receivers = append(receivers, fog)
+	}
+	allianceId := fm.worldMap.relations.GetPlayerAlliance(playerId)
+	if !fm.shareAlliance || allianceId == 0 {
+		return receivers
+	}
+	for memberId, fog := range fm.fogs {
+		if memberId != playerId && fm.worldMap.relations.GetPlayerAlliance(memberId) == allianceId {
+			receivers = append(receivers, fog)
+		}
+	}
+	return receivers
+}
+
+// getSharedSources 获取玩家能获得视野的所有来源：自己的，共享视野时还包括同联盟成员的
+func (fm *FogManager) getSharedSources(playerId int64) []*visionSource {
+	result := make([]*visionSource, 0, len(fm.playerSources[playerId]))
+	for _, source := range fm.playerSources[playerId] {
+		result = append(result, source)
+	}
+	allianceId := fm.worldMap.relations.GetPlayerAlliance(playerId)
+	if !fm.shareAlliance || allianceId == 0 {
+		return result
+	}
+	for memberId, sources := range fm.playerSources {
+		if memberId == playerId || fm.worldMap.relations.GetPlayerAlliance(memberId) != allianceId {
+			continue
+		}
+		for _, source := range sources {
+			result = append(result, source)
+		}
+	}
+	return result
+}
+
+// computeVision 计算视野来源可见的六边形：视线不能被障碍物阻挡，不可见地形只有站在其中才能看到
+func (fm *FogManager) computeVision(source *visionSource) {
+	source.hex = fm.unitHex(source.unit).Clone()
+	source.hexes = source.hexes[:0:0]
+
+	vision := fm.GetVisionRange(source.unit)
+	hexGridMgr := fm.worldMap.hexGridMgr
+	for _, grid := range hexGridMgr.GetVisionRange(source.hex, vision) {
+		hex := grid.GetCoord()
+		if !hex.Equal(source.hex) {
+			if !fm.worldMap.getTerrainConfig(hex).Visible {
+				continue
+			}
+			if !hexGridMgr.IsVisible(source.hex, hex, vision) {
+				continue
+			}
+		}
+		source.hexes = append(source.hexes, hex.Hash())
+	}
+}
+
+// snapshotHex 记录六边形中的静态单位
+func (fm *FogManager) snapshotHex(key uint64) []*UnitSnapshot {
+	grid := fm.worldMap.hexGridMgr.grids[key]
+	if grid == nil {
+		return nil
+	}
+
+	snapshots := make([]*UnitSnapshot, 0)
+	grid.RangeUnits(func(unit Unit) bool {
+		if isStaticUnitType(unit.GetType()) {
+			snapshots = append(snapshots, NewUnitSnapshot(unit, grid.GetCoord()))
+		}
+		return true
+	})
+	return snapshots
+}
+
+// unitHex 获取单位所在六边形（未设置时按矩形坐标换算）
+func (fm *FogManager) unitHex(unit Unit) *geo.HexCoord {
+	if hex := unit.GetHexCoord(); hex != nil {
+		return hex
+	}
+	return fm.worldMap.coordToHex(unit.GetCoord())
+}
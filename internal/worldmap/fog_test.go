@@ -0,0 +1,156 @@
+package worldmap
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/GooLuck/WorldMap/internal/worldmap/config"
+	"github.com/GooLuck/WorldMap/internal/worldmap/geo"
+)
+
+// TestFogOfWar 测试战争迷雾的可见范围、探索记录和最后已知状态
+func TestFogOfWar(t *testing.T) {
+	mapConfig := newTestMapConfig()
+	mapConfig.DefaultVisionRange = 1
+	mapConfig.Fog.Enabled = true
+	wm := NewWorldMap(mapConfig)
+	troopMgr := wm.GetTroopManager()
+	stepLen := math.Sqrt(3) * wm.GetHexGridManager().GetLayout().Radius
+	fullRect := geo.NewRectangle(0, 0, 1000, 1000)
+
+	nearHex, farHex := geo.NewHexCoord(2, 1), geo.NewHexCoord(5, 4)
+	wm.AddUnit(&TestUnit{id: 1, coord: *wm.hexToCoord(nearHex), hexCoord: nearHex, unitType: MapUnitType_PlayerCity, owner: NewPlayerOwner(2)})
+	wm.AddUnit(&TestUnit{id: 2, coord: *wm.hexToCoord(farHex), hexCoord: farHex, unitType: MapUnitType_PlayerCity, owner: NewPlayerOwner(2)})
+	troop := troopMgr.CreateTroop(1, NewPlayerOwner(1), wm.hexToCoord(geo.NewHexCoord(1, 1)))
+
+	observer := wm.GetObserverManager().AddObserver(1, fullRect, 0)
+	fog := observer.GetFog()
+	if fog == nil {
+		t.Fatal("启用战争迷雾后观察者应该有迷雾")
+	}
+	if fog.GetVisibleCount() != 7 {
+		t.Errorf("可见六边形数量错误：期望 7, 得到 %d", fog.GetVisibleCount())
+	}
+
+	units := wm.GetVisibleUnits(1, fullRect)
+	if len(units) != 2 || units[1] == nil || units[troop.GetId()] == nil {
+		t.Errorf("可见单位错误：得到 %v", units)
+	}
+
+	// 部队离开后，附近的主城进入迷雾，保留最后已知状态
+	now := time.Now()
+	if !troopMgr.MarchTo(troop, geo.NewHexCoord(4, 1), stepLen, now) {
+		t.Fatal("行军失败")
+	}
+	wm.Update(now.Add(3 * time.Second))
+	if fog.IsHexVisible(nearHex) || !fog.IsExplored(nearHex) {
+		t.Error("主城所在六边形应该已探索但不可见")
+	}
+	if fog.IsExplored(farHex) {
+		t.Error("远处的六边形不应该被探索")
+	}
+	if _, exists := wm.GetVisibleUnits(1, fullRect)[1]; exists {
+		t.Error("迷雾中的主城不应该可见")
+	}
+
+	wm.RemoveUnit(wm.GetHexGridManager().GetGrid(nearHex).GetUnitById(1))
+	snapshots := wm.GetLastKnownUnits(1, fullRect)
+	if len(snapshots) != 1 || snapshots[0].Id != 1 || snapshots[0].Owner.Id != 2 {
+		t.Fatalf("最后已知单位错误：期望 1, 得到 %d", len(snapshots))
+	}
+
+	// 重新进入视野后以实际状态为准
+	if !troopMgr.MarchTo(troop, geo.NewHexCoord(2, 1), stepLen, now.Add(3*time.Second)) {
+		t.Fatal("行军失败")
+	}
+	wm.Update(now.Add(5 * time.Second))
+	if !fog.IsHexVisible(nearHex) || len(fog.GetLastKnown(nearHex)) != 0 {
+		t.Error("重新进入视野后不应该保留最后已知状态")
+	}
+}
+
+// TestFogLineOfSight 测试障碍物阻挡视线
+func TestFogLineOfSight(t *testing.T) {
+	mapConfig := newTestMapConfig()
+	mapConfig.Fog.Enabled = true
+	mapConfig.Fog.TroopVision = 2
+	wm := NewWorldMap(mapConfig)
+
+	obstacleHex := geo.NewHexCoord(2, 1)
+	wm.AddUnit(&TestUnit{id: 1, coord: *wm.hexToCoord(obstacleHex), hexCoord: obstacleHex, unitType: MapUnitType_Obstacle})
+	wm.GetTroopManager().CreateTroop(1, NewPlayerOwner(1), wm.hexToCoord(geo.NewHexCoord(1, 1)))
+
+	fog := wm.GetFogManager().AddPlayer(1)
+	if !fog.IsHexVisible(obstacleHex) {
+		t.Error("障碍物所在六边形应该可见")
+	}
+	if fog.IsHexVisible(geo.NewHexCoord(3, 1)) {
+		t.Error("障碍物后面的六边形不应该可见")
+	}
+	if !fog.IsHexVisible(geo.NewHexCoord(1, 3)) {
+		t.Error("未被阻挡的六边形应该可见")
+	}
+}
+
+// TestFogVisionSources 测试视野来源的地形加成、移动时的增量更新以及联盟共享视野
+func TestFogVisionSources(t *testing.T) {
+	mapConfig := newTestMapConfig()
+	mapConfig.Fog = config.FogConfig{Enabled: true, ShareAlliance: true, CityVision: 1, WatchtowerVision: 2}
+	wm := NewWorldMap(mapConfig)
+	registry := wm.GetRelationRegistry()
+	registry.SetPlayerAlliance(1, 100)
+	registry.SetPlayerAlliance(2, 100)
+
+	terrainMap := NewTerrainMap(wm.GetHexGridManager().GetBounds())
+	towerHex := geo.NewHexCoord(1, 4)
+	terrainMap.SetTerrain(towerHex, TerrainType_Mountain)
+	wm.SetTerrainMap(terrainMap)
+
+	fog := wm.GetFogManager().AddPlayer(1)
+	tower := &TestUnit{id: 1, coord: *wm.hexToCoord(towerHex), hexCoord: towerHex, unitType: MapUnitType_Watchtower, owner: NewPlayerOwner(1)}
+	wm.AddUnit(tower)
+
+	// 山地上的瞭望塔视野 +1
+	if !fog.IsHexVisible(geo.NewHexCoord(4, 4)) || fog.IsHexVisible(geo.NewHexCoord(5, 4)) {
+		t.Error("山地瞭望塔的视野半径应该为 3")
+	}
+
+	// 同盟成员的主城共享视野
+	allyHex := geo.NewHexCoord(6, 0)
+	wm.AddUnit(&TestUnit{id: 2, coord: *wm.hexToCoord(allyHex), hexCoord: allyHex, unitType: MapUnitType_PlayerCity, owner: NewPlayerOwner(2)})
+	if !fog.IsHexVisible(geo.NewHexCoord(5, 0)) {
+		t.Error("应该获得同盟成员的视野")
+	}
+	registry.SetPlayerAlliance(2, 0)
+	if fog.IsHexVisible(geo.NewHexCoord(5, 0)) {
+		t.Error("成员退出联盟后不应该继续共享视野")
+	}
+
+	// 成员变化只重新分配视野，不重新计算视野范围（直接修改地形后没有 Rebuild，瞭望塔的视野保持不变）
+	terrainMap.SetTerrain(towerHex, TerrainType_Plain)
+	fog2 := wm.GetFogManager().AddPlayer(2)
+	registry.SetPlayerAlliance(2, 100)
+	if !fog.IsHexVisible(geo.NewHexCoord(5, 0)) || !fog2.IsHexVisible(geo.NewHexCoord(4, 4)) {
+		t.Error("重新加入联盟后应该恢复共享视野")
+	}
+	if fog.GetVisibleCount() != fog2.GetVisibleCount() {
+		t.Errorf("同盟成员的可见六边形数量应该相同：%d != %d", fog.GetVisibleCount(), fog2.GetVisibleCount())
+	}
+	registry.SetPlayerAlliance(2, 0)
+	terrainMap.SetTerrain(towerHex, TerrainType_Mountain)
+
+	// 移动瞭望塔，只有离开视野的六边形进入迷雾
+	wm.MoveUnitToHex(tower, geo.NewHexCoord(1, 0))
+	if fog.IsHexVisible(geo.NewHexCoord(4, 4)) || !fog.IsExplored(geo.NewHexCoord(4, 4)) {
+		t.Error("移动后原视野应该进入迷雾")
+	}
+	if !fog.IsHexVisible(geo.NewHexCoord(3, 0)) {
+		t.Error("移动后应该看到新位置周围")
+	}
+
+	wm.RemoveUnit(tower)
+	if fog.GetVisibleCount() != 0 {
+		t.Errorf("视野来源移除后可见六边形数量错误：期望 0, 得到 %d", fog.GetVisibleCount())
+	}
+}
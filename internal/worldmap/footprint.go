@@ -0,0 +1,109 @@
+package worldmap
+
+import "github.com/GooLuck/WorldMap/internal/worldmap/geo"
+
+// Footprint 单位占地范围：矩形占地用于矩形网格，六边形半径用于六边形网格
+// 有占地范围的单位注册到占地覆盖的所有网格和六边形中，占地矩形应包含单位坐标
+type Footprint struct {
+	OffsetX   int32 // 占地矩形左上角相对单位坐标的偏移
+	OffsetY   int32
+	Width     int32 // 占地矩形宽度（世界单位）
+	Height    int32 // 占地矩形高度（世界单位）
+	HexRadius int32 // 六边形占地半径（步数，0表示只占单位所在六边形）
+}
+
+// NewRectFootprint 创建以单位坐标为左上角的矩形占地（障碍物等）
+func NewRectFootprint(width, height int32) *Footprint {
+	return &Footprint{Width: max(width, 1), Height: max(height, 1)}
+}
+
+// NewCenterFootprint 创建以单位坐标为中心的占地（主城、建筑等）
+// radius: 矩形占地半径（世界单位），hexRadius: 六边形占地半径（步数）
+func NewCenterFootprint(radius, hexRadius int32) *Footprint {
+	return &Footprint{
+		OffsetX:   -radius,
+		OffsetY:   -radius,
+		Width:     2*radius + 1,
+		Height:    2*radius + 1,
+		HexRadius: hexRadius,
+	}
+}
+
+// GetRect 单位位于 coord 时的占地矩形
+func (f *Footprint) GetRect(coord *geo.Coord) *geo.Rectangle {
+	return geo.NewRectangle(coord.X+f.OffsetX, coord.Y+f.OffsetY, f.Width, f.Height)
+}
+
+// FootprintUnit 有占地范围的单位
+// GetFootprint 返回nil时只占单位坐标所在的一个网格和一个六边形，占地范围需要在单位加入地图前设置
+type FootprintUnit interface {
+	GetFootprint() *Footprint
+}
+
+// getFootprint 获取单位的占地范围（没有时返回nil）
+func getFootprint(unit Unit) *Footprint {
+	if footprintUnit, ok := unit.(FootprintUnit); ok {
+		return footprintUnit.GetFootprint()
+	}
+	return nil
+}
+
+// unitFootprintRect 单位位于 coord 时的占地矩形（没有占地范围时为坐标所在的1x1矩形）
+func unitFootprintRect(unit Unit, coord *geo.Coord) *geo.Rectangle {
+	if footprint := getFootprint(unit); footprint != nil {
+		return footprint.GetRect(coord)
+	}
+	return geo.NewRectangle(coord.X, coord.Y, 1, 1)
+}
+
+// unitHexRadius 单位的六边形占地半径
+func unitHexRadius(unit Unit) int32 {
+	if footprint := getFootprint(unit); footprint != nil {
+		return footprint.HexRadius
+	}
+	return 0
+}
+
+// footprintVisited 空间查询中多格单位的去重集合（只记录有占地范围的单位，惰性创建）
+type footprintVisited map[int64]bool
+
+// firstVisit 单位是否第一次遍历到
+func (v *footprintVisited) firstVisit(unit Unit) bool {
+	if getFootprint(unit) == nil {
+		return true
+	}
+	if *v == nil {
+		*v = make(footprintVisited)
+	}
+	if (*v)[unit.GetId()] {
+		return false
+	}
+	(*v)[unit.GetId()] = true
+	return true
+}
+
+// rangeFootprintGrids 遍历单位位于 coord 时占地覆盖的网格（惰性创建），anchor 表示坐标所在的网格
+func (mgr *GridManager) rangeFootprintGrids(unit Unit, coord *geo.Coord, f func(grid *Grid, anchor bool)) {
+	anchorIndex, ok := mgr.calcGridIndex(coord.X, coord.Y)
+	if !ok {
+		anchorIndex = -1
+	}
+
+	rect := unitFootprintRect(unit, coord)
+	minX := max(floorDiv(rect.X, mgr.mapSize.GridWidth), 0)
+	maxX := min(floorDiv(rect.X+rect.Width-1, mgr.mapSize.GridWidth), mgr.gridCols-1)
+	minY := max(floorDiv(rect.Y, mgr.mapSize.GridHeight), 0)
+	maxY := min(floorDiv(rect.Y+rect.Height-1, mgr.mapSize.GridHeight), mgr.gridRows-1)
+	for y := minY; y <= maxY; y++ {
+		for x := minX; x <= maxX; x++ {
+			f(mgr.GetGridByIndex(x, y), y*mgr.gridCols+x == anchorIndex)
+		}
+	}
+}
+
+// rangeFootprintHexes 遍历单位位于 hex 时占地覆盖的六边形网格
+func (hgm *HexGridManager) rangeFootprintHexes(unit Unit, hex *geo.HexCoord, f func(grid *HexGrid)) {
+	for _, grid := range hgm.GetHexGridsInRadius(hex, unitHexRadius(unit)) {
+		f(grid)
+	}
+}
@@ -0,0 +1,76 @@
+package worldmap
+
+import (
+	"testing"
+
+	"github.com/GooLuck/WorldMap/internal/worldmap/config"
+	"github.com/GooLuck/WorldMap/internal/worldmap/geo"
+)
+
+// TestFootprint 测试多格单位注册到占地覆盖的所有网格，以及查询去重和放置检查
+func TestFootprint(t *testing.T) {
+	mapConfig := newTestMapConfig()
+	mapConfig.Obstacles = []config.ObstacleConfig{
+		{ObstacleID: 1, X: 80, Y: 80, Width: 250, Height: 50},
+	}
+	wm := NewWorldMap(mapConfig)
+	gridMgr := wm.GetGridManager()
+
+	// 障碍物注册到矩形覆盖的所有网格，查询时只返回一次
+	if units := gridMgr.GetRectUnits(geo.NewRectangle(300, 100, 50, 50), false, nil); len(units) != 1 {
+		t.Errorf("障碍物右下部分查询数量错误：期望 1, 得到 %d", len(units))
+	}
+	if units := gridMgr.GetRectUnits(geo.NewRectangle(0, 0, 400, 200), true, nil); len(units) != 1 {
+		t.Errorf("对齐查询去重错误：期望 1, 得到 %d", len(units))
+	}
+	if summary := wm.GetClusterSummaries(nil, geo.NewRectangle(0, 0, 400, 200), 4)[0]; summary.Total != 1 {
+		t.Errorf("多格单位聚合统计错误：期望 1, 得到 %d", summary.Total)
+	}
+
+	// 主城占地
+	coord := geo.NewCoord(500, 500)
+	city := NewBaseUnit(1, 0, *coord, wm.coordToHex(coord), MapUnitType_PlayerCity, NewPlayerOwner(1))
+	city.SetFootprint(NewCenterFootprint(60, 1))
+	wm.AddUnit(city)
+
+	hexGridMgr := wm.GetHexGridManager()
+	for _, neighbor := range city.GetHexCoord().GetAllNeighbors() {
+		if grid := hexGridMgr.GetGrid(neighbor); grid != nil && !grid.IsExistUnit(city) {
+			t.Errorf("主城应该注册到相邻六边形 %v", neighbor)
+		}
+	}
+	if units := hexGridMgr.GetUnitsInRadius(city.GetHexCoord(), 3, nil); len(units) != 1 {
+		t.Errorf("六边形范围查询去重错误：期望 1, 得到 %d", len(units))
+	}
+	if units := hexGridMgr.GetAllUnits(); len(units) != 1 {
+		t.Errorf("全部单位去重错误：期望 1, 得到 %d", len(units))
+	}
+
+	// 放置检查按整个占地范围
+	if wm.CanPlaceCity(geo.NewCoord(555, 500), 2) {
+		t.Error("与主城占地重叠的位置不能放置")
+	}
+	if !wm.CanPlaceCity(geo.NewCoord(570, 500), 2) {
+		t.Error("主城占地外的位置应该可以放置")
+	}
+
+	nearest := gridMgr.GetNearestUnits(geo.NewCoord(700, 500), 1, 150, NewUnitFilter(nil, nil).WithUnitTypes(MapUnitType_PlayerCity))
+	if len(nearest) != 1 || nearest[0] != city {
+		t.Error("最近邻应该按到占地矩形的距离计算")
+	}
+
+	// 移动后旧网格和旧六边形中不再有主城
+	oldHex := city.GetHexCoord().Clone()
+	wm.MoveUnitToHex(city, geo.NewHexCoord(1, 1))
+	if hexGridMgr.GetGrid(oldHex).IsExistUnit(city) {
+		t.Error("移动后旧六边形中不应该有主城")
+	}
+	if units := gridMgr.GetRectUnits(geo.NewRectangle(500, 500, 100, 100), false, nil); len(units) != 0 {
+		t.Errorf("移动后旧网格查询数量错误：期望 0, 得到 %d", len(units))
+	}
+
+	wm.RemoveUnit(city)
+	if units := hexGridMgr.GetAllUnits(); len(units) != 0 {
+		t.Errorf("移除后六边形网格中还有 %d 个单位", len(units))
+	}
+}
@@ -0,0 +1,162 @@
+package worldmap
+
+import "time"
+
+// GatherFinishCallback 采集结束回调，amount 为本次采集量
+type GatherFinishCallback func(troop *TroopUnit, resource *ResourceUnit, amount int32, now time.Time)
+
+// gatherSession 部队在资源点上的采集
+type gatherSession struct {
+	troop     *TroopUnit
+	resource  *ResourceUnit
+	startLoad int32     // 开始采集时的携带量
+	lastTime  time.Time // 上次结算时间
+	pending   float64   // 不足1单位的累计采集量
+}
+
+// AddGatherFinishCallback 注册采集结束回调
+func (rm *ResourceManager) AddGatherFinishCallback(callback GatherFinishCallback) {
+	rm.gatherCallbacks = append(rm.gatherCallbacks, callback)
+}
+
+// CanGather 部队是否可以在资源点开始采集
+func (rm *ResourceManager) CanGather(troop *TroopUnit, resource *ResourceUnit) bool {
+	if !resource.IsActive() || resource.GetCurrentAmount() <= 0 || resource.IsOccupied() {
+		return false
+	}
+	if troop.GetGatherRate() <= 0 {
+		return false
+	}
+	loadType, load := troop.GetLoad()
+	if load >= troop.GetCapacity() || (load > 0 && loadType != resource.GetResourceType()) {
+		return false
+	}
+	return true
+}
+
+// StartGather 已到达资源点的部队开始采集并占领资源点
+func (rm *ResourceManager) StartGather(troop *TroopUnit, resource *ResourceUnit, now time.Time) bool {
+	if troop.IsMarching() || troop.IsGathering() || !rm.CanGather(troop, resource) {
+		return false
+	}
+	if !troop.GetHexCoord().Equal(resource.GetHexCoord()) {
+		return false
+	}
+
+	_, startLoad := troop.GetLoad()
+	resource.occupy(troop)
+	troop.startGather(resource.GetResourceType())
+	rm.gathers[resource.GetId()] = &gatherSession{
+		troop:     troop,
+		resource:  resource,
+		startLoad: startLoad,
+		lastTime:  now,
+	}
+	rm.troopGathers[troop.GetId()] = resource.GetId()
+	return true
+}
+
+// StopGather 提前结束采集，结算到当前时间的采集量后返回驻地
+func (rm *ResourceManager) StopGather(troopId int64, now time.Time) bool {
+	session := rm.getTroopGather(troopId)
+	if session == nil {
+		return false
+	}
+
+	rm.settleGather(session, now)
+	rm.finishGather(session, now)
+	return true
+}
+
+// GetGatherTroop 获取资源点上正在采集的部队
+func (rm *ResourceManager) GetGatherTroop(resourceId int64) *TroopUnit {
+	if session, exists := rm.gathers[resourceId]; exists {
+		return session.troop
+	}
+	return nil
+}
+
+// updateGathers 推进所有采集，负重满或资源耗尽时结束
+func (rm *ResourceManager) updateGathers(now time.Time) {
+	finished := make([]*gatherSession, 0)
+	for _, session := range rm.gathers {
+		rm.settleGather(session, now)
+		if _, load := session.troop.GetLoad(); load >= session.troop.GetCapacity() || session.resource.GetCurrentAmount() <= 0 {
+			finished = append(finished, session)
+		}
+	}
+
+	for _, session := range finished {
+		rm.finishGather(session, now)
+	}
+}
+
+// settleGather 结算从上次结算到 now 的采集量
+func (rm *ResourceManager) settleGather(session *gatherSession, now time.Time) {
+	elapsed := now.Sub(session.lastTime).Seconds()
+	if elapsed <= 0 {
+		return
+	}
+	session.lastTime = now
+
+	troop := session.troop
+	_, load := troop.GetLoad()
+	amount := session.pending + elapsed*troop.GetGatherRate()
+	whole := min(int32(amount), troop.GetCapacity()-load)
+	session.pending = amount - float64(whole)
+
+	harvested := session.resource.harvestAt(whole, now)
+	if harvested > 0 {
+		load += harvested
+		troop.setLoad(load)
+		rm.worldMap.observerMgr.NotifyUnitUpdate(session.resource)
+	}
+	if load >= troop.GetCapacity() {
+		session.pending = 0
+	}
+}
+
+// finishGather 结束采集：解除占领，通知游戏服，部队返回驻地
+func (rm *ResourceManager) finishGather(session *gatherSession, now time.Time) {
+	rm.releaseGather(session)
+
+	troop := session.troop
+	troop.stopGather()
+	troop.setIntent(MarchIntent_None, 0)
+	_, load := troop.GetLoad()
+	for _, callback := range rm.gatherCallbacks {
+		callback(troop, session.resource, load-session.startLoad, now)
+	}
+	rm.worldMap.troopMgr.ReturnHome(troop, now)
+}
+
+// releaseGather 解除资源点占领并删除采集记录
+func (rm *ResourceManager) releaseGather(session *gatherSession) {
+	session.resource.release()
+	delete(rm.gathers, session.resource.GetId())
+	delete(rm.troopGathers, session.troop.GetId())
+}
+
+// onTroopRemoved 部队被移除（被消灭等）时释放资源点，已采集的资源随部队一起损失
+func (rm *ResourceManager) onTroopRemoved(troopId int64) {
+	if session := rm.getTroopGather(troopId); session != nil {
+		rm.releaseGather(session)
+	}
+}
+
+func (rm *ResourceManager) getTroopGather(troopId int64) *gatherSession {
+	resourceId, exists := rm.troopGathers[troopId]
+	if !exists {
+		return nil
+	}
+	return rm.gathers[resourceId]
+}
+
+// resolveGather 默认的采集到达结算：开始采集并留在资源点，无法采集时返回
+func (rm *ResourceManager) resolveGather(wm *WorldMap, troop *TroopUnit, target Unit, now time.Time) MarchOutcome {
+	resource := rm.resources[target.GetId()]
+	if resource == nil || !rm.StartGather(troop, resource, now) {
+		return MarchOutcome_Return
+	}
+	return MarchOutcome_Stay
+}
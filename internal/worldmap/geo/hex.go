@@ -64,6 +64,23 @@ func (h *HexCoord) GetAllNeighbors() []*HexCoord {
 	return neighbors
 }
 
+// GetRing 获取距离为 radius 的一圈六边形坐标（radius 为0时只有自身）
+func (h *HexCoord) GetRing(radius int32) []*HexCoord {
+	if radius <= 0 {
+		return []*HexCoord{h.Clone()}
+	}
+
+	ring := make([]*HexCoord, 0, 6*radius)
+	hex := h.Add(hexDirections[4].Multiply(radius))
+	for direction := 0; direction < 6; direction++ {
+		for i := int32(0); i < radius; i++ {
+			ring = append(ring, hex)
+			hex = hex.GetNeighbor(direction)
+		}
+	}
+	return ring
+}
+
 // DistanceTo 计算两个六边形之间的距离（步数）
 func (h *HexCoord) DistanceTo(other *HexCoord) int32 {
 	dq := abs(h.Q - other.Q)
@@ -222,6 +239,152 @@ func (hr *HexRectangle) Contains(h *HexCoord) bool {
 	return h.Q >= hr.MinQ && h.Q <= hr.MaxQ && h.R >= hr.MinR && h.R <= hr.MaxR
 }
 
+// HexMapBounds 覆盖矩形地图的六边形范围（偏移坐标下的矩形）
+// 顶点朝上时按行（r）排列，平边朝上时按列（q）排列，相邻两行错开半个六边形，每一行另一个坐标的范围不同
+type HexMapBounds struct {
+	isPointy bool
+	minLine  int32      // 第一行的行坐标（顶点朝上为 r，平边朝上为 q）
+	ranges   [][2]int32 // 每一行另一个坐标（顶点朝上为 q，平边朝上为 r）的范围 [min, max]
+	count    int32      // 六边形总数
+}
+
+// NewHexMapBounds 计算覆盖 [0, mapWidth) x [0, mapHeight) 地图的六边形范围（布局原点在 (0,0)）
+// 与地图相交（包括边界接触）的六边形都在范围内，地图内任意坐标所在的六边形都不会超出范围
+func NewHexMapBounds(mapWidth, mapHeight int32, radius float64, isPointy bool) *HexMapBounds {
+	// 平边朝上的布局交换 x、y 后与顶点朝上相同：along 为行排列的方向，across 为行内的方向
+	across, along := float64(mapWidth-1), float64(mapHeight-1)
+	if !isPointy {
+		across, along = along, across
+	}
+	lineStep := 1.5 * radius          // 相邻两行中心的距离
+	cellStep := math.Sqrt(3) * radius // 行内相邻六边形中心的距离
+
+	// 先按外接矩形确定每一行的范围，再去掉两端只有外接矩形与地图相交的六边形
+	minLine := int32(math.Ceil(-radius / lineStep))
+	maxLine := int32(math.Floor((along + radius) / lineStep))
+	ranges := make([][2]int32, 0, max(maxLine-minLine+1, 0))
+	for line := minLine; line <= maxLine; line++ {
+		// 六边形中心在行内的位置为 cellStep*(other+line/2)
+		half := float64(line) / 2
+		minOther := int32(math.Ceil(-0.5 - half))
+		maxOther := int32(math.Floor(across/cellStep + 0.5 - half))
+		intersects := func(other int32) bool {
+			return hexIntersectsRect(cellStep*(float64(other)+half), lineStep*float64(line), radius, across, along)
+		}
+		for minOther <= maxOther && !intersects(minOther) {
+			minOther++
+		}
+		for maxOther >= minOther && !intersects(maxOther) {
+			maxOther--
+		}
+		ranges = append(ranges, [2]int32{minOther, maxOther})
+	}
+
+	// 去掉两端没有六边形的行
+	for len(ranges) > 0 && ranges[0][0] > ranges[0][1] {
+		ranges = ranges[1:]
+		minLine++
+	}
+	for len(ranges) > 0 && ranges[len(ranges)-1][0] > ranges[len(ranges)-1][1] {
+		ranges = ranges[:len(ranges)-1]
+	}
+
+	bounds := &HexMapBounds{
+		isPointy: isPointy,
+		minLine:  minLine,
+		ranges:   ranges,
+	}
+	for _, otherRange := range ranges {
+		bounds.count += otherRange[1] - otherRange[0] + 1
+	}
+	return bounds
+}
+
+// hexIntersectsRect 中心在 (cu, cv) 的顶点朝上六边形是否与矩形 [0, width] x [0, height] 相交（包括边界接触）
+// 按分离轴判断：矩形的两条边和六边形两条斜边的法线上投影都重叠才相交
+func hexIntersectsRect(cu, cv, radius, width, height float64) bool {
+	apothem := math.Sqrt(3) / 2 * radius
+	if cu+apothem < 0 || cu-apothem > width || cv+radius < 0 || cv-radius > height {
+		return false
+	}
+	// 斜边法线 (1/2, √3/2) 和 (-1/2, √3/2)
+	sin := math.Sqrt(3) / 2
+	p1 := cu/2 + cv*sin
+	if p1+apothem < 0 || p1-apothem > width/2+height*sin {
+		return false
+	}
+	p2 := -cu/2 + cv*sin
+	return p2+apothem >= -width/2 && p2-apothem <= height*sin
+}
+
+// split 六边形坐标拆分为行坐标和行内坐标
+func (hb *HexMapBounds) split(h *HexCoord) (line, other int32) {
+	if hb.isPointy {
+		return h.R, h.Q
+	}
+	return h.Q, h.R
+}
+
+// join 行坐标和行内坐标组合为六边形坐标
+func (hb *HexMapBounds) join(line, other int32) *HexCoord {
+	if hb.isPointy {
+		return NewHexCoord(other, line)
+	}
+	return NewHexCoord(line, other)
+}
+
+// Contains 检查坐标是否在范围内
+func (hb *HexMapBounds) Contains(h *HexCoord) bool {
+	line, other := hb.split(h)
+	index := line - hb.minLine
+	if index < 0 || index >= int32(len(hb.ranges)) {
+		return false
+	}
+	return other >= hb.ranges[index][0] && other <= hb.ranges[index][1]
+}
+
+// Count 范围内的六边形数量
+func (hb *HexMapBounds) Count() int32 {
+	return hb.count
+}
+
+// Range 遍历范围内的所有六边形，f 返回 false 停止遍历
+func (hb *HexMapBounds) Range(f func(h *HexCoord) bool) {
+	for i, otherRange := range hb.ranges {
+		for other := otherRange[0]; other <= otherRange[1]; other++ {
+			if !f(hb.join(hb.minLine+int32(i), other)) {
+				return
+			}
+		}
+	}
+}
+
+// GetAxialRange 范围在轴向坐标下的外接区间
+func (hb *HexMapBounds) GetAxialRange() (minQ, maxQ, minR, maxR int32) {
+	minLine, maxLine := hb.minLine, hb.minLine+int32(len(hb.ranges))-1
+	minOther, maxOther := int32(math.MaxInt32), int32(math.MinInt32)
+	for _, otherRange := range hb.ranges {
+		minOther = min(minOther, otherRange[0])
+		maxOther = max(maxOther, otherRange[1])
+	}
+	if hb.isPointy {
+		return minOther, maxOther, minLine, maxLine
+	}
+	return minLine, maxLine, minOther, maxOther
+}
+
+// GetLineEnds 每一行两端的六边形（范围的顶点都在其中，可用于计算到范围内最远六边形的距离）
+func (hb *HexMapBounds) GetLineEnds() []*HexCoord {
+	ends := make([]*HexCoord, 0, 2*len(hb.ranges))
+	for i, otherRange := range hb.ranges {
+		if otherRange[0] <= otherRange[1] {
+			line := hb.minLine + int32(i)
+			ends = append(ends, hb.join(line, otherRange[0]), hb.join(line, otherRange[1]))
+		}
+	}
+	return ends
+}
+
 // CalculateGridSize 计算地图边界能容纳的六边形数量
 // mapWidth, mapHeight: 地图大小，单位和半径相同
 // 返回值: q方向数量, r方向数量
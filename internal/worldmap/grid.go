@@ -9,7 +9,10 @@ import (
 
 type Grid struct {
 	geo.Rectangle
-	units []Unit
+	units      []Unit
+	counts     map[summaryKey]int32 // (单位类型, 所属者) -> 数量
+	unitKeys   map[int64]summaryKey // 单位计入的统计键
+	footprints int32                // 有占地范围的单位数量，为0时查询不需要去重
 }
 
 func NewGrid(coord *geo.Coord, width, height int32) *Grid {
@@ -19,13 +22,26 @@ func NewGrid(coord *geo.Coord, width, height int32) *Grid {
 			Width:  width,
 			Height: height,
 		},
-		units: make([]Unit, 0),
+		units:    make([]Unit, 0),
+		counts:   make(map[summaryKey]int32),
+		unitKeys: make(map[int64]summaryKey),
 	}
 }
 
 // 添加地图单位
 func (g *Grid) AddUnit(unit Unit) {
+	g.addUnit(unit, true)
+}
+
+// addUnit 添加地图单位，counted 为false时不计入聚合统计（多格单位只在坐标所在网格计数）
+func (g *Grid) addUnit(unit Unit, counted bool) {
 	g.units = append(g.units, unit)
+	if counted {
+		g.addCount(unit)
+	}
+	if getFootprint(unit) != nil {
+		g.footprints++
+	}
 }
 
 // 移除地图单位
@@ -33,6 +49,10 @@ func (g *Grid) RemoveUnit(unit Unit) {
 	for i, u := range g.units {
 		if u == unit {
 			g.units = append(g.units[:i], g.units[i+1:]...)
+			g.removeCount(unit)
+			if getFootprint(unit) != nil {
+				g.footprints--
+			}
 			return
 		}
 	}
@@ -66,12 +86,12 @@ func MaxGridXY(mapSize *config.MapSize) (maxGridX, maxGridY int32) {
 	return (mapSize.Width - 1) / mapSize.GridWidth, (mapSize.Height - 1) / mapSize.GridHeight
 }
 
-// rect转grid
+// rect转grid（矩形为左闭右开区间，右边界和下边界不属于矩形）
 func RectToGrid(mapSize *config.MapSize, rect *geo.Rectangle) (minGridX, maxGridX, minGridY, maxGridY int32) {
 	maxGridXTmp, maxGridYTmp := MaxGridXY(mapSize)
-	minGridX = int32(math.Max(float64(rect.X)/float64(mapSize.Width), 0))
-	maxGridX = int32(math.Min(float64(rect.X+rect.Width)/float64(mapSize.Width), float64(maxGridXTmp)))
-	minGridY = int32(math.Max(float64(rect.Y)/float64(mapSize.Height), 0))
-	maxGridY = int32(math.Min(float64(rect.Y+rect.Height)/float64(mapSize.Height), float64(maxGridYTmp)))
+	minGridX = int32(math.Max(float64(rect.X)/float64(mapSize.GridWidth), 0))
+	maxGridX = int32(math.Min(math.Floor(float64(rect.X+rect.Width-1)/float64(mapSize.GridWidth)), float64(maxGridXTmp)))
+	minGridY = int32(math.Max(float64(rect.Y)/float64(mapSize.GridHeight), 0))
+	maxGridY = int32(math.Min(math.Floor(float64(rect.Y+rect.Height-1)/float64(mapSize.GridHeight)), float64(maxGridYTmp)))
 	return
 }
@@ -62,6 +62,11 @@ func (gm *GridManager) GetGridByPos(x, y int32) *Grid {
 	return gm.grids[index]
 }
 
+// 通过网格索引获取网格（惰性初始化）
+func (gm *GridManager) GetGridByIndex(gridX, gridY int32) *Grid {
+	return gm.GetGridByPos(gridX*gm.mapSize.GridWidth, gridY*gm.mapSize.GridHeight)
+}
+
 // 通过坐标对象获取网格
 func (gm *GridManager) GetGridByCoord(coord *geo.Coord) *Grid {
 	return gm.GetGridByPos(coord.X, coord.Y)
@@ -95,22 +100,45 @@ func (gm *GridManager) CleanupEmptyGrids() {
 	}
 }
 
+// 添加地图单位，有占地范围的单位添加到占地覆盖的所有网格
 func (mgr *GridManager) AddUnit(unit Unit) {
+	if getFootprint(unit) != nil {
+		mgr.rangeFootprintGrids(unit, unit.GetCoord(), func(grid *Grid, anchor bool) {
+			grid.addUnit(unit, anchor)
+		})
+		return
+	}
+
 	grid := mgr.GetGridByCoord(unit.GetCoord())
 	if grid != nil {
 		grid.AddUnit(unit)
 	}
 }
 
+// 移除地图单位
 func (mgr *GridManager) RemoveUnit(unit Unit) {
+	if getFootprint(unit) != nil {
+		mgr.rangeFootprintGrids(unit, unit.GetCoord(), func(grid *Grid, anchor bool) {
+			grid.RemoveUnit(unit)
+		})
+		return
+	}
+
 	grid := mgr.GetGridByCoord(unit.GetCoord())
 	if grid != nil {
 		grid.RemoveUnit(unit)
 	}
 }
 
-// 更新地图单位的位置
-func (mgr *GridManager) UpdateInitCoord(unit Unit, coord *geo.Coord) {
+// updateUnitCoord 更新地图单位的矩形坐标和所在网格（只由 WorldMap 调用，六边形坐标由 WorldMap 同步更新）
+func (mgr *GridManager) updateUnitCoord(unit Unit, coord *geo.Coord) {
+	if getFootprint(unit) != nil {
+		mgr.RemoveUnit(unit)
+		unit.SetCoord(coord)
+		mgr.AddUnit(unit)
+		return
+	}
+
 	oldGrid := mgr.GetGridByCoord(unit.GetCoord())
 	newGrid := mgr.GetGridByCoord(coord)
 	unit.SetCoord(coord)
@@ -129,16 +157,26 @@ func (mgr *GridManager) UpdateInitCoord(unit Unit, coord *geo.Coord) {
 
 // rect 是否与grid 对齐，对齐的话，就不用一个一个unit判断了，整个grid的unit都满足
 func (mgr *GridManager) isAlignGrid(rect *geo.Rectangle) bool {
-	return rect.X%mgr.mapSize.GridWidth == 0 &&
+	return rect.Width > 0 && rect.Height > 0 &&
+		rect.X%mgr.mapSize.GridWidth == 0 &&
 		rect.Y%mgr.mapSize.GridHeight == 0 &&
 		rect.Width%mgr.mapSize.GridWidth == 0 &&
 		rect.Height%mgr.mapSize.GridHeight == 0
 }
 
-// 获取矩形范围内的单位
-func (mgr *GridManager) GetRectUnits(rect *geo.Rectangle, align bool) []Unit {
+// isUnitInRect 单位是否在矩形范围内（有占地范围的单位按占地矩形相交判断）
+func (mgr *GridManager) isUnitInRect(rect *geo.Rectangle, unit Unit) bool {
+	if getFootprint(unit) != nil {
+		return rect.Intersects(unitFootprintRect(unit, unit.GetCoord()))
+	}
+	return rect.IsCoordInRect(unit.GetCoord())
+}
+
+// 获取矩形范围内满足过滤条件的单位（filter 为nil表示不过滤，多格单位只返回一次）
+func (mgr *GridManager) GetRectUnits(rect *geo.Rectangle, align bool, filter *UnitFilter) []Unit {
 	leftX, rightX, leftY, rightY := RectToGrid(mgr.mapSize, rect)
 	retUnits := make([]Unit, 0)
+	var visited footprintVisited
 
 	if !align && mgr.isAlignGrid(rect) {
 		align = true
@@ -146,19 +184,19 @@ func (mgr *GridManager) GetRectUnits(rect *geo.Rectangle, align bool) []Unit {
 
 	for y := leftY; y <= rightY; y++ {
 		for x := leftX; x <= rightX; x++ {
-			grid := mgr.GetGridByPos(x, y)
+			grid := mgr.GetGridByIndex(x, y)
 			if grid == nil {
 				continue
 			}
 
-			if align {
+			if align && filter == nil && grid.footprints == 0 {
 				retUnits = append(retUnits, grid.GetUnits()...)
 				continue
 			}
 
 			gridUnits := grid.GetUnits()
 			for _, u := range gridUnits {
-				if rect.IsCoordInRect(u.GetCoord()) {
+				if (align || mgr.isUnitInRect(rect, u)) && filter.Match(u) && visited.firstVisit(u) {
 					retUnits = append(retUnits, u)
 				}
 			}
@@ -167,15 +205,32 @@ func (mgr *GridManager) GetRectUnits(rect *geo.Rectangle, align bool) []Unit {
 	return retUnits
 }
 
-// 遍历矩形范围内的单位
-func (mgr *GridManager) RangeRectUnits(rect *geo.Rectangle, align bool, callback func(unit Unit) bool) {
+// 遍历矩形范围内满足过滤条件的单位（多格单位只遍历一次）
+func (mgr *GridManager) RangeRectUnits(rect *geo.Rectangle, align bool, filter *UnitFilter, callback func(unit Unit) bool) {
 	leftX, rightX, leftY, rightY := RectToGrid(mgr.mapSize, rect)
+	var visited footprintVisited
+
+	if !align && mgr.isAlignGrid(rect) {
+		align = true
+	}
 
 	for y := leftY; y <= rightY; y++ {
 		for x := leftX; x <= rightX; x++ {
-			grid := mgr.GetGridByPos(x, y)
-			if grid != nil {
-				grid.RangeUnits(callback)
+			grid := mgr.GetGridByIndex(x, y)
+			if grid == nil {
+				continue
+			}
+
+			for _, u := range grid.GetUnits() {
+				if !align && !mgr.isUnitInRect(rect, u) {
+					continue
+				}
+				if !filter.Match(u) || !visited.firstVisit(u) {
+					continue
+				}
+				if !callback(u) {
+					return
+				}
 			}
 		}
 	}
@@ -0,0 +1,40 @@
+package worldmap
+
+import (
+	"testing"
+
+	"github.com/GooLuck/WorldMap/internal/worldmap/geo"
+)
+
+// TestRectToGrid 测试与网格对齐的矩形只覆盖自身范围内的网格
+func TestRectToGrid(t *testing.T) {
+	wm := NewWorldMap(newTestMapConfig())
+	gridMgr := wm.GetGridManager()
+
+	rect := geo.NewRectangle(0, 0, 100, 100)
+	minX, maxX, minY, maxY := RectToGrid(gridMgr.mapSize, rect)
+	if minX != 0 || maxX != 0 || minY != 0 || maxY != 0 {
+		t.Errorf("对齐矩形的网格范围错误：得到 x[%d,%d] y[%d,%d]", minX, maxX, minY, maxY)
+	}
+	minX, maxX, minY, maxY = RectToGrid(gridMgr.mapSize, geo.NewRectangle(100, 200, 200, 101))
+	if minX != 1 || maxX != 2 || minY != 2 || maxY != 3 {
+		t.Errorf("矩形的网格范围错误：得到 x[%d,%d] y[%d,%d]", minX, maxX, minY, maxY)
+	}
+
+	inside := &TestUnit{id: 1, coord: *geo.NewCoord(99, 99), unitType: MapUnitType_PlayerCity}
+	outside := &TestUnit{id: 2, coord: *geo.NewCoord(150, 150), unitType: MapUnitType_PlayerCity}
+	wm.AddUnit(inside)
+	wm.AddUnit(outside)
+	units := gridMgr.GetRectUnits(rect, false, nil)
+	if len(units) != 1 || units[0] != inside {
+		t.Errorf("对齐矩形内的单位数量错误：期望 1, 得到 %d", len(units))
+	}
+	count := 0
+	gridMgr.RangeRectUnits(rect, false, nil, func(unit Unit) bool {
+		count++
+		return true
+	})
+	if count != 1 {
+		t.Errorf("遍历对齐矩形内的单位数量错误：期望 1, 得到 %d", count)
+	}
+}
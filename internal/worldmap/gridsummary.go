@@ -0,0 +1,129 @@
+package worldmap
+
+import "github.com/GooLuck/WorldMap/internal/worldmap/geo"
+
+// summaryKey 网格聚合统计的键
+// 按所属者计数，关系和联盟在查询时由所属者换算，联盟成员和立场变化不需要重新统计
+type summaryKey struct {
+	unitType MapUnitType
+	owner    Owner
+}
+
+func newSummaryKey(unit Unit) summaryKey {
+	key := summaryKey{unitType: unit.GetType()}
+	if owner := unit.GetOwner(); owner != nil {
+		key.owner = *owner
+	}
+	return key
+}
+
+// addCount 单位计入网格统计
+func (g *Grid) addCount(unit Unit) {
+	key := newSummaryKey(unit)
+	g.unitKeys[unit.GetId()] = key
+	g.counts[key]++
+}
+
+// removeCount 单位移出网格统计（按计入时的键扣减）
+func (g *Grid) removeCount(unit Unit) {
+	key, exists := g.unitKeys[unit.GetId()]
+	if !exists {
+		return
+	}
+	delete(g.unitKeys, unit.GetId())
+	if g.counts[key] <= 1 {
+		delete(g.counts, key)
+	} else {
+		g.counts[key]--
+	}
+}
+
+// ClusterSummary 区域内单位的聚合统计，用于最远缩放等级下按区域显示数量
+type ClusterSummary struct {
+	Rect       geo.Rectangle         // 统计区域（网格或超级格子）
+	Total      int32                 // 单位总数
+	ByType     map[MapUnitType]int32 // 单位类型 -> 数量
+	ByRelation map[Relation]int32    // 与观察者的关系 -> 数量
+	ByAlliance map[int64]int32       // 联盟id -> 数量（不含无联盟的单位）
+}
+
+func newClusterSummary(rect geo.Rectangle) *ClusterSummary {
+	return &ClusterSummary{
+		Rect:       rect,
+		ByType:     make(map[MapUnitType]int32),
+		ByRelation: make(map[Relation]int32),
+		ByAlliance: make(map[int64]int32),
+	}
+}
+
+// UpdateUnitOwner 单位所属者变化后重新计入网格统计
+func (mgr *GridManager) UpdateUnitOwner(unit Unit) {
+	index, ok := mgr.calcGridIndex(unit.GetCoord().X, unit.GetCoord().Y)
+	if !ok || mgr.grids[index] == nil {
+		return
+	}
+	grid := mgr.grids[index]
+	if _, exists := grid.unitKeys[unit.GetId()]; exists {
+		grid.removeCount(unit)
+		grid.addCount(unit)
+	}
+}
+
+// GetClusterSummaries 按超级格子汇总矩形范围覆盖的网格
+// cellSize: 超级格子的边长（网格数，1表示按网格汇总），超级格子按网格索引对齐，相同参数的查询结果区域稳定
+// registry 为nil时不统计关系和联盟；没有单位的超级格子不返回
+func (mgr *GridManager) GetClusterSummaries(rect *geo.Rectangle, cellSize int32, registry *RelationRegistry, viewer *Owner) []*ClusterSummary {
+	if cellSize <= 0 {
+		cellSize = 1
+	}
+	leftX, rightX, leftY, rightY := RectToGrid(mgr.mapSize, rect)
+
+	retSummaries := make([]*ClusterSummary, 0)
+	if leftX > rightX || leftY > rightY {
+		return retSummaries
+	}
+	for cellY := leftY / cellSize; cellY <= rightY/cellSize; cellY++ {
+		for cellX := leftX / cellSize; cellX <= rightX/cellSize; cellX++ {
+			summary := mgr.summarizeCell(cellX, cellY, cellSize, registry, viewer)
+			if summary.Total > 0 {
+				retSummaries = append(retSummaries, summary)
+			}
+		}
+	}
+	return retSummaries
+}
+
+// summarizeCell 汇总一个超级格子内已创建的网格
+func (mgr *GridManager) summarizeCell(cellX, cellY, cellSize int32, registry *RelationRegistry, viewer *Owner) *ClusterSummary {
+	width, height := mgr.mapSize.GridWidth*cellSize, mgr.mapSize.GridHeight*cellSize
+	summary := newClusterSummary(*geo.NewRectangle(cellX*width, cellY*height, width, height))
+
+	relations := make(map[Owner]Relation)
+	for y := cellY * cellSize; y < (cellY+1)*cellSize && y < mgr.gridRows; y++ {
+		for x := cellX * cellSize; x < (cellX+1)*cellSize && x < mgr.gridCols; x++ {
+			grid := mgr.grids[y*mgr.gridCols+x]
+			if grid == nil {
+				continue
+			}
+			for key, count := range grid.counts {
+				summary.Total += count
+				summary.ByType[key.unitType] += count
+				if registry == nil {
+					continue
+				}
+
+				owner := key.owner
+				relation, exists := relations[owner]
+				if !exists {
+					relation = registry.GetRelation(viewer, &owner)
+					relations[owner] = relation
+				}
+				summary.ByRelation[relation] += count
+				if allianceId := registry.GetOwnerAlliance(&owner); allianceId != 0 {
+					summary.ByAlliance[allianceId] += count
+				}
+			}
+		}
+	}
+	return summary
+}
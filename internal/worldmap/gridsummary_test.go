@@ -0,0 +1,64 @@
+package worldmap
+
+import (
+	"testing"
+
+	"github.com/GooLuck/WorldMap/internal/worldmap/geo"
+)
+
+// TestClusterSummaries 测试按网格和超级格子汇总单位数量
+func TestClusterSummaries(t *testing.T) {
+	wm := NewWorldMap(newTestMapConfig())
+	registry := wm.GetRelationRegistry()
+	registry.SetPlayerAlliance(1, 100)
+	registry.SetPlayerAlliance(2, 100)
+	registry.SetPlayerAlliance(3, 200)
+
+	addUnit := func(id int64, x, y int32, unitType MapUnitType, owner *Owner) *TestUnit {
+		unit := &TestUnit{id: id, coord: *geo.NewCoord(x, y), unitType: unitType, owner: owner}
+		wm.AddUnit(unit)
+		return unit
+	}
+	addUnit(1, 10, 10, MapUnitType_PlayerCity, NewPlayerOwner(1))
+	addUnit(2, 20, 20, MapUnitType_PlayerCity, NewPlayerOwner(2))
+	addUnit(3, 150, 50, MapUnitType_PlayerCity, NewPlayerOwner(3))
+	addUnit(4, 160, 60, MapUnitType_PlayerCity, NewPlayerOwner(3))
+	resource := addUnit(5, 30, 150, MapUnitType_Resource, NewOwner(0, OwnerType_System))
+	addUnit(6, 550, 550, MapUnitType_PlayerCity, NewPlayerOwner(3))
+	addUnit(7, 250, 50, MapUnitType_PlayerCity, NewPlayerOwner(3)) // 紧邻对齐矩形右边界外的网格
+
+	viewer := NewPlayerOwner(1)
+	summaries := wm.GetClusterSummaries(viewer, geo.NewRectangle(0, 0, 200, 200), 1)
+	if len(summaries) != 3 {
+		t.Fatalf("按网格汇总数量错误：期望 3, 得到 %d", len(summaries))
+	}
+
+	// 2x2 网格的超级格子
+	summaries = wm.GetClusterSummaries(viewer, geo.NewRectangle(0, 0, 200, 200), 2)
+	if len(summaries) != 1 {
+		t.Fatalf("按超级格子汇总数量错误：期望 1, 得到 %d", len(summaries))
+	}
+	summary := summaries[0]
+	if summary.Total != 5 || summary.ByType[MapUnitType_PlayerCity] != 4 || summary.ByType[MapUnitType_Resource] != 1 {
+		t.Errorf("类型统计错误：总数 %d, 主城 %d", summary.Total, summary.ByType[MapUnitType_PlayerCity])
+	}
+	if summary.ByRelation[Relation_Self] != 1 || summary.ByRelation[Relation_Union] != 1 ||
+		summary.ByRelation[Relation_Enemy] != 2 || summary.ByRelation[Relation_System] != 1 {
+		t.Errorf("关系统计错误：得到 %v", summary.ByRelation)
+	}
+	if summary.ByAlliance[100] != 2 || summary.ByAlliance[200] != 2 {
+		t.Errorf("联盟统计错误：得到 %v", summary.ByAlliance)
+	}
+
+	// 增删和移动单位后增量更新，成员变化在查询时生效
+	wm.RemoveUnit(resource)
+	wm.MoveUnit(wm.GetUnit(3), geo.NewCoord(450, 450))
+	registry.SetPlayerAlliance(2, 200)
+	summary = wm.GetClusterSummaries(viewer, geo.NewRectangle(0, 0, 200, 200), 2)[0]
+	if summary.Total != 3 || summary.ByType[MapUnitType_Resource] != 0 {
+		t.Errorf("增量更新后总数错误：期望 3, 得到 %d", summary.Total)
+	}
+	if summary.ByRelation[Relation_Enemy] != 2 || summary.ByAlliance[200] != 2 {
+		t.Errorf("成员变化后统计错误：得到 %v, %v", summary.ByRelation, summary.ByAlliance)
+	}
+}
@@ -110,9 +110,9 @@ func (g *HexGrid) GetUnitsByType(unitType MapUnitType) []Unit {
 // HexGridManager 六边形网格管理器
 type HexGridManager struct {
 	layout  *geo.HexLayout      // 六边形布局
-	qCount  int32               // q 方向网格数量
-	rCount  int32               // r 方向网格数量
-	bounds  *geo.HexRectangle   // 边界范围
+	qCount  int32               // q 方向跨度（轴向外接区间的宽度）
+	rCount  int32               // r 方向跨度（轴向外接区间的高度）
+	bounds  *geo.HexMapBounds   // 边界范围（覆盖矩形地图的六边形）
 	grids   map[uint64]*HexGrid // 所有网格 (key = hash(q, r))
 	mapSize *config.MapSize     // 地图大小配置
 }
@@ -120,24 +120,19 @@ type HexGridManager struct {
 // NewHexGridManager 创建新的六边形网格管理器
 // mapConfig: 地图配置，hexRadius: 六边形边长
 func NewHexGridManager(mapSize *config.MapSize, hexRadius float64, isPointy bool) *HexGridManager {
-	// 计算能容纳多少个六边形
-	qCount, rCount := geo.CalculateGridSize(float64(mapSize.Width), float64(mapSize.Height), hexRadius, isPointy)
-
 	// 创建布局，原点在 (0,0)
 	layout := geo.NewHexLayout(hexRadius, 0, 0, isPointy)
 
-	// 计算边界范围
-	minQ := int32(0)
-	maxQ := qCount - 1
-	minR := int32(0)
-	maxR := rCount - 1
+	// 计算覆盖整个矩形地图的六边形范围
+	bounds := geo.NewHexMapBounds(mapSize.Width, mapSize.Height, hexRadius, isPointy)
+	minQ, maxQ, minR, maxR := bounds.GetAxialRange()
 
 	hgm := &HexGridManager{
 		layout:  layout,
-		qCount:  qCount,
-		rCount:  rCount,
-		bounds:  geo.NewHexRectangle(minQ, maxQ, minR, maxR),
-		grids:   make(map[uint64]*HexGrid),
+		qCount:  maxQ - minQ + 1,
+		rCount:  maxR - minR + 1,
+		bounds:  bounds,
+		grids:   make(map[uint64]*HexGrid, bounds.Count()),
 		mapSize: mapSize,
 	}
 
@@ -162,14 +157,10 @@ func absInt32(x int32) int32 {
 
 // initializeAllGrids 初始化所有网格
 func (hgm *HexGridManager) initializeAllGrids() {
-	for q := hgm.bounds.MinQ; q <= hgm.bounds.MaxQ; q++ {
-		for r := hgm.bounds.MinR; r <= hgm.bounds.MaxR; r++ {
-			hex := geo.NewHexCoord(q, r)
-			grid := NewHexGrid(hex, hgm.layout)
-			hash := hashHex(q, r)
-			hgm.grids[hash] = grid
-		}
-	}
+	hgm.bounds.Range(func(hex *geo.HexCoord) bool {
+		hgm.grids[hashHex(hex.Q, hex.R)] = NewHexGrid(hex, hgm.layout)
+		return true
+	})
 }
 
 // GetGrid 获取指定六边形坐标的网格
@@ -190,15 +181,15 @@ func (hgm *HexGridManager) GetGridByWorld(worldX, worldY float64) *HexGrid {
 
 // GetGridCount 获取网格总数
 func (hgm *HexGridManager) GetGridCount() int32 {
-	return hgm.qCount * hgm.rCount
+	return hgm.bounds.Count()
 }
 
-// GetQCount 获取 q 方向网格数量
+// GetQCount 获取 q 方向跨度
 func (hgm *HexGridManager) GetQCount() int32 {
 	return hgm.qCount
 }
 
-// GetRCount 获取 r 方向网格数量
+// GetRCount 获取 r 方向跨度
 func (hgm *HexGridManager) GetRCount() int32 {
 	return hgm.rCount
 }
@@ -208,13 +199,14 @@ func (hgm *HexGridManager) Contains(hex *geo.HexCoord) bool {
 	return hgm.bounds.Contains(hex)
 }
 
-// AddUnitToGrid 将单位添加到指定六边形
+// AddUnitToGrid 将单位添加到指定六边形（有六边形占地半径的单位添加到占地覆盖的所有六边形）
 func (hgm *HexGridManager) AddUnitToGrid(unit Unit, hex *geo.HexCoord) bool {
-	grid := hgm.GetGrid(hex)
-	if grid == nil {
+	if hgm.GetGrid(hex) == nil {
 		return false
 	}
-	grid.AddUnit(unit)
+	hgm.rangeFootprintHexes(unit, hex, func(grid *HexGrid) {
+		grid.AddUnit(unit)
+	})
 	return true
 }
 
@@ -224,17 +216,17 @@ func (hgm *HexGridManager) AddUnitToWorld(unit Unit, worldX, worldY float64) boo
 	if grid == nil {
 		return false
 	}
-	grid.AddUnit(unit)
-	return true
+	return hgm.AddUnitToGrid(unit, grid.GetCoord())
 }
 
-// RemoveUnitFromGrid 从指定六边形移除单位
+// RemoveUnitFromGrid 从指定六边形移除单位（包括占地覆盖的所有六边形）
 func (hgm *HexGridManager) RemoveUnitFromGrid(unit Unit, hex *geo.HexCoord) bool {
-	grid := hgm.GetGrid(hex)
-	if grid == nil {
+	if hgm.GetGrid(hex) == nil {
 		return false
 	}
-	grid.RemoveUnit(unit)
+	hgm.rangeFootprintHexes(unit, hex, func(grid *HexGrid) {
+		grid.RemoveUnit(unit)
+	})
 	return true
 }
 
@@ -244,8 +236,7 @@ func (hgm *HexGridManager) RemoveUnitFromWorld(unit Unit, worldX, worldY float64
 	if grid == nil {
 		return false
 	}
-	grid.RemoveUnit(unit)
-	return true
+	return hgm.RemoveUnitFromGrid(unit, grid.GetCoord())
 }
 
 // GetNeighborGrids 获取相邻网格
@@ -276,40 +267,21 @@ func (hgm *HexGridManager) GetNeighborGridsByCoord(hex *geo.HexCoord) []*HexGrid
 // includeOffScreen: 是否包含不在屏幕内的网格
 // f: 遍历回调函数，返回 false 停止遍历
 func (hgm *HexGridManager) RangeInRect(minX, minY, maxX, maxY float64, f func(grid *HexGrid) bool) {
-	// 计算矩形范围覆盖的六边形坐标范围
-	qMin, rMin := hgm.layout.WorldToHex(minX, minY)
-	qMax, rMax := hgm.layout.WorldToHex(maxX, maxY)
-
-	// 取整得到范围边界
-	var minQ, maxQ, minR, maxR int32
-	if qMin < qMax {
-		minQ = int32(math.Floor(qMin))
-		maxQ = int32(math.Ceil(qMax))
-	} else {
-		minQ = int32(math.Floor(qMax))
-		maxQ = int32(math.Ceil(qMin))
-	}
-	if rMin < rMax {
-		minR = int32(math.Floor(rMin))
-		maxR = int32(math.Ceil(rMax))
-	} else {
-		minR = int32(math.Floor(rMax))
-		maxR = int32(math.Ceil(rMin))
-	}
-
-	// 限制在地图范围内
-	if minQ < hgm.bounds.MinQ {
-		minQ = hgm.bounds.MinQ
-	}
-	if maxQ > hgm.bounds.MaxQ {
-		maxQ = hgm.bounds.MaxQ
-	}
-	if minR < hgm.bounds.MinR {
-		minR = hgm.bounds.MinR
-	}
-	if maxR > hgm.bounds.MaxR {
-		maxR = hgm.bounds.MaxR
-	}
+	// 计算矩形范围覆盖的六边形坐标范围（轴向坐标同时依赖 x 和 y，需要取四个角）
+	qLow, qHigh := math.Inf(1), math.Inf(-1)
+	rLow, rHigh := math.Inf(1), math.Inf(-1)
+	for _, corner := range [][2]float64{{minX, minY}, {maxX, minY}, {minX, maxY}, {maxX, maxY}} {
+		q, r := hgm.layout.WorldToHex(corner[0], corner[1])
+		qLow, qHigh = math.Min(qLow, q), math.Max(qHigh, q)
+		rLow, rHigh = math.Min(rLow, r), math.Max(rHigh, r)
+	}
+
+	// 取整得到范围边界，并限制在地图范围内
+	boundMinQ, boundMaxQ, boundMinR, boundMaxR := hgm.bounds.GetAxialRange()
+	minQ := max(int32(math.Floor(qLow)), boundMinQ)
+	maxQ := min(int32(math.Ceil(qHigh)), boundMaxQ)
+	minR := max(int32(math.Floor(rLow)), boundMinR)
+	maxR := min(int32(math.Ceil(rHigh)), boundMaxR)
 
 	// 遍历所有覆盖的六边形
 	for q := minQ; q <= maxQ; q++ {
@@ -332,15 +304,18 @@ func (hgm *HexGridManager) RangeInRect(minX, minY, maxX, maxY float64, f func(gr
 	}
 }
 
-// RangeUnitsInRect 遍历矩形范围内的所有单位
-func (hgm *HexGridManager) RangeUnitsInRect(minX, minY, maxX, maxY float64, f func(unit Unit) bool) {
+// RangeUnitsInRect 遍历矩形范围内满足过滤条件的单位（多格单位只遍历一次）
+func (hgm *HexGridManager) RangeUnitsInRect(minX, minY, maxX, maxY float64, filter *UnitFilter, f func(unit Unit) bool) {
+	var visited footprintVisited
 	hgm.RangeInRect(minX, minY, maxX, maxY, func(grid *HexGrid) bool {
-		for _, unit := range grid.GetUnits() {
-			if !f(unit) {
-				return false
+		keepOn := true
+		grid.RangeUnits(func(unit Unit) bool {
+			if filter.Match(unit) && visited.firstVisit(unit) {
+				keepOn = f(unit)
 			}
-		}
-		return true
+			return keepOn
+		})
+		return keepOn
 	})
 }
 
@@ -350,7 +325,7 @@ func (hgm *HexGridManager) GetLayout() *geo.HexLayout {
 }
 
 // GetBounds 获取网格边界
-func (hgm *HexGridManager) GetBounds() *geo.HexRectangle {
+func (hgm *HexGridManager) GetBounds() *geo.HexMapBounds {
 	return hgm.bounds
 }
 
@@ -371,21 +346,27 @@ func (hgm *HexGridManager) GetHexGridsInRadius(center *geo.HexCoord, radius int3
 	return result
 }
 
-// GetUnitsInRadius 获取指定半径范围内的所有单位
-func (hgm *HexGridManager) GetUnitsInRadius(center *geo.HexCoord, radius int32) []Unit {
+// GetUnitsInRadius 获取指定半径范围内满足过滤条件的单位（filter 为nil表示不过滤，多格单位只返回一次）
+func (hgm *HexGridManager) GetUnitsInRadius(center *geo.HexCoord, radius int32, filter *UnitFilter) []Unit {
 	result := make([]Unit, 0)
+	var visited footprintVisited
 	grids := hgm.GetHexGridsInRadius(center, radius)
 	for _, grid := range grids {
-		result = append(result, grid.GetUnits()...)
+		grid.RangeUnits(func(unit Unit) bool {
+			if filter.Match(unit) && visited.firstVisit(unit) {
+				result = append(result, unit)
+			}
+			return true
+		})
 	}
 	return result
 }
 
-// GetUnitsInRadiusByWorld 获取世界坐标指定半径范围内的所有单位
-func (hgm *HexGridManager) GetUnitsInRadiusByWorld(worldX, worldY float64, radius int32) []Unit {
+// GetUnitsInRadiusByWorld 获取世界坐标指定半径范围内满足过滤条件的单位
+func (hgm *HexGridManager) GetUnitsInRadiusByWorld(worldX, worldY float64, radius int32, filter *UnitFilter) []Unit {
 	q, r := hgm.layout.WorldToHex(worldX, worldY)
 	center := geo.RoundToHex(q, r)
-	return hgm.GetUnitsInRadius(center, radius)
+	return hgm.GetUnitsInRadius(center, radius, filter)
 }
 
 // GetDistance 计算两个六边形坐标之间的距离
@@ -404,19 +385,25 @@ func (hgm *HexGridManager) GetWorldDistance(x1, y1, x2, y2 float64) int32 {
 
 // pathNode A*路径查找节点
 type pathNode struct {
-	hex    *geo.HexCoord
-	gCost  int32 // 从起点到当前节点的实际成本
-	hCost  int32 // 从当前节点到终点的估计成本
-	fCost  int32 // 总成本 (gCost + hCost)
-	parent *pathNode
-	index  int // heap 需要的索引
+	hex      *geo.HexCoord
+	stepCost float64 // 从父节点走到当前节点的成本
+	gCost    float64 // 从起点到当前节点的实际成本
+	hCost    float64 // 从当前节点到终点的估计成本
+	fCost    float64 // 总成本 (gCost + hCost)
+	parent   *pathNode
+	index    int // heap 需要的索引
 }
 
 // pathNodeHeap 实现 heap.Interface 用于优先队列
 type pathNodeHeap []*pathNode
 
-func (h pathNodeHeap) Len() int           { return len(h) }
-func (h pathNodeHeap) Less(i, j int) bool { return h[i].fCost < h[j].fCost }
+func (h pathNodeHeap) Len() int { return len(h) }
+func (h pathNodeHeap) Less(i, j int) bool {
+	if h[i].fCost != h[j].fCost {
+		return h[i].fCost < h[j].fCost
+	}
+	return h[i].hCost < h[j].hCost // 总成本相同时优先扩展离终点更近的节点
+}
 func (h pathNodeHeap) Swap(i, j int) {
 	h[i], h[j] = h[j], h[i]
 	h[i].index = i
@@ -436,7 +423,8 @@ func (h *pathNodeHeap) Pop() interface{} {
 	return node
 }
 
-// TerrainCostFunc 地形成本函数类型
+// TerrainCostFunc 地形成本函数类型（整数成本，只按目标六边形计算）
+// 新代码应使用 PathCostModel，它可以排除不可通行的六边形并保留小数成本
 type TerrainCostFunc func(hex *geo.HexCoord) int32
 
 // FindPath A*算法查找路径
@@ -444,18 +432,26 @@ type TerrainCostFunc func(hex *geo.HexCoord) int32
 // terrainCost: 地形成本函数（可选，nil 表示默认成本为 1）
 // 返回：路径上的六边形坐标列表（包含起点和终点）
 func (hgm *HexGridManager) FindPath(start, end *geo.HexCoord, terrainCost TerrainCostFunc) []*geo.HexCoord {
+	model := &PathCostModel{MinStepCost: 1}
+	if terrainCost != nil {
+		model.StepCost = func(from, to *geo.HexCoord) (float64, bool) {
+			return float64(terrainCost(to)), true
+		}
+	}
+	return hgm.FindPathWithCost(start, end, model).GetPath()
+}
+
+// FindPathWithCost 按成本模型进行A*寻路
+// model: 成本模型（nil 表示每步成本为 1，不限预算）
+// 返回：路径及每一步的成本，找不到路径或超出成本预算时返回 nil
+func (hgm *HexGridManager) FindPathWithCost(start, end *geo.HexCoord, model *PathCostModel) *PathResult {
 	if !hgm.bounds.Contains(start) || !hgm.bounds.Contains(end) {
 		return nil
 	}
 
 	// 如果起点和终点相同，直接返回
 	if start.Equal(end) {
-		return []*geo.HexCoord{start}
-	}
-
-	// 初始化地形成本函数
-	if terrainCost == nil {
-		terrainCost = func(hex *geo.HexCoord) int32 { return 1 }
+		return &PathResult{Path: []*geo.HexCoord{start}, StepCosts: []float64{0}}
 	}
 
 	// openSet: 待处理节点集合（优先队列）
@@ -472,7 +468,7 @@ func (hgm *HexGridManager) FindPath(start, end *geo.HexCoord, terrainCost Terrai
 	startNode := &pathNode{
 		hex:   start,
 		gCost: 0,
-		hCost: start.DistanceTo(end),
+		hCost: model.estimate(start, end),
 	}
 	startNode.fCost = startNode.gCost + startNode.hCost
 	nodes[start.Hash()] = startNode
@@ -505,8 +501,17 @@ func (hgm *HexGridManager) FindPath(start, end *geo.HexCoord, terrainCost Terrai
 				continue
 			}
 
-			// 计算新的 gCost
-			newGCost := current.gCost + terrainCost(neighborHex)
+			// 不可通行的六边形直接排除
+			stepCost, passable := model.stepCost(current.hex, neighborHex)
+			if !passable {
+				continue
+			}
+
+			// 计算新的 gCost，超出预算的路径剪枝
+			newGCost := current.gCost + stepCost
+			if !model.withinBudget(newGCost) {
+				continue
+			}
 
 			// 如果找到更好的路径或这是新节点
 			existingNode, exists := nodes[neighborHash]
@@ -517,8 +522,9 @@ func (hgm *HexGridManager) FindPath(start, end *geo.HexCoord, terrainCost Terrai
 					}
 					nodes[neighborHash] = existingNode
 				}
+				existingNode.stepCost = stepCost
 				existingNode.gCost = newGCost
-				existingNode.hCost = neighborHex.DistanceTo(end)
+				existingNode.hCost = model.estimate(neighborHex, end)
 				existingNode.fCost = existingNode.gCost + existingNode.hCost
 				existingNode.parent = current
 
@@ -533,17 +539,23 @@ func (hgm *HexGridManager) FindPath(start, end *geo.HexCoord, terrainCost Terrai
 	return nil
 }
 
-// reconstructPath 重建路径
-func (hgm *HexGridManager) reconstructPath(endNode *pathNode) []*geo.HexCoord {
-	path := make([]*geo.HexCoord, 0)
+// reconstructPath 重建路径和每一步的成本
+func (hgm *HexGridManager) reconstructPath(endNode *pathNode) *PathResult {
+	result := &PathResult{
+		Path:      make([]*geo.HexCoord, 0),
+		StepCosts: make([]float64, 0),
+		TotalCost: endNode.gCost,
+	}
 	for node := endNode; node != nil; node = node.parent {
-		path = append(path, node.hex)
+		result.Path = append(result.Path, node.hex)
+		result.StepCosts = append(result.StepCosts, node.stepCost)
 	}
 	// 反转路径
-	for i, j := 0, len(path)-1; i < j; i, j = i+1, j-1 {
-		path[i], path[j] = path[j], path[i]
+	for i, j := 0, len(result.Path)-1; i < j; i, j = i+1, j-1 {
+		result.Path[i], result.Path[j] = result.Path[j], result.Path[i]
+		result.StepCosts[i], result.StepCosts[j] = result.StepCosts[j], result.StepCosts[i]
 	}
-	return path
+	return result
 }
 
 // GetHexesInLine 获取两个六边形坐标之间的直线路径（Bresenham 算法）
@@ -568,9 +580,9 @@ func (hgm *HexGridManager) GetVisionRange(center *geo.HexCoord, visionRange int3
 	return hgm.GetHexGridsInRadius(center, visionRange)
 }
 
-// GetVisibleUnits 获取视野范围内的所有单位
-func (hgm *HexGridManager) GetVisibleUnits(center *geo.HexCoord, visionRange int32) []Unit {
-	return hgm.GetUnitsInRadius(center, visionRange)
+// GetVisibleUnits 获取视野范围内满足过滤条件的单位
+func (hgm *HexGridManager) GetVisibleUnits(center *geo.HexCoord, visionRange int32, filter *UnitFilter) []Unit {
+	return hgm.GetUnitsInRadius(center, visionRange, filter)
 }
 
 // IsVisible 检查目标是否在视野范围内（无障碍）
@@ -582,6 +594,9 @@ func (hgm *HexGridManager) IsVisible(from, to *geo.HexCoord, visionRange int32)
 
 	// 检查直线路径上是否有障碍物
 	line := hgm.GetHexesInLine(from, to)
+	if len(line) <= 2 {
+		return true
+	}
 	for _, hex := range line[1 : len(line)-1] { // 跳过起点和终点
 		if grid := hgm.GetGrid(hex); grid != nil {
 			// 检查是否有障碍物
@@ -600,6 +615,12 @@ func (hgm *HexGridManager) MoveUnit(unit Unit, from, to *geo.HexCoord) bool {
 		return false
 	}
 
+	// 有六边形占地半径的单位整体移动
+	if unitHexRadius(unit) > 0 {
+		hgm.RemoveUnitFromGrid(unit, from)
+		return hgm.AddUnitToGrid(unit, to)
+	}
+
 	// 从原位置移除
 	fromGrid := hgm.GetGrid(from)
 	if fromGrid != nil {
@@ -615,20 +636,30 @@ func (hgm *HexGridManager) MoveUnit(unit Unit, from, to *geo.HexCoord) bool {
 	return false
 }
 
-// GetAllUnits 获取地图上所有单位
+// GetAllUnits 获取地图上所有单位（多格单位只返回一次）
 func (hgm *HexGridManager) GetAllUnits() []Unit {
 	result := make([]Unit, 0)
+	var visited footprintVisited
 	for _, grid := range hgm.grids {
-		result = append(result, grid.GetUnits()...)
+		for _, unit := range grid.GetUnits() {
+			if visited.firstVisit(unit) {
+				result = append(result, unit)
+			}
+		}
 	}
 	return result
 }
 
-// GetUnitsByType 根据类型获取所有单位
+// GetUnitsByType 根据类型获取所有单位（多格单位只返回一次）
 func (hgm *HexGridManager) GetUnitsByType(unitType MapUnitType) []Unit {
 	result := make([]Unit, 0)
+	var visited footprintVisited
 	for _, grid := range hgm.grids {
-		result = append(result, grid.GetUnitsByType(unitType)...)
+		for _, unit := range grid.GetUnitsByType(unitType) {
+			if visited.firstVisit(unit) {
+				result = append(result, unit)
+			}
+		}
 	}
 	return result
 }
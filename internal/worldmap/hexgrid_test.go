@@ -267,7 +267,7 @@ func TestHexGridManagerUnitsInRadius(t *testing.T) {
 	hgm.AddUnitToGrid(nearbyUnit, nearbyHex)
 
 	// 测试获取范围内单位
-	units := hgm.GetUnitsInRadius(centerHex, 2)
+	units := hgm.GetUnitsInRadius(centerHex, 2, nil)
 	if len(units) != 2 {
 		t.Errorf("范围内单位数量错误：期望 2, 得到 %d", len(units))
 	}
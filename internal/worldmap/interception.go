@@ -0,0 +1,441 @@
+package worldmap
+
+import (
+	"math"
+	"sort"
+	"time"
+
+	"github.com/GooLuck/WorldMap/internal/worldmap/geo"
+)
+
+// EncounterType 遭遇类型
+type EncounterType int32
+
+const (
+	EncounterType_None      EncounterType = iota
+	EncounterType_March                   // 两支敌对行军相遇
+	EncounterType_Territory               // 行军经过敌方控制的六边形
+)
+
+// MarchEncounter 行军遭遇事件
+type MarchEncounter struct {
+	Type    EncounterType
+	TroopId int64         // 行军部队id
+	OtherId int64         // 遭遇的部队id（经过敌方六边形时为0）
+	Owner   *Owner        // 敌方所有者
+	Time    time.Time     // 遭遇时间
+	X       float64       // 遭遇位置（世界坐标）
+	Y       float64       // 遭遇位置（世界坐标）
+	Hex     *geo.HexCoord // 遭遇位置所在六边形
+}
+
+// EncounterCallback 遭遇事件回调
+type EncounterCallback func(encounter *MarchEncounter)
+
+// HexControllerFunc 查询六边形的控制者，nil 表示无人控制
+type HexControllerFunc func(hex *geo.HexCoord) *Owner
+
+// encounterKey 已上报遭遇的去重键，出发时间不同视为新的行军
+type encounterKey struct {
+	encounterType EncounterType
+	troopId       int64
+	troopDepart   int64
+	otherId       int64
+	otherDepart   int64
+	hex           uint64
+}
+
+// marchPair 两支行军（按部队id从小到大）
+type marchPair [2]int64
+
+// InterceptionDetector 行军拦截检测器
+// 基于行军的时间参数化路径计算敌对行军的相遇时间，以及行军经过敌方控制六边形的时间
+// 行军相遇只在行军开始、改道、加速、召回时重新计算与其他行军的相遇，关系变化后全部重新计算；
+// 经过敌方六边形每个Tick只检查新进入的六边形（控制者随领土变化，不缓存）
+type InterceptionDetector struct {
+	worldMap        *WorldMap
+	encounterRadius float64 // 两支行军距离小于该值视为相遇（世界单位）
+	hexController   HexControllerFunc
+	callbacks       []EncounterCallback
+	reported        map[encounterKey]bool
+	marchEncounters map[marchPair]*MarchEncounter // 敌对行军的相遇缓存（只保存会相遇的两支行军）
+	territoryNext   map[int64]int                 // 每支行军下一个待检查是否为敌方控制的路径索引
+	dirty           bool                          // 相遇缓存需要全部重新计算
+}
+
+// NewInterceptionDetector 创建拦截检测器，默认相遇距离为六边形半径，六边形控制者为领土所属联盟或其中的主城所有者
+func NewInterceptionDetector(worldMap *WorldMap) *InterceptionDetector {
+	detector := &InterceptionDetector{
+		worldMap:        worldMap,
+		encounterRadius: worldMap.hexGridMgr.GetLayout().Radius,
+		callbacks:       make([]EncounterCallback, 0),
+		reported:        make(map[encounterKey]bool),
+		marchEncounters: make(map[marchPair]*MarchEncounter),
+		territoryNext:   make(map[int64]int),
+	}
+	detector.hexController = detector.defaultHexController
+	return detector
+}
+
+// SetEncounterRadius 设置相遇距离
+func (d *InterceptionDetector) SetEncounterRadius(radius float64) {
+	d.encounterRadius = radius
+	d.dirty = true
+}
+
+// SetHexController 设置六边形控制者查询函数
+func (d *InterceptionDetector) SetHexController(controller HexControllerFunc) {
+	d.hexController = controller
+}
+
+// AddEncounterCallback 注册遭遇事件回调
+func (d *InterceptionDetector) AddEncounterCallback(callback EncounterCallback) {
+	d.callbacks = append(d.callbacks, callback)
+}
+
+// defaultHexController 以领土所属联盟作为控制者，无主领土以六边形内主城的所有者作为控制者
+func (d *InterceptionDetector) defaultHexController(hex *geo.HexCoord) *Owner {
+	if allianceId := d.worldMap.territoryMgr.GetOwner(hex); allianceId != 0 {
+		return NewUnionOwner(allianceId)
+	}
+
+	grid := d.worldMap.hexGridMgr.GetGrid(hex)
+	if grid == nil {
+		return nil
+	}
+	for _, unit := range grid.GetUnitsByType(MapUnitType_PlayerCity) {
+		return unit.GetOwner()
+	}
+	return nil
+}
+
+// Update 上报发生时间不晚于 now 且未上报过的遭遇
+func (d *InterceptionDetector) Update(now time.Time) {
+	encounters := d.collectMarchEncounters()
+	for _, troop := range d.sortedMarching() {
+		encounters = append(encounters, d.advanceTerritory(troop, now)...)
+	}
+	sortEncounters(encounters)
+
+	for _, encounter := range encounters {
+		if encounter.Time.After(now) {
+			continue
+		}
+		key := d.makeKey(encounter)
+		if d.reported[key] {
+			continue
+		}
+		d.reported[key] = true
+		for _, callback := range d.callbacks {
+			callback(encounter)
+		}
+	}
+	d.pruneReported()
+}
+
+// Detect 计算当前所有行军将要发生（或已发生）的遭遇，按时间排序
+func (d *InterceptionDetector) Detect() []*MarchEncounter {
+	encounters := d.collectMarchEncounters()
+	for _, troop := range d.sortedMarching() {
+		encounters = append(encounters, d.detectTerritory(troop)...)
+	}
+	sortEncounters(encounters)
+	return encounters
+}
+
+// PredictEncounters 预测指定行军的所有遭遇，不上报
+func (d *InterceptionDetector) PredictEncounters(troopId int64) []*MarchEncounter {
+	result := make([]*MarchEncounter, 0)
+	for _, encounter := range d.Detect() {
+		if encounter.TroopId == troopId || encounter.OtherId == troopId {
+			result = append(result, encounter)
+		}
+	}
+	return result
+}
+
+// onMarchChanged 行军开始、改道、加速或召回：重新计算它与其他行军的相遇，从 now 之后进入的六边形开始检查敌方控制
+func (d *InterceptionDetector) onMarchChanged(troop *TroopUnit, now time.Time) {
+	next := 1
+	for next < len(troop.path)-1 && !d.territoryEnterTime(troop, next).After(now) {
+		next++
+	}
+	d.territoryNext[troop.GetId()] = next
+
+	if d.dirty {
+		return
+	}
+	d.removeMarchEncounters(troop.GetId())
+	for _, other := range d.worldMap.troopMgr.marching {
+		if other != troop {
+			d.updateMarchPair(troop, other)
+		}
+	}
+}
+
+// onMarchEnded 行军到达或部队移除
+func (d *InterceptionDetector) onMarchEnded(troopId int64) {
+	delete(d.territoryNext, troopId)
+	d.removeMarchEncounters(troopId)
+}
+
+// onRelationChanged 关系变化后敌对关系可能改变，下次检测时重新计算所有相遇
+func (d *InterceptionDetector) onRelationChanged() {
+	d.dirty = true
+}
+
+// collectMarchEncounters 获取缓存的行军相遇（缓存失效时重新计算）
+func (d *InterceptionDetector) collectMarchEncounters() []*MarchEncounter {
+	if d.dirty {
+		d.dirty = false
+		clear(d.marchEncounters)
+		troops := d.sortedMarching()
+		for i, troop := range troops {
+			for _, other := range troops[i+1:] {
+				d.updateMarchPair(troop, other)
+			}
+		}
+	}
+
+	encounters := make([]*MarchEncounter, 0, len(d.marchEncounters))
+	for _, encounter := range d.marchEncounters {
+		encounters = append(encounters, encounter)
+	}
+	return encounters
+}
+
+// updateMarchPair 计算两支行军的相遇并更新缓存
+func (d *InterceptionDetector) updateMarchPair(a, b *TroopUnit) {
+	if a.GetId() > b.GetId() {
+		a, b = b, a
+	}
+	pair := marchPair{a.GetId(), b.GetId()}
+	delete(d.marchEncounters, pair)
+	if d.worldMap.GetOwnerRelation(a.GetOwner(), b.GetOwner()) != Relation_Enemy {
+		return
+	}
+	if encounter := d.detectMarchPair(a, b); encounter != nil {
+		d.marchEncounters[pair] = encounter
+	}
+}
+
+// removeMarchEncounters 删除行军的所有相遇缓存
+func (d *InterceptionDetector) removeMarchEncounters(troopId int64) {
+	for pair := range d.marchEncounters {
+		if pair[0] == troopId || pair[1] == troopId {
+			delete(d.marchEncounters, pair)
+		}
+	}
+}
+
+// sortedMarching 按部队id排序的行军
+func (d *InterceptionDetector) sortedMarching() []*TroopUnit {
+	troops := make([]*TroopUnit, 0, len(d.worldMap.troopMgr.marching))
+	for _, troop := range d.worldMap.troopMgr.marching {
+		troops = append(troops, troop)
+	}
+	sort.Slice(troops, func(i, j int) bool {
+		return troops[i].GetId() < troops[j].GetId()
+	})
+	return troops
+}
+
+// advanceTerritory 检查行军在 now 之前新进入的六边形是否为敌方控制
+func (d *InterceptionDetector) advanceTerritory(troop *TroopUnit, now time.Time) []*MarchEncounter {
+	encounters := make([]*MarchEncounter, 0)
+	next, exists := d.territoryNext[troop.GetId()]
+	if !exists {
+		next = 1
+	}
+	for ; next < len(troop.path)-1 && !d.territoryEnterTime(troop, next).After(now); next++ {
+		if encounter := d.territoryEncounter(troop, next); encounter != nil {
+			encounters = append(encounters, encounter)
+		}
+	}
+	d.territoryNext[troop.GetId()] = next
+	return encounters
+}
+
+// detectTerritory 检测行军经过的敌方控制六边形（不含起点和终点），进入时间取路径段中点
+func (d *InterceptionDetector) detectTerritory(troop *TroopUnit) []*MarchEncounter {
+	encounters := make([]*MarchEncounter, 0)
+	for i := 1; i < len(troop.path)-1; i++ {
+		if encounter := d.territoryEncounter(troop, i); encounter != nil {
+			encounters = append(encounters, encounter)
+		}
+	}
+	return encounters
+}
+
+// territoryEncounter 行军进入路径上第 i 个六边形时的遭遇（不是敌方控制时为nil）
+func (d *InterceptionDetector) territoryEncounter(troop *TroopUnit, i int) *MarchEncounter {
+	if d.hexController == nil {
+		return nil
+	}
+	controller := d.hexController(troop.path[i])
+	if controller == nil || d.worldMap.GetOwnerRelation(troop.GetOwner(), controller) != Relation_Enemy {
+		return nil
+	}
+	return &MarchEncounter{
+		Type:    EncounterType_Territory,
+		TroopId: troop.GetId(),
+		Owner:   controller,
+		Time:    d.territoryEnterTime(troop, i),
+		X:       (troop.points[i-1].X + troop.points[i].X) / 2,
+		Y:       (troop.points[i-1].Y + troop.points[i].Y) / 2,
+		Hex:     troop.path[i],
+	}
+}
+
+// territoryEnterTime 行军进入路径上第 i 个六边形的时间（路径段中点）
+func (d *InterceptionDetector) territoryEnterTime(troop *TroopUnit, i int) time.Time {
+	return troop.stepTimes[i-1].Add(troop.stepTimes[i].Sub(troop.stepTimes[i-1]) / 2)
+}
+
+// detectMarchPair 计算两支行军最早的相遇时间
+// 两条路径的所有拐点把时间切成若干段，每段内两者都做匀速直线运动，相对位移是线性的，解二次方程即可
+func (d *InterceptionDetector) detectMarchPair(a, b *TroopUnit) *MarchEncounter {
+	start := maxTime(a.stepTimes[0], b.stepTimes[0])
+	end := minTime(a.GetArriveTime(), b.GetArriveTime())
+	if end.Before(start) || !d.isPathBoundsClose(a, b) {
+		return nil
+	}
+
+	breakpoints := []time.Time{start, end}
+	for _, t := range append(append([]time.Time{}, a.stepTimes...), b.stepTimes...) {
+		if t.After(start) && t.Before(end) {
+			breakpoints = append(breakpoints, t)
+		}
+	}
+	sort.Slice(breakpoints, func(i, j int) bool {
+		return breakpoints[i].Before(breakpoints[j])
+	})
+
+	r2 := d.encounterRadius * d.encounterRadius
+	for i := 0; i+1 < len(breakpoints); i++ {
+		t0, t1 := breakpoints[i], breakpoints[i+1]
+		ax0, ay0 := a.GetPositionAt(t0)
+		bx0, by0 := b.GetPositionAt(t0)
+		ax1, ay1 := a.GetPositionAt(t1)
+		bx1, by1 := b.GetPositionAt(t1)
+
+		// 相对位移 p(s) = p0 + v*s, s ∈ [0, 1]
+		p0 := geo.Vector2{X: ax0 - bx0, Y: ay0 - by0}
+		v := geo.Vector2{X: (ax1 - bx1) - p0.X, Y: (ay1 - by1) - p0.Y}
+		s, ok := firstRootInUnit(v.LengthSquared(), 2*p0.Dot(&v), p0.LengthSquared()-r2)
+		if !ok {
+			continue
+		}
+
+		t := t0.Add(time.Duration(s * float64(t1.Sub(t0))))
+		ax, ay := a.GetPositionAt(t)
+		bx, by := b.GetPositionAt(t)
+		x, y := (ax+bx)/2, (ay+by)/2
+		q, r := d.worldMap.hexGridMgr.GetLayout().WorldToHex(x, y)
+		return &MarchEncounter{
+			Type:    EncounterType_March,
+			TroopId: a.GetId(),
+			OtherId: b.GetId(),
+			Owner:   b.GetOwner(),
+			Time:    t,
+			X:       x,
+			Y:       y,
+			Hex:     geo.RoundToHex(q, r),
+		}
+	}
+	return nil
+}
+
+// isPathBoundsClose 粗筛：两条路径的包围盒（外扩相遇距离）是否相交
+func (d *InterceptionDetector) isPathBoundsClose(a, b *TroopUnit) bool {
+	aMinX, aMinY, aMaxX, aMaxY := pathBounds(a.points)
+	bMinX, bMinY, bMaxX, bMaxY := pathBounds(b.points)
+	return aMinX-d.encounterRadius <= bMaxX && bMinX-d.encounterRadius <= aMaxX &&
+		aMinY-d.encounterRadius <= bMaxY && bMinY-d.encounterRadius <= aMaxY
+}
+
+// pruneReported 清理已结束行军的上报记录
+func (d *InterceptionDetector) pruneReported() {
+	marching := d.worldMap.troopMgr.marching
+	for key := range d.reported {
+		troop, exists := marching[key.troopId]
+		if !exists || troop.GetDepartTime().UnixNano() != key.troopDepart {
+			delete(d.reported, key)
+		}
+	}
+}
+
+func (d *InterceptionDetector) makeKey(encounter *MarchEncounter) encounterKey {
+	key := encounterKey{
+		encounterType: encounter.Type,
+		troopId:       encounter.TroopId,
+		otherId:       encounter.OtherId,
+	}
+	if troop := d.worldMap.troopMgr.GetTroop(encounter.TroopId); troop != nil {
+		key.troopDepart = troop.GetDepartTime().UnixNano()
+	}
+	if other := d.worldMap.troopMgr.GetTroop(encounter.OtherId); other != nil {
+		key.otherDepart = other.GetDepartTime().UnixNano()
+	}
+	if encounter.Type == EncounterType_Territory {
+		key.hex = encounter.Hex.Hash()
+	}
+	return key
+}
+
+// sortEncounters 按时间排序遭遇，同一时间按部队id排序
+func sortEncounters(encounters []*MarchEncounter) {
+	sort.SliceStable(encounters, func(i, j int) bool {
+		a, b := encounters[i], encounters[j]
+		if !a.Time.Equal(b.Time) {
+			return a.Time.Before(b.Time)
+		}
+		if a.TroopId != b.TroopId {
+			return a.TroopId < b.TroopId
+		}
+		return a.OtherId < b.OtherId
+	})
+}
+
+// firstRootInUnit 求 a*s^2 + b*s + c <= 0 在 [0, 1] 内的最小 s
+func firstRootInUnit(a, b, c float64) (float64, bool) {
+	if c <= 0 {
+		return 0, true
+	}
+	if a == 0 {
+		return 0, false
+	}
+	disc := b*b - 4*a*c
+	if disc < 0 {
+		return 0, false
+	}
+	s := (-b - math.Sqrt(disc)) / (2 * a)
+	if s < 0 || s > 1 {
+		return 0, false
+	}
+	return s, true
+}
+
+func pathBounds(points []geo.Vector2) (minX, minY, maxX, maxY float64) {
+	minX, minY = math.Inf(1), math.Inf(1)
+	maxX, maxY = math.Inf(-1), math.Inf(-1)
+	for _, p := range points {
+		minX, maxX = math.Min(minX, p.X), math.Max(maxX, p.X)
+		minY, maxY = math.Min(minY, p.Y), math.Max(maxY, p.Y)
+	}
+	return
+}
+
+func maxTime(a, b time.Time) time.Time {
+	if a.After(b) {
+		return a
+	}
+	return b
+}
+
+func minTime(a, b time.Time) time.Time {
+	if a.Before(b) {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,72 @@
+package worldmap
+
+import (
+	"sort"
+
+	"github.com/GooLuck/WorldMap/internal/worldmap/config"
+)
+
+// LevelUnit 有等级的单位
+type LevelUnit interface {
+	GetLevel() int32
+}
+
+// LodRule 观察等级对应的可见规则
+type LodRule struct {
+	Lod       int32
+	minLevels map[MapUnitType]int32 // 可见的单位类型 -> 最小等级
+}
+
+// IsVisible 单位在该观察等级下是否可见（规则为nil表示全部可见）
+func (r *LodRule) IsVisible(unit Unit) bool {
+	if r == nil {
+		return true
+	}
+	minLevel, exists := r.minLevels[unit.GetType()]
+	if !exists {
+		return false
+	}
+	if leveled, ok := unit.(LevelUnit); ok && leveled.GetLevel() < minLevel {
+		return false
+	}
+	return true
+}
+
+// LodRules 所有观察等级规则
+type LodRules struct {
+	rules []*LodRule // 按 Lod 升序
+}
+
+// NewLodRules 根据配置创建观察等级规则
+func NewLodRules(configs []config.LodRuleConfig) *LodRules {
+	rules := make([]*LodRule, 0, len(configs))
+	for _, ruleConfig := range configs {
+		rule := &LodRule{
+			Lod:       ruleConfig.Lod,
+			minLevels: make(map[MapUnitType]int32, len(ruleConfig.UnitTypes)),
+		}
+		for _, unitConfig := range ruleConfig.UnitTypes {
+			rule.minLevels[MapUnitType(unitConfig.UnitType)] = unitConfig.MinLevel
+		}
+		rules = append(rules, rule)
+	}
+	sort.Slice(rules, func(i, j int) bool {
+		return rules[i].Lod < rules[j].Lod
+	})
+	return &LodRules{rules: rules}
+}
+
+// GetRule 获取观察等级适用的规则：Lod 不超过观察等级的最大的一条，没有时返回nil（全部可见）
+func (lr *LodRules) GetRule(lod int32) *LodRule {
+	if lr == nil {
+		return nil
+	}
+	var rule *LodRule
+	for _, r := range lr.rules {
+		if r.Lod > lod {
+			break
+		}
+		rule = r
+	}
+	return rule
+}
@@ -1,57 +1,508 @@
 package worldmap
 
 import (
+	"math"
+	"math/rand"
+	"time"
+
 	"github.com/GooLuck/WorldMap/internal/worldmap/config"
 	"github.com/GooLuck/WorldMap/internal/worldmap/geo"
 )
 
 // 世界地图
 type WorldMap struct {
-	id          int64             // 地图实例id
-	mapConfig   *config.MapConfig // 地图配置
-	gridMgr     *GridManager      // 网格管理器
-	unitMgr     *UnitManager      // 单位管理器
-	playerMgr   *MapPlayerManager // 玩家管理器
-	observerMgr *ObserverManager  // 观察者管理器
+	id             int64                  // 地图实例id
+	mapConfig      *config.MapConfig      // 地图配置
+	gridMgr        *GridManager           // 网格管理器
+	hexGridMgr     *HexGridManager        // 六边形网格管理器
+	coords         *CoordSystem           // 坐标系（矩形坐标和六边形坐标换算）
+	topology       Topology               // 地图拓扑（按配置选择正方形或六边形后端）
+	unitMgr        *UnitManager           // 单位管理器
+	playerMgr      *MapPlayerManager      // 玩家管理器
+	observerMgr    *ObserverManager       // 观察者管理器
+	obstacleMgr    *ObstacleManager       // 障碍物管理器
+	terrainMap     *TerrainMap            // 地形（可选，nil表示全部可通行）
+	cityZones      []*CityZoneArea        // 主城区域（与配置顺序一致）
+	citySlotPool   *CitySlotPool          // 主城位置池（未配置时为nil）
+	npcMgr         *NpcManager            // NPC管理器
+	troopMgr       *TroopManager          // 部队管理器
+	interception   *InterceptionDetector  // 行军拦截检测器
+	marchResolvers *MarchResolverRegistry // 行军到达结算器
+	resourceMgr    *ResourceManager       // 资源管理器
+	territoryMgr   *TerritoryManager      // 联盟领土管理器
+	fogMgr         *FogManager            // 战争迷雾管理器
+	relations      *RelationRegistry      // 关系注册表（可在多张地图间共享）
+	relationListen int64                  // 关系变化监听id
 }
 
 type CityZoneArea struct {
 	ZoneID   int32 // 区域id
 	CurCount int32 // 当前区域内的主城数量
-	CurIndex int32 // 区域索引
+	CurIndex int32 // 区域内下一个落点的扇区索引
 }
 
+// 单次寻找主城坐标的最大尝试次数
+const maxCityCoordAttempts = 64
+
 func NewWorldMap(config *config.MapConfig) *WorldMap {
+	hexRadius := config.HexRadius
+	if hexRadius <= 0 {
+		hexRadius = float64(config.MapSize.GridWidth)
+	}
+
 	newMap := &WorldMap{
-		id:        GetIDGenerator().GenerateNewID(),
-		mapConfig: config,
-		gridMgr:   NewGridManager(config.MapSize),
-		unitMgr:   NewUnitManager(),
-		playerMgr: NewMapPlayerManager(),
+		id:         GetIDGenerator().GenerateNewID(),
+		mapConfig:  config,
+		gridMgr:    NewGridManager(config.MapSize),
+		hexGridMgr: NewHexGridManager(config.MapSize, hexRadius, config.HexPointy),
+		unitMgr:    NewUnitManager(),
+		playerMgr:  NewMapPlayerManager(),
+		cityZones:  make([]*CityZoneArea, 0, len(config.CityZones)),
+	}
+
+	newMap.coords = NewCoordSystem(config.MapSize, newMap.hexGridMgr.GetLayout())
+	newMap.topology = newTopology(newMap, config.Topology)
+	newMap.obstacleMgr = NewObstacleManager(newMap.gridMgr, newMap.coords)
+	newMap.obstacleMgr.LoadConfig(config)
+
+	for _, zoneConfig := range config.CityZones {
+		newMap.cityZones = append(newMap.cityZones, &CityZoneArea{ZoneID: zoneConfig.ZoneID})
+	}
+
+	if config.CitySlotPool.PoolSize > 0 {
+		newMap.citySlotPool = NewCitySlotPool(newMap)
 	}
 
 	newMap.observerMgr = NewObserverManager(newMap)
+	newMap.fogMgr = NewFogManager(newMap, &config.Fog)
+	newMap.SetRelationRegistry(NewRelationRegistry())
+	newMap.npcMgr = NewNpcManager(newMap, &config.NpcSpawn)
+	newMap.troopMgr = NewTroopManager(newMap)
+	newMap.territoryMgr = NewTerritoryManager(newMap)
+	newMap.interception = NewInterceptionDetector(newMap)
+	newMap.marchResolvers = NewMarchResolverRegistry(newMap)
+	newMap.resourceMgr = NewResourceManager(newMap, &config.GlobalRefreshConfig)
+	newMap.resourceMgr.LoadConfig(config)
+	newMap.marchResolvers.Register(MarchIntent_Gather, MarchResolverFunc(newMap.resourceMgr.resolveGather))
 	return newMap
 }
 
-// 创建一个城市坐标
+// Update 地图Tick
+func (wm *WorldMap) Update(now time.Time) {
+	if wm.citySlotPool != nil {
+		wm.citySlotPool.Update(now)
+	}
+	wm.npcMgr.Update(now)
+	// 先上报遭遇，再处理到达
+	wm.interception.Update(now)
+	wm.troopMgr.Update(now)
+	wm.resourceMgr.Update(now)
+}
+
+// 创建一个城市坐标，按配置顺序依次填充主城区域，区域全部填满后随机选点
 func (wm *WorldMap) NewCityCoord() (*geo.Coord, bool) {
-	return nil, false
+	radius := wm.mapConfig.CityRadius
+	for _, area := range wm.cityZones {
+		if wm.isCityZoneFull(area) {
+			continue
+		}
+		if coord, ok := wm.NewCityCoordInArea(radius, area); ok {
+			return coord, true
+		}
+	}
+	return wm.RandomCityCoord()
 }
 
 // 在指定区域内创建城市坐标
+// radius: 主城占地半径；返回坐标时不计数，主城通过 AddUnit 加入地图后才计入区域并轮转到下一个扇区
 func (wm *WorldMap) NewCityCoordInArea(radius int32, area *CityZoneArea) (*geo.Coord, bool) {
+	zoneConfig := wm.getCityZoneConfig(area.ZoneID)
+	if zoneConfig == nil || wm.isCityZoneFull(area) {
+		return nil, false
+	}
+
+	sectors := zoneConfig.Sectors
+	if sectors <= 0 {
+		sectors = 1
+	}
+	sectorAngle := 2 * math.Pi / float64(sectors)
+	center := wm.GetMapCenter()
+	minR := float64(zoneConfig.MinRadius)
+	maxR := float64(zoneConfig.MaxRadius)
+
+	for attempt := int32(0); attempt < maxCityCoordAttempts; attempt++ {
+		// 优先使用当前扇区，失败后依次尝试后面的扇区
+		sector := (area.CurIndex + attempt) % sectors
+		angle := (float64(sector) + rand.Float64()) * sectorAngle
+		// 按面积均匀分布取半径
+		dist := math.Sqrt(minR*minR + rand.Float64()*(maxR*maxR-minR*minR))
+
+		coord := geo.NewCoord(
+			center.X+int32(math.Round(dist*math.Cos(angle))),
+			center.Y+int32(math.Round(dist*math.Sin(angle))),
+		)
+		if !wm.CanPlaceCity(coord, radius) {
+			continue
+		}
+		return coord, true
+	}
 	return nil, false
 }
 
 // 随机生成一个城市坐标
 func (wm *WorldMap) RandomCityCoord() (*geo.Coord, bool) {
+	radius := wm.mapConfig.CityRadius
+	mapSize := wm.GetMapSize()
+	if mapSize.Width <= 2*radius || mapSize.Height <= 2*radius {
+		return nil, false
+	}
+
+	rect := geo.NewRectangle(radius, radius, mapSize.Width-2*radius, mapSize.Height-2*radius)
+	for attempt := 0; attempt < maxCityCoordAttempts; attempt++ {
+		coord := rect.RandomCoord()
+		if wm.CanPlaceCity(coord, radius) {
+			return coord, true
+		}
+	}
 	return nil, false
 }
 
+// CanPlaceCity 检查指定坐标能否放置占地半径为 radius 的主城
+func (wm *WorldMap) CanPlaceCity(coord *geo.Coord, radius int32) bool {
+	mapSize := wm.GetMapSize()
+	if coord.X-radius < 0 || coord.X+radius >= mapSize.Width ||
+		coord.Y-radius < 0 || coord.Y+radius >= mapSize.Height {
+		return false
+	}
+
+	// 障碍物
+	if !wm.obstacleMgr.CanBuildAt(coord.X, coord.Y, radius) {
+		return false
+	}
+
+	// 出生点安全区内不允许建城
+	for _, spawnPoint := range wm.mapConfig.SpawnPoints {
+		dx := int64(coord.X - spawnPoint.X)
+		dy := int64(coord.Y - spawnPoint.Y)
+		limit := int64(spawnPoint.Radius + radius)
+		if dx*dx+dy*dy < limit*limit {
+			return false
+		}
+	}
+
+	// 地形（占地范围内的六边形都要可通行）
+	if !wm.isFootprintPassable(coord, radius) {
+		return false
+	}
+
+	// 不能占用位置池中已预留的位置
+	if wm.citySlotPool != nil && wm.citySlotPool.isOverlapReservation(coord, radius) {
+		return false
+	}
+
+	// 与已有主城的占地范围不能重叠
+	return !wm.isOverlapCity(coord, radius)
+}
+
+// isOverlapCity 检查占地范围是否与已有主城重叠
+// 已有主城有占地范围时按占地矩形判断，否则按配置的主城占地半径判断
+func (wm *WorldMap) isOverlapCity(coord *geo.Coord, radius int32) bool {
+	footprintRect := NewCenterFootprint(radius, 0).GetRect(coord)
+	limit := radius + wm.mapConfig.CityRadius
+	rect := geo.NewRectangle(coord.X-limit, coord.Y-limit, 2*limit+1, 2*limit+1)
+
+	overlap := false
+	filter := NewUnitFilter(wm.relations, nil).WithUnitTypes(MapUnitType_PlayerCity)
+	wm.gridMgr.RangeRectUnits(rect, false, filter, func(unit Unit) bool {
+		cityRect := NewCenterFootprint(wm.mapConfig.CityRadius, 0).GetRect(unit.GetCoord())
+		if getFootprint(unit) != nil {
+			cityRect = unitFootprintRect(unit, unit.GetCoord())
+		}
+		overlap = cityRect.Intersects(footprintRect)
+		return !overlap
+	})
+	return overlap
+}
+
+// isFootprintPassable 检查占地范围内的六边形（中心在占地矩形内的六边形和坐标所在六边形）是否都可通行
+func (wm *WorldMap) isFootprintPassable(coord *geo.Coord, radius int32) bool {
+	if !wm.hasTerrain() {
+		return true
+	}
+	if !wm.getTerrainConfig(wm.coordToHex(coord)).Passable {
+		return false
+	}
+
+	footprintRect := NewCenterFootprint(radius, 0).GetRect(coord)
+	passable := true
+	minX, minY := float64(footprintRect.X), float64(footprintRect.Y)
+	maxX, maxY := float64(footprintRect.X+footprintRect.Width-1), float64(footprintRect.Y+footprintRect.Height-1)
+	wm.hexGridMgr.RangeInRect(minX, minY, maxX, maxY, func(grid *HexGrid) bool {
+		if x, y := grid.GetCenterWorld(); x >= minX && x <= maxX && y >= minY && y <= maxY {
+			passable = wm.getTerrainConfig(grid.GetCoord()).Passable
+		}
+		return passable
+	})
+	return passable
+}
+
+// cityZoneAreaOf 坐标所在的主城区域（按到地图中心的距离匹配，不在任何区域内时返回nil）
+func (wm *WorldMap) cityZoneAreaOf(coord *geo.Coord) *CityZoneArea {
+	center := wm.GetMapCenter()
+	dx := int64(coord.X - center.X)
+	dy := int64(coord.Y - center.Y)
+	dist2 := dx*dx + dy*dy
+	for _, area := range wm.cityZones {
+		zoneConfig := wm.getCityZoneConfig(area.ZoneID)
+		if zoneConfig == nil {
+			continue
+		}
+		minR2 := int64(zoneConfig.MinRadius) * int64(zoneConfig.MinRadius)
+		maxR2 := int64(zoneConfig.MaxRadius) * int64(zoneConfig.MaxRadius)
+		if dist2 >= minR2 && dist2 <= maxR2 {
+			return area
+		}
+	}
+	return nil
+}
+
+// onCityAdded 主城加入地图后计入所在区域，并轮转到落点的下一个扇区
+func (wm *WorldMap) onCityAdded(coord *geo.Coord) {
+	area := wm.cityZoneAreaOf(coord)
+	if area == nil {
+		return
+	}
+	area.CurCount++
+
+	sectors := wm.getCityZoneConfig(area.ZoneID).Sectors
+	if sectors <= 0 {
+		sectors = 1
+	}
+	center := wm.GetMapCenter()
+	angle := math.Atan2(float64(coord.Y-center.Y), float64(coord.X-center.X))
+	if angle < 0 {
+		angle += 2 * math.Pi
+	}
+	sector := int32(angle/(2*math.Pi/float64(sectors))) % sectors
+	area.CurIndex = (sector + 1) % sectors
+}
+
+// onCityRemoved 主城离开地图后从所在区域的计数中移除，并把坐标放回位置池
+func (wm *WorldMap) onCityRemoved(coord *geo.Coord) {
+	if area := wm.cityZoneAreaOf(coord); area != nil && area.CurCount > 0 {
+		area.CurCount--
+	}
+	if wm.citySlotPool != nil {
+		wm.citySlotPool.OnCityRemoved(coord)
+	}
+}
+
+// isCityZoneFull 主城区域是否已满
+func (wm *WorldMap) isCityZoneFull(area *CityZoneArea) bool {
+	zoneConfig := wm.getCityZoneConfig(area.ZoneID)
+	if zoneConfig == nil {
+		return true
+	}
+	return zoneConfig.MaxCities > 0 && area.CurCount >= zoneConfig.MaxCities
+}
+
+// getNpcConfig 获取NPC配置
+func (wm *WorldMap) getNpcConfig(confId int32) *config.NpcConfig {
+	for i := range wm.mapConfig.Npcs {
+		if wm.mapConfig.Npcs[i].NpcID == confId {
+			return &wm.mapConfig.Npcs[i]
+		}
+	}
+	return nil
+}
+
+// isCoordInMap 坐标是否在地图范围内
+func (wm *WorldMap) isCoordInMap(coord *geo.Coord) bool {
+	mapSize := wm.GetMapSize()
+	return coord.X >= 0 && coord.X < mapSize.Width && coord.Y >= 0 && coord.Y < mapSize.Height
+}
+
+// getCityZoneConfig 获取主城区域配置
+func (wm *WorldMap) getCityZoneConfig(zoneId int32) *config.CityZoneConfig {
+	for i := range wm.mapConfig.CityZones {
+		if wm.mapConfig.CityZones[i].ZoneID == zoneId {
+			return &wm.mapConfig.CityZones[i]
+		}
+	}
+	return nil
+}
+
+// GetCitySlotPool 获取主城位置池（未启用时返回nil）
+func (wm *WorldMap) GetCitySlotPool() *CitySlotPool {
+	return wm.citySlotPool
+}
+
+// GetCityZoneAreas 获取所有主城区域
+func (wm *WorldMap) GetCityZoneAreas() []*CityZoneArea {
+	return wm.cityZones
+}
+
+// hexToCoord 六边形中心转矩形坐标（限制在地图范围内）
+func (wm *WorldMap) hexToCoord(hex *geo.HexCoord) *geo.Coord {
+	return wm.coords.HexToCoord(hex)
+}
+
+// worldToCoord 浮点世界坐标转矩形坐标（限制在地图范围内）
+func (wm *WorldMap) worldToCoord(x, y float64) *geo.Coord {
+	return wm.coords.WorldToCoord(x, y)
+}
+
+// terrainCostModel 获取只考虑地形的寻路成本模型（未设置地形时为nil）
+func (wm *WorldMap) terrainCostModel() *PathCostModel {
+	if !wm.hasTerrain() {
+		return nil
+	}
+	return &PathCostModel{
+		StepCost: func(from, to *geo.HexCoord) (float64, bool) {
+			return terrainPathCost(wm.getTerrainConfig(to))
+		},
+		MinStepCost: minPassableMoveCost(),
+	}
+}
+
+// hasTerrain 是否设置了地形（正方形地图的地形由拓扑按格子保存，总是存在）
+func (wm *WorldMap) hasTerrain() bool {
+	return wm.terrainMap != nil || wm.topology.GetType() == config.TopologyType_Square
+}
+
+// getTerrainConfig 六边形的地形配置，行军、NPC、迷雾和建造都通过它取地形
+// 地形按地图拓扑的格子保存：正方形地图取六边形中心所在正方形格子的地形，六边形地图取地形地图（未设置时为平原）
+func (wm *WorldMap) getTerrainConfig(hex *geo.HexCoord) *TerrainConfig {
+	if wm.topology.GetType() == config.TopologyType_Square {
+		return GetTerrainConfig(wm.topology.GetTerrain(wm.hexToCoord(hex)))
+	}
+	if wm.terrainMap == nil {
+		return GetTerrainConfig(TerrainType_Plain)
+	}
+	return wm.terrainMap.GetTerrainConfig(hex)
+}
+
+// coordToHex 矩形坐标转六边形坐标
+func (wm *WorldMap) coordToHex(coord *geo.Coord) *geo.HexCoord {
+	return wm.coords.CoordToHex(coord)
+}
+
 // 在指定位置创建一个Npc部队
 func (wm *WorldMap) NewNpcTroop(confId int32, level int32, coord *geo.Coord) Unit {
-	return nil
+	npcConfig := wm.getNpcConfig(confId)
+	if npcConfig == nil {
+		return nil
+	}
+	if level <= 0 || level < npcConfig.MinLevel || (npcConfig.MaxLevel > 0 && level > npcConfig.MaxLevel) {
+		return nil
+	}
+	if !wm.isCoordInMap(coord) || !wm.obstacleMgr.CanSpawnMonsterAt(coord.X, coord.Y) {
+		return nil
+	}
+
+	npc := NewNpcUnit(GetIDGenerator().GenerateNewID(), *coord, wm.coordToHex(coord), level, npcConfig)
+	if !wm.AddUnit(npc) {
+		return nil
+	}
+	wm.npcMgr.attachBehavior(npc)
+	return npc
+}
+
+// AddUnit 将单位注册到单位管理器、网格和六边形网格，并通知观察者
+// 六边形坐标总是按矩形坐标换算，覆盖单位上已设置的值，保证两种坐标描述同一个位置
+// 坐标不在地图内时不加入，返回 false
+func (wm *WorldMap) AddUnit(unit Unit) bool {
+	hex := wm.coords.CoordToHex(unit.GetCoord())
+	if !wm.isCoordInMap(unit.GetCoord()) || !wm.hexGridMgr.Contains(hex) {
+		return false
+	}
+	unit.SetHexCoord(hex)
+	wm.unitMgr.AddUnit(unit)
+	wm.gridMgr.AddUnit(unit)
+	wm.hexGridMgr.AddUnitToGrid(unit, unit.GetHexCoord())
+	if unit.GetType() == MapUnitType_PlayerCity {
+		wm.onCityAdded(unit.GetCoord())
+		wm.territoryMgr.OnCityAdded(unit)
+	}
+	// 先通知观察者新单位本身，再更新迷雾，新视野中的其他单位由迷雾变化通知
+	wm.observerMgr.OnUnitAdded(unit)
+	wm.fogMgr.OnUnitAdded(unit)
+	return true
+}
+
+// RemoveUnit 将单位从单位管理器、网格和六边形网格中移除，并通知观察者
+// 单位离开网格后才移除它的视野，迷雾变化通知不会再看到这个单位
+func (wm *WorldMap) RemoveUnit(unit Unit) {
+	wm.observerMgr.OnUnitRemoved(unit)
+	wm.unitMgr.RemoveUnit(unit)
+	wm.gridMgr.RemoveUnit(unit)
+	if hex := unit.GetHexCoord(); hex != nil {
+		wm.hexGridMgr.RemoveUnitFromGrid(unit, hex)
+	}
+	wm.fogMgr.OnUnitRemoved(unit)
+	if unit.GetType() == MapUnitType_PlayerCity {
+		wm.onCityRemoved(unit.GetCoord())
+		wm.territoryMgr.OnCityRemoved(unit)
+	}
+}
+
+// MoveUnit 将单位移动到矩形坐标，六边形坐标由坐标系换算
+// 同时更新矩形网格、六边形网格、迷雾视野并通知观察者，地图上的单位都应通过它（或 MoveUnitToHex）移动
+func (wm *WorldMap) MoveUnit(unit Unit, coord *geo.Coord) bool {
+	if !wm.isCoordInMap(coord) {
+		return false
+	}
+	wm.moveUnit(unit, coord, wm.coords.CoordToHex(coord))
+	return true
+}
+
+// MoveUnitToHex 将单位移动到指定六边形的中心
+func (wm *WorldMap) MoveUnitToHex(unit Unit, hex *geo.HexCoord) bool {
+	if !wm.hexGridMgr.Contains(hex) {
+		return false
+	}
+	wm.moveUnit(unit, wm.coords.HexToCoord(hex), hex)
+	return true
+}
+
+// moveUnit 同时更新单位的六边形和矩形坐标
+// 先更新六边形（迷雾按新六边形计算视野），再更新矩形坐标并通知观察者（可见性按新位置判断）
+func (wm *WorldMap) moveUnit(unit Unit, coord *geo.Coord, hex *geo.HexCoord) {
+	if from := unit.GetHexCoord(); !hex.Equal(from) {
+		if from != nil {
+			wm.hexGridMgr.RemoveUnitFromGrid(unit, from)
+		}
+		wm.hexGridMgr.AddUnitToGrid(unit, hex)
+		unit.SetHexCoord(hex)
+		wm.fogMgr.OnUnitMoved(unit)
+		if unit.GetType() == MapUnitType_PlayerCity {
+			wm.territoryMgr.OnCityMoved(unit)
+		}
+	}
+	if unit.GetType() == MapUnitType_PlayerCity && *unit.GetCoord() != *coord {
+		// 迁城：从原区域移除，计入新区域
+		wm.onCityRemoved(unit.GetCoord())
+		wm.onCityAdded(coord)
+	}
+	wm.updateUnitCoord(unit, coord)
+}
+
+// updateUnitCoord 更新单位的矩形坐标和所在网格，并通知观察者
+func (wm *WorldMap) updateUnitCoord(unit Unit, coord *geo.Coord) {
+	from := *unit.GetCoord()
+	if from == *coord {
+		return
+	}
+	wm.gridMgr.updateUnitCoord(unit, coord)
+	if unit.GetType() == MapUnitType_Npc {
+		wm.npcMgr.OnNpcMoved(unit)
+	}
+	wm.observerMgr.OnUnitMoved(unit, &from)
+}
+
+// GetUnit 根据id获取单位
+func (wm *WorldMap) GetUnit(unitId int64) Unit {
+	return wm.unitMgr.GetUnitById(unitId)
 }
 
 // 获取可见单位
@@ -62,7 +513,7 @@ func (wm *WorldMap) GetVisibleUnits(playerId int64, rect *geo.Rectangle) map[int
 		return retUnits
 	}
 
-	wm.gridMgr.RangeRectUnits(rect, true, func(unit Unit) bool {
+	wm.gridMgr.RangeRectUnits(rect, true, nil, func(unit Unit) bool {
 		if observer.IsVisible(unit) {
 			retUnits[unit.GetId()] = unit
 		}
@@ -76,6 +527,28 @@ func (wm *WorldMap) GetVisibleUnits(playerId int64, rect *geo.Rectangle) map[int
 	return retUnits
 }
 
+// 获取矩形区域按超级格子汇总的单位统计，关系按 viewer 计算
+func (wm *WorldMap) GetClusterSummaries(viewer *Owner, rect *geo.Rectangle, cellSize int32) []*ClusterSummary {
+	return wm.gridMgr.GetClusterSummaries(rect, cellSize, wm.relations, viewer)
+}
+
+// 获取已探索但当前不可见区域中最后已知的静态单位（未启用战争迷雾时为空）
+func (wm *WorldMap) GetLastKnownUnits(playerId int64, rect *geo.Rectangle) []*UnitSnapshot {
+	retSnapshots := make([]*UnitSnapshot, 0)
+	fog := wm.fogMgr.GetFog(playerId)
+	if fog == nil {
+		return retSnapshots
+	}
+
+	fog.RangeLastKnown(func(snapshot *UnitSnapshot) bool {
+		if rect.IsCoordInRect(&snapshot.Coord) {
+			retSnapshots = append(retSnapshots, snapshot)
+		}
+		return true
+	})
+	return retSnapshots
+}
+
 func (wm *WorldMap) GetConfig() *config.MapConfig {
 	return wm.mapConfig
 }
@@ -83,3 +556,119 @@ func (wm *WorldMap) GetConfig() *config.MapConfig {
 func (wm *WorldMap) GetMapSize() *config.MapSize {
 	return wm.mapConfig.MapSize
 }
+
+// GetMapCenter 获取地图中心坐标
+func (wm *WorldMap) GetMapCenter() *geo.Coord {
+	return geo.NewCoord(wm.mapConfig.MapSize.Width/2, wm.mapConfig.MapSize.Height/2)
+}
+
+func (wm *WorldMap) GetObstacleManager() *ObstacleManager {
+	return wm.obstacleMgr
+}
+
+func (wm *WorldMap) GetUnitManager() *UnitManager {
+	return wm.unitMgr
+}
+
+func (wm *WorldMap) GetGridManager() *GridManager {
+	return wm.gridMgr
+}
+
+func (wm *WorldMap) GetObserverManager() *ObserverManager {
+	return wm.observerMgr
+}
+
+func (wm *WorldMap) GetNpcManager() *NpcManager {
+	return wm.npcMgr
+}
+
+func (wm *WorldMap) GetTroopManager() *TroopManager {
+	return wm.troopMgr
+}
+
+func (wm *WorldMap) GetInterceptionDetector() *InterceptionDetector {
+	return wm.interception
+}
+
+func (wm *WorldMap) GetRelationRegistry() *RelationRegistry {
+	return wm.relations
+}
+
+// 设置关系注册表（多张地图共享外交关系时使用），关系变化会通知本地图的观察者
+func (wm *WorldMap) SetRelationRegistry(registry *RelationRegistry) {
+	if wm.relations != nil {
+		wm.relations.RemoveListener(wm.relationListen)
+	}
+	wm.relations = registry
+	wm.relationListen = registry.AddListener(wm.onRelationChanged)
+	if wm.territoryMgr != nil {
+		wm.territoryMgr.refreshCities(nil)
+	}
+	if wm.interception != nil {
+		wm.interception.onRelationChanged()
+	}
+}
+
+// 关系变化：先转移主城领土、重新分配共享视野，再通知观察者，行军相遇在下次检测时重新计算
+func (wm *WorldMap) onRelationChanged(change *RelationChange) {
+	wm.territoryMgr.OnRelationChanged(change)
+	wm.fogMgr.OnRelationChanged(change)
+	wm.observerMgr.OnRelationChanged(change)
+	wm.interception.onRelationChanged()
+}
+
+// 获取两个owner之间的关系
+func (wm *WorldMap) GetOwnerRelation(owner1 *Owner, owner2 *Owner) Relation {
+	return wm.relations.GetRelation(owner1, owner2)
+}
+
+// 创建以 viewer 为观察者的空间查询过滤条件
+func (wm *WorldMap) NewUnitFilter(viewer *Owner) *UnitFilter {
+	return NewUnitFilter(wm.relations, viewer)
+}
+
+// 两个owner是否敌对
+func (wm *WorldMap) IsHostile(owner1 *Owner, owner2 *Owner) bool {
+	return wm.relations.IsHostile(owner1, owner2)
+}
+
+func (wm *WorldMap) GetFogManager() *FogManager {
+	return wm.fogMgr
+}
+
+func (wm *WorldMap) GetTerritoryManager() *TerritoryManager {
+	return wm.territoryMgr
+}
+
+func (wm *WorldMap) GetResourceManager() *ResourceManager {
+	return wm.resourceMgr
+}
+
+func (wm *WorldMap) GetMarchResolverRegistry() *MarchResolverRegistry {
+	return wm.marchResolvers
+}
+
+// 注册行军意图的到达结算器
+func (wm *WorldMap) RegisterMarchResolver(intent MarchIntent, resolver MarchResolver) {
+	wm.marchResolvers.Register(intent, resolver)
+}
+
+func (wm *WorldMap) GetHexGridManager() *HexGridManager {
+	return wm.hexGridMgr
+}
+
+// GetCoordSystem 获取地图坐标系
+func (wm *WorldMap) GetCoordSystem() *CoordSystem {
+	return wm.coords
+}
+
+// GetTopology 获取地图拓扑（空间查询、移动、距离和寻路的统一接口）
+func (wm *WorldMap) GetTopology() Topology {
+	return wm.topology
+}
+
+// SetTerrainMap 设置地形（六边形地图；正方形地图的地形通过 Topology.SetTerrain 按格子设置，地形地图不生效）
+func (wm *WorldMap) SetTerrainMap(terrainMap *TerrainMap) {
+	wm.terrainMap = terrainMap
+	wm.fogMgr.Rebuild()
+}
@@ -0,0 +1,279 @@
+package worldmap
+
+import (
+	"testing"
+	"time"
+
+	"github.com/GooLuck/WorldMap/internal/worldmap/config"
+	"github.com/GooLuck/WorldMap/internal/worldmap/geo"
+)
+
+// newTestMapConfig 创建测试用地图配置
+func newTestMapConfig() *config.MapConfig {
+	return &config.MapConfig{
+		MapID:   1,
+		MapName: "test",
+		MapSize: &config.MapSize{
+			Width:      1000,
+			Height:     1000,
+			GridWidth:  100,
+			GridHeight: 100,
+		},
+		CityRadius: 2,
+	}
+}
+
+// TestNewCityCoordInZones 测试按区域顺序分配主城坐标
+func TestNewCityCoordInZones(t *testing.T) {
+	mapConfig := newTestMapConfig()
+	mapConfig.CityZones = []config.CityZoneConfig{
+		{ZoneID: 1, MinRadius: 0, MaxRadius: 100, MaxCities: 3, Sectors: 3},
+		{ZoneID: 2, MinRadius: 200, MaxRadius: 300, MaxCities: 0, Sectors: 4},
+	}
+	wm := NewWorldMap(mapConfig)
+	center := wm.GetMapCenter()
+
+	for i := 0; i < 5; i++ {
+		coord, ok := wm.NewCityCoord()
+		if !ok {
+			t.Fatalf("第 %d 次分配主城坐标失败", i)
+		}
+
+		dx := float64(coord.X - center.X)
+		dy := float64(coord.Y - center.Y)
+		dist := sqrtFloat(dx*dx + dy*dy)
+		if i < 3 && dist > 101 {
+			t.Errorf("前 3 座主城应该在第一个区域：距离 %.2f", dist)
+		}
+		if i >= 3 && (dist < 199 || dist > 301) {
+			t.Errorf("第一个区域满后应该落在第二个区域：距离 %.2f", dist)
+		}
+
+		wm.AddUnit(&TestUnit{
+			id:       int64(i + 1),
+			coord:    *coord,
+			unitType: MapUnitType_PlayerCity,
+			owner:    NewPlayerOwner(int64(i + 1)),
+		})
+	}
+
+	areas := wm.GetCityZoneAreas()
+	if areas[0].CurCount != 3 || areas[1].CurCount != 2 {
+		t.Errorf("区域计数错误：期望 (3, 2), 得到 (%d, %d)", areas[0].CurCount, areas[1].CurCount)
+	}
+
+	// 只取坐标不建城时不计数
+	if _, ok := wm.NewCityCoord(); !ok {
+		t.Fatal("分配主城坐标失败")
+	}
+	if areas[1].CurCount != 2 {
+		t.Errorf("未建城时区域计数不应该变化：期望 2, 得到 %d", areas[1].CurCount)
+	}
+
+	// 主城移除后从区域计数中移除
+	wm.RemoveUnit(wm.GetUnit(1))
+	if areas[0].CurCount != 2 {
+		t.Errorf("移除主城后区域计数错误：期望 2, 得到 %d", areas[0].CurCount)
+	}
+}
+
+// TestCanPlaceCity 测试主城放置检查
+func TestCanPlaceCity(t *testing.T) {
+	mapConfig := newTestMapConfig()
+	mapConfig.SpawnPoints = []config.SpawnPointConfig{
+		{PointID: 1, X: 500, Y: 500, Radius: 20},
+	}
+	mapConfig.Obstacles = []config.ObstacleConfig{
+		{ObstacleID: 1, X: 100, Y: 100, Width: 10, Height: 10, BlockBuilding: true},
+	}
+	wm := NewWorldMap(mapConfig)
+
+	if !wm.CanPlaceCity(geo.NewCoord(300, 300), 2) {
+		t.Error("空地应该可以放置主城")
+	}
+	if wm.CanPlaceCity(geo.NewCoord(1, 300), 2) {
+		t.Error("占地超出地图边界时不应该可以放置主城")
+	}
+	if wm.CanPlaceCity(geo.NewCoord(510, 500), 2) {
+		t.Error("出生点安全区内不应该可以放置主城")
+	}
+	if wm.CanPlaceCity(geo.NewCoord(105, 105), 2) {
+		t.Error("障碍物上不应该可以放置主城")
+	}
+	// 障碍物占 100..109 列，距离最后一列 2，占地半径 2，刚好接触
+	if wm.CanPlaceCity(geo.NewCoord(111, 105), 2) {
+		t.Error("占地范围与障碍物接触时不应该可以放置主城")
+	}
+	if !wm.CanPlaceCity(geo.NewCoord(112, 105), 2) {
+		t.Error("占地范围与障碍物不接触时应该可以放置主城")
+	}
+
+	wm.gridMgr.AddUnit(&TestUnit{
+		id:       1,
+		coord:    geo.Coord{X: 300, Y: 300},
+		unitType: MapUnitType_PlayerCity,
+		owner:    NewPlayerOwner(1),
+	})
+	if wm.CanPlaceCity(geo.NewCoord(303, 302), 2) {
+		t.Error("与已有主城占地重叠时不应该可以放置主城")
+	}
+	if !wm.CanPlaceCity(geo.NewCoord(305, 300), 2) {
+		t.Error("与已有主城不重叠时应该可以放置主城")
+	}
+}
+
+// TestRandomCityCoord 测试随机主城坐标
+func TestRandomCityCoord(t *testing.T) {
+	wm := NewWorldMap(newTestMapConfig())
+	coord, ok := wm.RandomCityCoord()
+	if !ok {
+		t.Fatal("随机主城坐标失败")
+	}
+	if !wm.CanPlaceCity(coord, wm.GetConfig().CityRadius) {
+		t.Error("随机得到的主城坐标应该可以放置主城")
+	}
+}
+
+// TestCitySlotPool 测试主城位置池的预留、确认与释放
+func TestCitySlotPool(t *testing.T) {
+	mapConfig := newTestMapConfig()
+	mapConfig.CityZones = []config.CityZoneConfig{
+		{ZoneID: 1, MinRadius: 0, MaxRadius: 50, MaxCities: 2, Sectors: 4},
+		{ZoneID: 2, MinRadius: 100, MaxRadius: 200, Sectors: 4},
+	}
+	mapConfig.CitySlotPool = config.CitySlotPoolConfig{
+		PoolSize:       8,
+		RefillPerTick:  100,
+		ReserveTimeout: 60,
+	}
+	wm := NewWorldMap(mapConfig)
+	pool := wm.GetCitySlotPool()
+	if pool == nil {
+		t.Fatal("配置了位置池时应该创建位置池")
+	}
+	if pool.GetFreeCount(1) != 8 || pool.GetFreeCount(2) != 8 {
+		t.Fatalf("首次填充数量错误：期望 (8, 8), 得到 (%d, %d)", pool.GetFreeCount(1), pool.GetFreeCount(2))
+	}
+
+	now := time.Now()
+	first, ok := pool.Reserve(now)
+	if !ok || first.ZoneID != 1 {
+		t.Fatal("第一次预留应该落在区域 1")
+	}
+	if wm.CanPlaceCity(&first.Coord, first.Radius) {
+		t.Error("已预留的位置不应该再被其他主城占用")
+	}
+
+	second, _ := pool.Reserve(now)
+	third, ok := pool.Reserve(now)
+	if !ok || third.ZoneID != 2 {
+		t.Error("区域 1 预留满后应该落在区域 2")
+	}
+
+	// 释放后区域 1 重新可用
+	pool.Release(second.Id)
+	fourth, ok := pool.Reserve(now)
+	if !ok || fourth.ZoneID != 1 || fourth.Coord != second.Coord {
+		t.Error("释放的位置应该优先被重新预留")
+	}
+
+	city := &TestUnit{id: 1, unitType: MapUnitType_PlayerCity, owner: NewPlayerOwner(1)}
+	if !pool.Commit(first.Id, city) {
+		t.Fatal("确认预留失败")
+	}
+	if *city.GetCoord() != first.Coord || wm.GetUnitManager().GetUnitById(city.GetId()) == nil {
+		t.Error("确认预留后主城应该在预留坐标加入地图")
+	}
+	if pool.GetReservation(first.Id) != nil {
+		t.Error("主城加入地图后预留应该被释放")
+	}
+	if wm.GetCityZoneAreas()[0].CurCount != 1 {
+		t.Errorf("建城后区域计数错误：期望 1, 得到 %d", wm.GetCityZoneAreas()[0].CurCount)
+	}
+	// 主城已计入区域数量，区域 1 仍然是满的
+	if _, ok := pool.ReserveInZone(1, now); ok {
+		t.Error("建城后区域 1 的主城与预留数量已达上限，不应该再预留")
+	}
+
+	// 超时的预留自动释放并补充位置池
+	wm.Update(now.Add(2 * time.Minute))
+	if pool.GetReservation(third.Id) != nil || pool.GetReservation(fourth.Id) != nil {
+		t.Error("超时的预留应该被释放")
+	}
+	if pool.GetFreeCount(2) != 8 {
+		t.Errorf("补充后空闲数量错误：期望 8, 得到 %d", pool.GetFreeCount(2))
+	}
+}
+
+// TestCitySlotPoolIndex 测试按网格分桶的预留重叠检查和候选位置的最小间距
+func TestCitySlotPoolIndex(t *testing.T) {
+	mapConfig := newTestMapConfig()
+	mapConfig.CityRadius = 0
+	mapConfig.CityZones = []config.CityZoneConfig{{ZoneID: 1, MinRadius: 0, MaxRadius: 50, Sectors: 4}}
+	mapConfig.CitySlotPool = config.CitySlotPoolConfig{PoolSize: 8, RefillPerTick: 100}
+	wm := NewWorldMap(mapConfig)
+	pool := wm.GetCitySlotPool()
+
+	// 占地半径为0时候选位置按最小间距生成，不枚举每一个坐标
+	maxCandidates := (2*50/int(minCandidateStep) + 1) * (2*50/int(minCandidateStep) + 1)
+	if count := len(pool.zones[0].candidates); count == 0 || count > maxCandidates {
+		t.Errorf("候选位置数量错误：期望 (0, %d], 得到 %d", maxCandidates, count)
+	}
+
+	// 跨网格边界的预留也能检测到重叠
+	reservation := &CitySlotReservation{Id: 1, Coord: *geo.NewCoord(99, 99), Radius: 0}
+	pool.addReservation(reservation)
+	if !pool.isOverlapReservation(geo.NewCoord(100, 100), 1) {
+		t.Error("相邻网格中的预留应该重叠")
+	}
+	if pool.isOverlapReservation(geo.NewCoord(101, 99), 1) {
+		t.Error("占地不相交的位置不应该重叠")
+	}
+	pool.removeReservation(reservation)
+	if pool.isOverlapReservation(geo.NewCoord(99, 99), 1) {
+		t.Error("删除后的预留不应该重叠")
+	}
+}
+
+// TestNewNpcTroop 测试创建NPC部队
+func TestNewNpcTroop(t *testing.T) {
+	mapConfig := newTestMapConfig()
+	mapConfig.Npcs = []config.NpcConfig{
+		{NpcID: 1, MinLevel: 1, MaxLevel: 10, BaseHp: 100, HpPerLevel: 10, BasePower: 50, PowerPerLevel: 5},
+	}
+	mapConfig.Obstacles = []config.ObstacleConfig{
+		{ObstacleID: 1, X: 100, Y: 100, Width: 10, Height: 10, BlockMonster: true},
+	}
+	wm := NewWorldMap(mapConfig)
+
+	unit := wm.NewNpcTroop(1, 3, geo.NewCoord(300, 300))
+	if unit == nil {
+		t.Fatal("创建NPC失败")
+	}
+	npc := unit.(*NpcUnit)
+	if npc.GetMaxHp() != 120 || npc.GetPower() != 60 {
+		t.Errorf("NPC属性错误：期望 (120, 60), 得到 (%d, %d)", npc.GetMaxHp(), npc.GetPower())
+	}
+	if npc.GetOwner().Type != OwnerType_System {
+		t.Error("NPC应该属于系统")
+	}
+	if wm.GetUnit(npc.GetId()) == nil {
+		t.Error("NPC应该注册到单位管理器")
+	}
+	if !wm.gridMgr.GetGridByCoord(npc.GetCoord()).IsExistUnit(npc) {
+		t.Error("NPC应该注册到网格")
+	}
+	if !wm.hexGridMgr.GetGrid(npc.GetHexCoord()).IsExistUnit(npc) {
+		t.Error("NPC应该注册到六边形网格")
+	}
+
+	if wm.NewNpcTroop(1, 3, geo.NewCoord(105, 105)) != nil {
+		t.Error("阻挡怪物的障碍物上不应该创建NPC")
+	}
+	if wm.NewNpcTroop(1, 11, geo.NewCoord(300, 300)) != nil {
+		t.Error("超出等级范围不应该创建NPC")
+	}
+	if wm.NewNpcTroop(2, 1, geo.NewCoord(300, 300)) != nil {
+		t.Error("未配置的NPC不应该被创建")
+	}
+}
@@ -0,0 +1,192 @@
+package worldmap
+
+import (
+	"slices"
+	"time"
+)
+
+// MarchIntent 行军意图
+type MarchIntent int32
+
+const (
+	MarchIntent_None      MarchIntent = iota // 无（单纯移动）
+	MarchIntent_Attack                       // 攻击
+	MarchIntent_Gather                       // 采集
+	MarchIntent_Reinforce                    // 增援
+	MarchIntent_Scout                        // 侦察
+	MarchIntent_Occupy                       // 占领
+)
+
+// MarchOutcome 到达结算后部队的后续动作
+type MarchOutcome int32
+
+const (
+	MarchOutcome_Return MarchOutcome = iota // 返回出发地
+	MarchOutcome_Stay                       // 留在目标处（驻防、采集、占领）
+	MarchOutcome_Remove                     // 部队移除（全军覆没等）
+)
+
+// MarchFailReason 到达结算失败原因
+type MarchFailReason int32
+
+const (
+	MarchFailReason_None             MarchFailReason = iota
+	MarchFailReason_TargetGone                       // 目标已不存在
+	MarchFailReason_TargetMoved                      // 目标已不在目标六边形
+	MarchFailReason_InvalidTarget                    // 目标类型不符合意图
+	MarchFailReason_RelationMismatch                 // 与目标的关系不符合意图
+	MarchFailReason_TargetOccupied                   // 目标资源点已被其他部队占领
+	MarchFailReason_NoResolver                       // 意图未注册结算器
+)
+
+// MarchResolver 行军到达结算器，由游戏服按意图注册战斗、采集、增援、侦察等逻辑
+type MarchResolver interface {
+	// Resolve 结算到达，target 为目标单位（前往空地时为nil）
+	Resolve(wm *WorldMap, troop *TroopUnit, target Unit, now time.Time) MarchOutcome
+}
+
+// MarchResolverFunc 函数形式的结算器
+type MarchResolverFunc func(wm *WorldMap, troop *TroopUnit, target Unit, now time.Time) MarchOutcome
+
+func (f MarchResolverFunc) Resolve(wm *WorldMap, troop *TroopUnit, target Unit, now time.Time) MarchOutcome {
+	return f(wm, troop, target, now)
+}
+
+// MarchFailCallback 到达结算失败回调，失败后部队自动返回出发地
+type MarchFailCallback func(troop *TroopUnit, reason MarchFailReason, now time.Time)
+
+// marchIntentRule 意图对目标的通用要求
+type marchIntentRule struct {
+	needTarget  bool          // 是否必须有目标单位
+	targetTypes []MapUnitType // 允许的目标类型（为空表示不限）
+	relations   []Relation    // 允许的关系（为空表示不限）
+}
+
+var marchIntentRules = map[MarchIntent]marchIntentRule{
+	MarchIntent_Attack: {
+		needTarget:  true,
+		targetTypes: []MapUnitType{MapUnitType_PlayerCity, MapUnitType_PlayerTroop, MapUnitType_Npc},
+		relations:   []Relation{Relation_Enemy, Relation_Neutral, Relation_System},
+	},
+	MarchIntent_Gather: {
+		needTarget:  true,
+		targetTypes: []MapUnitType{MapUnitType_Resource},
+	},
+	MarchIntent_Reinforce: {
+		needTarget:  true,
+		targetTypes: []MapUnitType{MapUnitType_PlayerCity, MapUnitType_PlayerTroop},
+		relations:   []Relation{Relation_Self, Relation_Union, Relation_Ally},
+	},
+	MarchIntent_Scout: {
+		relations: []Relation{Relation_Enemy, Relation_Neutral, Relation_System},
+	},
+	MarchIntent_Occupy: {
+		relations: []Relation{Relation_Enemy, Relation_Neutral, Relation_System},
+	},
+}
+
+// MarchResolverRegistry 行军到达结算器注册表
+type MarchResolverRegistry struct {
+	worldMap      *WorldMap
+	resolvers     map[MarchIntent]MarchResolver
+	failCallbacks []MarchFailCallback
+}
+
+// NewMarchResolverRegistry 创建结算器注册表
+func NewMarchResolverRegistry(worldMap *WorldMap) *MarchResolverRegistry {
+	return &MarchResolverRegistry{
+		worldMap:      worldMap,
+		resolvers:     make(map[MarchIntent]MarchResolver),
+		failCallbacks: make([]MarchFailCallback, 0),
+	}
+}
+
+// Register 注册意图的结算器，重复注册会覆盖
+func (r *MarchResolverRegistry) Register(intent MarchIntent, resolver MarchResolver) {
+	r.resolvers[intent] = resolver
+}
+
+// GetResolver 获取意图的结算器
+func (r *MarchResolverRegistry) GetResolver(intent MarchIntent) MarchResolver {
+	return r.resolvers[intent]
+}
+
+// AddFailCallback 注册结算失败回调
+func (r *MarchResolverRegistry) AddFailCallback(callback MarchFailCallback) {
+	r.failCallbacks = append(r.failCallbacks, callback)
+}
+
+// CheckTarget 检查部队以指定意图前往目标是否合法，出发前和到达时都会检查
+func (r *MarchResolverRegistry) CheckTarget(troop *TroopUnit, intent MarchIntent, target Unit) MarchFailReason {
+	rule, exists := marchIntentRules[intent]
+	if !exists {
+		return MarchFailReason_None
+	}
+	if target == nil {
+		if rule.needTarget {
+			return MarchFailReason_TargetGone
+		}
+		return MarchFailReason_None
+	}
+
+	if len(rule.targetTypes) > 0 && !slices.Contains(rule.targetTypes, target.GetType()) {
+		return MarchFailReason_InvalidTarget
+	}
+	if len(rule.relations) > 0 && !slices.Contains(rule.relations, r.worldMap.GetOwnerRelation(troop.GetOwner(), target.GetOwner())) {
+		return MarchFailReason_RelationMismatch
+	}
+	if resource, ok := target.(*ResourceUnit); ok && intent == MarchIntent_Gather &&
+		resource.IsOccupied() && resource.GetOccupierId() != troop.GetId() {
+		return MarchFailReason_TargetOccupied
+	}
+	return MarchFailReason_None
+}
+
+// resolve 结算到达的行军
+func (r *MarchResolverRegistry) resolve(troop *TroopUnit, now time.Time) {
+	intent := troop.GetIntent()
+	if intent == MarchIntent_None {
+		return
+	}
+
+	target, reason := r.findTarget(troop)
+	if reason == MarchFailReason_None {
+		reason = r.CheckTarget(troop, intent, target)
+	}
+	resolver := r.resolvers[intent]
+	if reason == MarchFailReason_None && resolver == nil {
+		reason = MarchFailReason_NoResolver
+	}
+	if reason != MarchFailReason_None {
+		for _, callback := range r.failCallbacks {
+			callback(troop, reason, now)
+		}
+		troop.setIntent(MarchIntent_None, 0)
+		r.worldMap.troopMgr.ReturnHome(troop, now)
+		return
+	}
+
+	switch resolver.Resolve(r.worldMap, troop, target, now) {
+	case MarchOutcome_Return:
+		troop.setIntent(MarchIntent_None, 0)
+		r.worldMap.troopMgr.ReturnHome(troop, now)
+	case MarchOutcome_Remove:
+		r.worldMap.troopMgr.RemoveTroop(troop.GetId())
+	}
+}
+
+// findTarget 查找部队的目标单位，目标必须仍在行军目标六边形内
+func (r *MarchResolverRegistry) findTarget(troop *TroopUnit) (Unit, MarchFailReason) {
+	if troop.GetTargetId() == 0 {
+		return nil, MarchFailReason_None
+	}
+
+	target := r.worldMap.GetUnit(troop.GetTargetId())
+	if target == nil {
+		return nil, MarchFailReason_TargetGone
+	}
+	if target.GetHexCoord() == nil || !target.GetHexCoord().Equal(troop.GetHexCoord()) {
+		return nil, MarchFailReason_TargetMoved
+	}
+	return target, MarchFailReason_None
+}
@@ -0,0 +1,157 @@
+package worldmap
+
+import (
+	"math"
+	"sort"
+
+	"github.com/GooLuck/WorldMap/internal/worldmap/geo"
+)
+
+// nearestCandidate 最近邻查询的候选单位
+type nearestCandidate struct {
+	unit     Unit
+	distance float64
+}
+
+// nearestCollector 收集最近邻查询的候选单位，按距离排序（距离相同按id排序）
+type nearestCollector struct {
+	k           int32
+	maxDistance float64 // 最大距离（<=0表示不限）
+	candidates  []nearestCandidate
+}
+
+func newNearestCollector(k int32, maxDistance float64) *nearestCollector {
+	return &nearestCollector{
+		k:           k,
+		maxDistance: maxDistance,
+		candidates:  make([]nearestCandidate, 0, k),
+	}
+}
+
+// add 加入候选单位，超出最大距离的忽略
+func (c *nearestCollector) add(unit Unit, distance float64) {
+	if c.maxDistance > 0 && distance > c.maxDistance {
+		return
+	}
+	c.candidates = append(c.candidates, nearestCandidate{unit: unit, distance: distance})
+}
+
+// done 未遍历的单位距离都不小于 bound 时，已找到的前k个是否就是结果（严格小于，距离相同时按id排序）
+func (c *nearestCollector) done(bound float64) bool {
+	if c.maxDistance > 0 && bound > c.maxDistance {
+		return true
+	}
+	if int32(len(c.candidates)) < c.k {
+		return false
+	}
+	c.sort()
+	return c.candidates[c.k-1].distance < bound
+}
+
+func (c *nearestCollector) sort() {
+	sort.Slice(c.candidates, func(i, j int) bool {
+		if c.candidates[i].distance != c.candidates[j].distance {
+			return c.candidates[i].distance < c.candidates[j].distance
+		}
+		return c.candidates[i].unit.GetId() < c.candidates[j].unit.GetId()
+	})
+}
+
+// result 按距离排序的前k个单位
+func (c *nearestCollector) result() []Unit {
+	c.sort()
+	count := min(int32(len(c.candidates)), c.k)
+	retUnits := make([]Unit, 0, count)
+	for _, candidate := range c.candidates[:count] {
+		retUnits = append(retUnits, candidate.unit)
+	}
+	return retUnits
+}
+
+// GetNearestUnits 获取距离坐标最近的k个满足过滤条件的单位，按距离从近到远排序
+// 从坐标所在网格开始逐圈向外扩展，已找到k个且更外圈不可能更近时停止，有占地范围的单位按到占地矩形的距离计算
+// maxDistance: 最大距离（世界单位，<=0表示不限）
+func (mgr *GridManager) GetNearestUnits(coord *geo.Coord, k int32, maxDistance int32, filter *UnitFilter) []Unit {
+	if k <= 0 {
+		return make([]Unit, 0)
+	}
+	collector := newNearestCollector(k, float64(maxDistance))
+	var visited footprintVisited
+	gridWidth, gridHeight := mgr.mapSize.GridWidth, mgr.mapSize.GridHeight
+	centerX, centerY := floorDiv(coord.X, gridWidth), floorDiv(coord.Y, gridHeight)
+
+	for ring := int32(0); ; ring++ {
+		minX, maxX, minY, maxY := centerX-ring, centerX+ring, centerY-ring, centerY+ring
+		for y := max(minY, 0); y <= min(maxY, mgr.gridRows-1); y++ {
+			for x := max(minX, 0); x <= min(maxX, mgr.gridCols-1); x++ {
+				// 只遍历这一圈上的网格
+				if x != minX && x != maxX && y != minY && y != maxY {
+					continue
+				}
+				grid := mgr.grids[y*mgr.gridCols+x]
+				if grid == nil {
+					continue
+				}
+				for _, unit := range grid.GetUnits() {
+					if filter.Match(unit) && visited.firstVisit(unit) {
+						collector.add(unit, rectDistance(coord, unitFootprintRect(unit, unit.GetCoord())))
+					}
+				}
+			}
+		}
+
+		// 已遍历范围覆盖整个地图
+		if minX <= 0 && minY <= 0 && maxX >= mgr.gridCols-1 && maxY >= mgr.gridRows-1 {
+			break
+		}
+		// 更外圈的单位到坐标的距离不小于坐标到已遍历范围边缘的距离
+		bound := min(coord.X-minX*gridWidth, (maxX+1)*gridWidth-coord.X, coord.Y-minY*gridHeight, (maxY+1)*gridHeight-coord.Y)
+		if collector.done(float64(bound)) {
+			break
+		}
+	}
+	return collector.result()
+}
+
+// GetNearestUnits 获取距离中心最近的k个满足过滤条件的单位，按六边形步数从近到远排序（步数相同按id排序）
+// 从中心逐圈向外扩展，已找到k个时停止，有六边形占地半径的单位按最先遍历到的占地六边形计算步数
+// maxDistance: 最大步数（<=0表示不限）
+func (hgm *HexGridManager) GetNearestUnits(center *geo.HexCoord, k int32, maxDistance int32, filter *UnitFilter) []Unit {
+	if k <= 0 {
+		return make([]Unit, 0)
+	}
+	collector := newNearestCollector(k, float64(maxDistance))
+	var visited footprintVisited
+
+	// 地图边界四个角中最远的距离，超过后不再有网格
+	maxRing := int32(0)
+	for _, corner := range hgm.bounds.GetLineEnds() {
+		maxRing = max(maxRing, center.DistanceTo(corner))
+	}
+
+	for ring := int32(0); ring <= maxRing; ring++ {
+		for _, hex := range center.GetRing(ring) {
+			grid := hgm.GetGrid(hex)
+			if grid == nil {
+				continue
+			}
+			grid.RangeUnits(func(unit Unit) bool {
+				if filter.Match(unit) && visited.firstVisit(unit) {
+					collector.add(unit, float64(ring))
+				}
+				return true
+			})
+		}
+		if collector.done(float64(ring + 1)) {
+			break
+		}
+	}
+	return collector.result()
+}
+
+// rectDistance 坐标到矩形（包含的坐标范围）的欧几里得距离，在矩形内时为0
+func rectDistance(coord *geo.Coord, rect *geo.Rectangle) float64 {
+	dx := float64(max(rect.X-coord.X, coord.X-(rect.X+rect.Width-1), 0))
+	dy := float64(max(rect.Y-coord.Y, coord.Y-(rect.Y+rect.Height-1), 0))
+	return math.Sqrt(dx*dx + dy*dy)
+}
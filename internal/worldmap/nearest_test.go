@@ -0,0 +1,68 @@
+package worldmap
+
+import (
+	"testing"
+
+	"github.com/GooLuck/WorldMap/internal/worldmap/geo"
+)
+
+// TestNearestUnits 测试矩形网格和六边形网格的最近邻查询
+func TestNearestUnits(t *testing.T) {
+	wm := NewWorldMap(newTestMapConfig())
+	wm.GetRelationRegistry().SetPlayerAlliance(1, 100)
+	wm.GetRelationRegistry().SetPlayerAlliance(2, 100)
+
+	addUnit := func(id int64, x, y int32, unitType MapUnitType, owner *Owner) {
+		coord := geo.NewCoord(x, y)
+		wm.AddUnit(&TestUnit{id: id, coord: *coord, hexCoord: wm.coordToHex(coord), unitType: unitType, owner: owner})
+	}
+	addUnit(1, 510, 510, MapUnitType_PlayerCity, NewPlayerOwner(1))
+	addUnit(2, 590, 500, MapUnitType_PlayerCity, NewPlayerOwner(2))
+	addUnit(3, 450, 450, MapUnitType_PlayerCity, NewPlayerOwner(3))
+	addUnit(4, 900, 900, MapUnitType_PlayerCity, NewPlayerOwner(4))
+	addUnit(5, 40, 20, MapUnitType_PlayerCity, NewPlayerOwner(5))
+	addUnit(6, 505, 505, MapUnitType_Resource, nil)
+	addUnit(7, 20, 990, MapUnitType_Resource, nil)
+
+	checkIds := func(step string, units []Unit, expects ...int64) {
+		if len(units) != len(expects) {
+			t.Fatalf("%s结果数量错误：期望 %d, 得到 %d", step, len(expects), len(units))
+		}
+		for i, unit := range units {
+			if unit.GetId() != expects[i] {
+				t.Errorf("%s第 %d 个单位错误：期望 %d, 得到 %d", step, i, expects[i], unit.GetId())
+			}
+		}
+	}
+
+	gridMgr := wm.GetGridManager()
+	center := geo.NewCoord(500, 500)
+	checkIds("最近的主城", gridMgr.GetNearestUnits(center, 3, 0, NewUnitFilter(nil, nil).WithUnitTypes(MapUnitType_PlayerCity)), 1, 3, 2)
+	checkIds("最近的资源", gridMgr.GetNearestUnits(center, 1, 0, NewUnitFilter(nil, nil).WithUnitTypes(MapUnitType_Resource)), 6)
+	checkIds("全部单位", gridMgr.GetNearestUnits(center, 10, 0, nil), 6, 1, 3, 2, 4, 5, 7)
+	checkIds("最大距离", gridMgr.GetNearestUnits(center, 10, 80, nil), 6, 1, 3)
+	checkIds("地图边缘", gridMgr.GetNearestUnits(geo.NewCoord(0, 0), 1, 0, nil), 5)
+
+	enemyFilter := wm.NewUnitFilter(NewPlayerOwner(1)).WithRelations(Relation_Enemy)
+	checkIds("最近的敌方", gridMgr.GetNearestUnits(center, 2, 0, enemyFilter), 3, 4)
+
+	// 六边形网格按步数排序
+	hexGridMgr := wm.GetHexGridManager()
+	hexCenter := wm.coordToHex(center)
+	hexUnits := hexGridMgr.GetNearestUnits(hexCenter, 10, 0, nil)
+	if expect := len(hexGridMgr.GetAllUnits()); len(hexUnits) != expect {
+		t.Fatalf("六边形最近邻数量错误：期望 %d, 得到 %d", expect, len(hexUnits))
+	}
+	for i := 1; i < len(hexUnits); i++ {
+		prev := hexUnits[i-1].GetHexCoord().DistanceTo(hexCenter)
+		curr := hexUnits[i].GetHexCoord().DistanceTo(hexCenter)
+		if prev > curr || (prev == curr && hexUnits[i-1].GetId() > hexUnits[i].GetId()) {
+			t.Errorf("六边形最近邻顺序错误：第 %d 个距离 %d, 第 %d 个距离 %d", i-1, prev, i, curr)
+		}
+	}
+	for _, unit := range hexGridMgr.GetNearestUnits(hexCenter, 10, 1, nil) {
+		if unit.GetHexCoord().DistanceTo(hexCenter) > 1 {
+			t.Errorf("单位 %d 超出最大步数", unit.GetId())
+		}
+	}
+}
@@ -0,0 +1,89 @@
+package worldmap
+
+import (
+	"github.com/GooLuck/WorldMap/internal/worldmap/config"
+	"github.com/GooLuck/WorldMap/internal/worldmap/geo"
+)
+
+// NpcUnit NPC单位实现
+type NpcUnit struct {
+	*BaseUnit
+	config     *config.NpcConfig
+	level      int32
+	hp         int32
+	maxHp      int32
+	power      int32
+	spawnCoord geo.Coord // 出生坐标
+}
+
+// NewNpcUnit 创建新的NPC单位
+func NewNpcUnit(id int64, coord geo.Coord, hexCoord *geo.HexCoord, level int32, config *config.NpcConfig) *NpcUnit {
+	maxHp := config.BaseHp + config.HpPerLevel*(level-1)
+	return &NpcUnit{
+		BaseUnit:   NewBaseUnit(id, config.NpcID, coord, hexCoord, MapUnitType_Npc, NewOwner(0, OwnerType_System)),
+		config:     config,
+		level:      level,
+		hp:         maxHp,
+		maxHp:      maxHp,
+		power:      config.BasePower + config.PowerPerLevel*(level-1),
+		spawnCoord: coord,
+	}
+}
+
+// GetLevel 获取等级
+func (n *NpcUnit) GetLevel() int32 {
+	return n.level
+}
+
+// GetHp 获取当前生命值
+func (n *NpcUnit) GetHp() int32 {
+	return n.hp
+}
+
+// GetMaxHp 获取最大生命值
+func (n *NpcUnit) GetMaxHp() int32 {
+	return n.maxHp
+}
+
+// GetPower 获取战力
+func (n *NpcUnit) GetPower() int32 {
+	return n.power
+}
+
+// Damage 造成伤害，返回实际扣除的生命值
+func (n *NpcUnit) Damage(amount int32) int32 {
+	if amount <= 0 || n.hp <= 0 {
+		return 0
+	}
+	if amount > n.hp {
+		amount = n.hp
+	}
+	n.hp -= amount
+	return amount
+}
+
+// Heal 恢复生命值，不超过最大生命值
+func (n *NpcUnit) Heal(amount int32) {
+	if amount <= 0 || n.hp <= 0 {
+		return
+	}
+	n.hp += amount
+	if n.hp > n.maxHp {
+		n.hp = n.maxHp
+	}
+}
+
+// IsDead 是否已死亡
+func (n *NpcUnit) IsDead() bool {
+	return n.hp <= 0
+}
+
+// GetSpawnCoord 获取出生坐标
+func (n *NpcUnit) GetSpawnCoord() *geo.Coord {
+	return &n.spawnCoord
+}
+
+// GetConfig 获取NPC配置
+func (n *NpcUnit) GetConfig() *config.NpcConfig {
+	return n.config
+}
@@ -0,0 +1,235 @@
+package worldmap
+
+import (
+	"math/rand"
+	"time"
+
+	"github.com/GooLuck/WorldMap/internal/worldmap/geo"
+)
+
+// NpcState NPC行为状态
+type NpcState int32
+
+const (
+	NpcState_Idle   NpcState = iota // 待机
+	NpcState_Wander                 // 游荡
+	NpcState_Patrol                 // 巡逻
+	NpcState_Chase                  // 追击
+	NpcState_Return                 // 返回
+)
+
+// NpcBehavior NPC行为控制器接口，随地图Tick更新
+type NpcBehavior interface {
+	GetState() NpcState                               // 获取当前状态
+	Update(wm *WorldMap, npc *NpcUnit, now time.Time) // 更新行为
+}
+
+// NpcBehaviorFactory 为指定NPC创建行为控制器
+type NpcBehaviorFactory func(npc *NpcUnit) NpcBehavior
+
+// NpcStateMachineConfig 默认状态机行为配置
+type NpcStateMachineConfig struct {
+	LeashRadius  int32           // 拴绳半径（六边形步数），游荡和追击都不会超出
+	VisionRange  int32           // 索敌视野（六边形步数，0表示不索敌）
+	IdleDuration time.Duration   // 每次待机时长
+	StepInterval time.Duration   // 每移动一格的耗时
+	PatrolPoints []*geo.HexCoord // 巡逻点（为空表示在拴绳范围内游荡）
+}
+
+// NpcStateMachine 默认NPC状态机：待机、游荡/巡逻、追击、返回
+type NpcStateMachine struct {
+	config      *NpcStateMachineConfig
+	state       NpcState
+	home        *geo.HexCoord   // 拴绳中心
+	path        []*geo.HexCoord // 当前移动路径
+	pathIndex   int             // 下一步在路径中的索引
+	nextStep    time.Time       // 下一步移动时间
+	idleUntil   time.Time       // 待机结束时间
+	patrolIndex int             // 当前巡逻目标点索引
+	targetId    int64           // 追击目标id
+}
+
+// NewNpcStateMachineFactory 创建默认状态机的行为工厂
+func NewNpcStateMachineFactory(config *NpcStateMachineConfig) NpcBehaviorFactory {
+	return func(npc *NpcUnit) NpcBehavior {
+		return NewNpcStateMachine(npc, config)
+	}
+}
+
+// NewNpcStateMachine 创建默认状态机，以NPC当前位置为拴绳中心
+func NewNpcStateMachine(npc *NpcUnit, config *NpcStateMachineConfig) *NpcStateMachine {
+	home := npc.GetHexCoord()
+	if home != nil {
+		home = home.Clone()
+	}
+	return &NpcStateMachine{
+		config: config,
+		state:  NpcState_Idle,
+		home:   home,
+	}
+}
+
+// GetState 获取当前状态
+func (sm *NpcStateMachine) GetState() NpcState {
+	return sm.state
+}
+
+// GetTargetId 获取追击目标id
+func (sm *NpcStateMachine) GetTargetId() int64 {
+	return sm.targetId
+}
+
+// Update 更新状态机
+func (sm *NpcStateMachine) Update(wm *WorldMap, npc *NpcUnit, now time.Time) {
+	if sm.home == nil || npc.GetHexCoord() == nil {
+		return
+	}
+
+	// 非追击、非返回状态下索敌
+	if sm.state != NpcState_Chase && sm.state != NpcState_Return {
+		if target := sm.findTarget(wm, npc); target != nil {
+			sm.targetId = target.GetId()
+			sm.changeState(NpcState_Chase, now)
+		}
+	}
+
+	switch sm.state {
+	case NpcState_Idle:
+		sm.updateIdle(wm, npc, now)
+	case NpcState_Wander, NpcState_Patrol:
+		if sm.step(wm, npc, now) {
+			sm.changeState(NpcState_Idle, now)
+		}
+	case NpcState_Chase:
+		sm.updateChase(wm, npc, now)
+	case NpcState_Return:
+		if sm.step(wm, npc, now) {
+			sm.changeState(NpcState_Idle, now)
+		}
+	}
+}
+
+// updateIdle 待机结束后开始巡逻或游荡
+func (sm *NpcStateMachine) updateIdle(wm *WorldMap, npc *NpcUnit, now time.Time) {
+	if now.Before(sm.idleUntil) {
+		return
+	}
+
+	current := npc.GetHexCoord()
+	if len(sm.config.PatrolPoints) > 0 {
+		target := sm.config.PatrolPoints[sm.patrolIndex]
+		sm.patrolIndex = (sm.patrolIndex + 1) % len(sm.config.PatrolPoints)
+		if sm.setPath(wm, current, target, now) {
+			sm.state = NpcState_Patrol
+		}
+		return
+	}
+
+	if sm.config.LeashRadius <= 0 {
+		return
+	}
+	grids := wm.hexGridMgr.GetHexGridsInRadius(sm.home, sm.config.LeashRadius)
+	if len(grids) == 0 {
+		return
+	}
+	target := grids[rand.Intn(len(grids))].GetCoord()
+	if sm.setPath(wm, current, target, now) {
+		sm.state = NpcState_Wander
+	}
+}
+
+// updateChase 追击目标，目标丢失或超出拴绳范围时返回
+func (sm *NpcStateMachine) updateChase(wm *WorldMap, npc *NpcUnit, now time.Time) {
+	target := wm.GetUnit(sm.targetId)
+	current := npc.GetHexCoord()
+	if target == nil || target.GetHexCoord() == nil ||
+		!wm.hexGridMgr.IsVisible(current, target.GetHexCoord(), sm.config.VisionRange) ||
+		sm.home.DistanceTo(target.GetHexCoord()) > sm.config.LeashRadius {
+		sm.targetId = 0
+		sm.changeState(NpcState_Return, now)
+		sm.setPath(wm, current, sm.home, now)
+		return
+	}
+
+	// 已与目标相邻，停止移动
+	targetHex := target.GetHexCoord()
+	if current.DistanceTo(targetHex) <= 1 {
+		return
+	}
+
+	// 目标移动后重新规划路径
+	if len(sm.path) == 0 || !sm.path[len(sm.path)-1].Equal(targetHex) {
+		sm.setPath(wm, current, targetHex, sm.nextStep)
+	}
+	sm.step(wm, npc, now)
+}
+
+// findTarget 在视野内寻找最近的可见敌方部队
+func (sm *NpcStateMachine) findTarget(wm *WorldMap, npc *NpcUnit) Unit {
+	if sm.config.VisionRange <= 0 {
+		return nil
+	}
+
+	current := npc.GetHexCoord()
+	var nearest Unit
+	nearestDist := int32(-1)
+	filter := wm.NewUnitFilter(npc.GetOwner()).
+		WithUnitTypes(MapUnitType_PlayerTroop).
+		WithRelations(Relation_Enemy, Relation_System)
+	for _, unit := range wm.hexGridMgr.GetUnitsInRadius(current, sm.config.VisionRange, filter) {
+		if unit.GetHexCoord() == nil {
+			continue
+		}
+		if sm.home.DistanceTo(unit.GetHexCoord()) > sm.config.LeashRadius {
+			continue
+		}
+		if !wm.hexGridMgr.IsVisible(current, unit.GetHexCoord(), sm.config.VisionRange) {
+			continue
+		}
+		dist := current.DistanceTo(unit.GetHexCoord())
+		if nearestDist < 0 || dist < nearestDist {
+			nearest = unit
+			nearestDist = dist
+		}
+	}
+	return nearest
+}
+
+// setPath 规划移动路径
+func (sm *NpcStateMachine) setPath(wm *WorldMap, from, to *geo.HexCoord, now time.Time) bool {
+	path := wm.hexGridMgr.FindPathWithCost(from, to, wm.terrainCostModel()).GetPath()
+	if len(path) == 0 {
+		sm.path = nil
+		return false
+	}
+	sm.path = path
+	sm.pathIndex = 1
+	if sm.nextStep.Before(now) {
+		sm.nextStep = now.Add(sm.config.StepInterval)
+	}
+	return true
+}
+
+// step 按移动间隔沿路径前进，返回是否已到达终点
+func (sm *NpcStateMachine) step(wm *WorldMap, npc *NpcUnit, now time.Time) bool {
+	for sm.pathIndex < len(sm.path) && !now.Before(sm.nextStep) {
+		wm.MoveUnitToHex(npc, sm.path[sm.pathIndex])
+		sm.pathIndex++
+		sm.nextStep = sm.nextStep.Add(sm.config.StepInterval)
+		if sm.config.StepInterval <= 0 {
+			sm.nextStep = now
+			break
+		}
+	}
+	return sm.pathIndex >= len(sm.path)
+}
+
+// changeState 切换状态
+func (sm *NpcStateMachine) changeState(state NpcState, now time.Time) {
+	sm.state = state
+	sm.path = nil
+	sm.pathIndex = 0
+	if state == NpcState_Idle {
+		sm.idleUntil = now.Add(sm.config.IdleDuration)
+	}
+}
@@ -0,0 +1,285 @@
+package worldmap
+
+import (
+	"math"
+	"time"
+
+	"github.com/GooLuck/WorldMap/internal/worldmap/config"
+	"github.com/GooLuck/WorldMap/internal/worldmap/geo"
+)
+
+// 单个NPC随机落点的最大尝试次数
+const maxNpcSpawnAttempts = 10
+
+// npcGridState 单个grid内由刷新管理器维护的NPC
+type npcGridState struct {
+	lastActiveTime time.Time            // 最后一次处于玩家视野附近的时间
+	bands          []map[int64]*NpcUnit // 等级段索引 -> NPC
+}
+
+// NpcManager NPC管理器
+// 在在线玩家视野附近按等级段维持NPC密度，无人区域超时后回收NPC
+type NpcManager struct {
+	worldMap     *WorldMap
+	spawnConfig  *config.NpcSpawnConfig
+	grids        map[int32]*npcGridState // grid索引 -> 状态
+	npcGrid      map[int64]int32         // NPC id -> grid索引
+	lastTickTime time.Time
+
+	behaviorFactories map[int32]NpcBehaviorFactory // NPC配置ID -> 行为工厂
+	behaviors         map[int64]NpcBehavior        // NPC id -> 行为控制器
+}
+
+// NewNpcManager 创建NPC管理器
+func NewNpcManager(worldMap *WorldMap, spawnConfig *config.NpcSpawnConfig) *NpcManager {
+	return &NpcManager{
+		worldMap:    worldMap,
+		spawnConfig: spawnConfig,
+		grids:       make(map[int32]*npcGridState),
+		npcGrid:     make(map[int64]int32),
+
+		behaviorFactories: make(map[int32]NpcBehaviorFactory),
+		behaviors:         make(map[int64]NpcBehavior),
+	}
+}
+
+// RegisterBehavior 为指定NPC配置ID注册行为，之后创建的该类NPC都会挂载行为控制器
+func (nm *NpcManager) RegisterBehavior(confId int32, factory NpcBehaviorFactory) {
+	nm.behaviorFactories[confId] = factory
+}
+
+// GetBehavior 获取NPC的行为控制器
+func (nm *NpcManager) GetBehavior(npcId int64) NpcBehavior {
+	return nm.behaviors[npcId]
+}
+
+// attachBehavior NPC创建后挂载已注册的行为
+func (nm *NpcManager) attachBehavior(npc *NpcUnit) {
+	if factory, exists := nm.behaviorFactories[npc.GetConfigId()]; exists {
+		nm.behaviors[npc.GetId()] = factory(npc)
+	}
+}
+
+// updateBehaviors 更新所有NPC行为，已移除或死亡的NPC卸载行为
+func (nm *NpcManager) updateBehaviors(now time.Time) {
+	for npcId, behavior := range nm.behaviors {
+		npc, ok := nm.worldMap.GetUnit(npcId).(*NpcUnit)
+		if !ok || npc.IsDead() {
+			delete(nm.behaviors, npcId)
+			continue
+		}
+		behavior.Update(nm.worldMap, npc, now)
+	}
+}
+
+// Update 更新NPC行为，并按刷新间隔刷新和回收NPC
+func (nm *NpcManager) Update(now time.Time) {
+	nm.updateBehaviors(now)
+
+	if len(nm.spawnConfig.LevelBands) == 0 {
+		return
+	}
+	interval := time.Duration(nm.spawnConfig.SpawnTickInterval) * time.Second
+	if !nm.lastTickTime.IsZero() && now.Sub(nm.lastTickTime) < interval {
+		return
+	}
+	nm.lastTickTime = now
+
+	nm.cleanupRemovedNpcs()
+	activeGrids := nm.markActiveGrids(now)
+	nm.despawnInactiveGrids(now)
+	nm.spawnInActiveGrids(activeGrids)
+}
+
+// markActiveGrids 标记在线玩家视野附近的grid，返回本次活跃的grid索引
+func (nm *NpcManager) markActiveGrids(now time.Time) []int32 {
+	gridMgr := nm.worldMap.gridMgr
+	activeGrids := make([]int32, 0)
+	visited := make(map[int32]bool)
+
+	nm.worldMap.observerMgr.RangeObservers(func(observer *Observer) bool {
+		if observer.ViewWindow == nil {
+			return true
+		}
+		minX, maxX, minY, maxY := RectToGrid(gridMgr.mapSize, observer.ViewWindow)
+		minX = int32(math.Max(float64(minX-nm.spawnConfig.ActiveRange), 0))
+		minY = int32(math.Max(float64(minY-nm.spawnConfig.ActiveRange), 0))
+		maxX = int32(math.Min(float64(maxX+nm.spawnConfig.ActiveRange), float64(gridMgr.gridCols-1)))
+		maxY = int32(math.Min(float64(maxY+nm.spawnConfig.ActiveRange), float64(gridMgr.gridRows-1)))
+
+		for y := minY; y <= maxY; y++ {
+			for x := minX; x <= maxX; x++ {
+				index := y*gridMgr.gridCols + x
+				if visited[index] {
+					continue
+				}
+				visited[index] = true
+				nm.getGridState(index).lastActiveTime = now
+				activeGrids = append(activeGrids, index)
+			}
+		}
+		return true
+	})
+	return activeGrids
+}
+
+// despawnInactiveGrids 回收长时间无人区域的NPC
+func (nm *NpcManager) despawnInactiveGrids(now time.Time) {
+	timeout := time.Duration(nm.spawnConfig.DespawnTimeout) * time.Second
+	for index, state := range nm.grids {
+		if now.Sub(state.lastActiveTime) <= timeout {
+			continue
+		}
+		for _, npcs := range state.bands {
+			for _, npc := range npcs {
+				nm.worldMap.RemoveUnit(npc)
+				delete(nm.npcGrid, npc.GetId())
+			}
+		}
+		delete(nm.grids, index)
+	}
+}
+
+// spawnInActiveGrids 补足活跃grid内各等级段的NPC数量，受每Tick刷新上限限制
+func (nm *NpcManager) spawnInActiveGrids(activeGrids []int32) {
+	budget := nm.spawnConfig.MaxSpawnPerTick
+	for _, index := range activeGrids {
+		state := nm.getGridState(index)
+		for bandIndex, band := range nm.spawnConfig.LevelBands {
+			needed := band.CountPerGrid - int32(len(state.bands[bandIndex]))
+			for i := int32(0); i < needed; i++ {
+				if budget <= 0 {
+					return
+				}
+				budget--
+				if npc := nm.spawnInGrid(index, &band); npc != nil {
+					state.bands[bandIndex][npc.GetId()] = npc
+					nm.npcGrid[npc.GetId()] = index
+				}
+			}
+		}
+	}
+}
+
+// spawnInGrid 在grid内随机位置刷新一个NPC
+func (nm *NpcManager) spawnInGrid(index int32, band *config.NpcLevelBand) *NpcUnit {
+	gridMgr := nm.worldMap.gridMgr
+	rect := geo.NewRectangle(
+		(index%gridMgr.gridCols)*gridMgr.mapSize.GridWidth,
+		(index/gridMgr.gridCols)*gridMgr.mapSize.GridHeight,
+		gridMgr.mapSize.GridWidth,
+		gridMgr.mapSize.GridHeight,
+	)
+
+	for attempt := 0; attempt < maxNpcSpawnAttempts; attempt++ {
+		coord := rect.RandomCoord()
+		if !nm.worldMap.isCoordInMap(coord) {
+			continue
+		}
+		level := nm.calcLevel(coord, band)
+		if npc, ok := nm.worldMap.NewNpcTroop(nm.spawnConfig.NpcID, level, coord).(*NpcUnit); ok {
+			return npc
+		}
+	}
+	return nil
+}
+
+// calcLevel 按离地图中心的距离计算等级，越靠近中心等级越高
+func (nm *NpcManager) calcLevel(coord *geo.Coord, band *config.NpcLevelBand) int32 {
+	center := nm.worldMap.GetMapCenter()
+	maxDist := math.Hypot(float64(center.X), float64(center.Y))
+	if maxDist <= 0 {
+		return band.MaxLevel
+	}
+	ratio := math.Hypot(float64(coord.X-center.X), float64(coord.Y-center.Y)) / maxDist
+	if ratio > 1 {
+		ratio = 1
+	}
+	return band.MaxLevel - int32(math.Round(ratio*float64(band.MaxLevel-band.MinLevel)))
+}
+
+// cleanupRemovedNpcs 清理已被击杀或从地图移除的NPC
+func (nm *NpcManager) cleanupRemovedNpcs() {
+	for npcId, index := range nm.npcGrid {
+		npc, ok := nm.worldMap.GetUnit(npcId).(*NpcUnit)
+		if ok && !npc.IsDead() {
+			continue
+		}
+		if ok {
+			nm.worldMap.RemoveUnit(npc)
+		}
+		nm.forgetNpc(npcId, index)
+	}
+}
+
+// OnNpcRemoved NPC被外部移除时调用，下个Tick会补充
+func (nm *NpcManager) OnNpcRemoved(npcId int64) {
+	if index, exists := nm.npcGrid[npcId]; exists {
+		nm.forgetNpc(npcId, index)
+	}
+}
+
+// OnNpcMoved NPC移动后按所在grid重新计数，离开的grid下个Tick会补充
+func (nm *NpcManager) OnNpcMoved(npc Unit) {
+	oldIndex, exists := nm.npcGrid[npc.GetId()]
+	if !exists {
+		return
+	}
+	newIndex, ok := nm.worldMap.gridMgr.calcGridIndex(npc.GetCoord().X, npc.GetCoord().Y)
+	if !ok || newIndex == oldIndex {
+		return
+	}
+
+	oldState := nm.getGridState(oldIndex)
+	_, newExists := nm.grids[newIndex]
+	newState := nm.getGridState(newIndex)
+	if !newExists {
+		// 新进入的grid沿用原grid的活跃时间，不会因为从未活跃而被立即回收
+		newState.lastActiveTime = oldState.lastActiveTime
+	}
+	for bandIndex, npcs := range oldState.bands {
+		if unit, exists := npcs[npc.GetId()]; exists {
+			delete(npcs, npc.GetId())
+			newState.bands[bandIndex][npc.GetId()] = unit
+			break
+		}
+	}
+	nm.npcGrid[npc.GetId()] = newIndex
+}
+
+func (nm *NpcManager) forgetNpc(npcId int64, index int32) {
+	delete(nm.npcGrid, npcId)
+	if state, exists := nm.grids[index]; exists {
+		for _, npcs := range state.bands {
+			delete(npcs, npcId)
+		}
+	}
+}
+
+func (nm *NpcManager) getGridState(index int32) *npcGridState {
+	state, exists := nm.grids[index]
+	if !exists {
+		state = &npcGridState{
+			bands: make([]map[int64]*NpcUnit, len(nm.spawnConfig.LevelBands)),
+		}
+		for i := range state.bands {
+			state.bands[i] = make(map[int64]*NpcUnit)
+		}
+		nm.grids[index] = state
+	}
+	return state
+}
+
+// GetNpcCount 获取刷新管理器维护的NPC数量
+func (nm *NpcManager) GetNpcCount() int32 {
+	return int32(len(nm.npcGrid))
+}
+
+// GetGridNpcCount 获取指定grid内指定等级段的NPC数量
+func (nm *NpcManager) GetGridNpcCount(index int32, bandIndex int) int32 {
+	state, exists := nm.grids[index]
+	if !exists || bandIndex < 0 || bandIndex >= len(state.bands) {
+		return 0
+	}
+	return int32(len(state.bands[bandIndex]))
+}
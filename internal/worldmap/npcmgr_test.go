@@ -0,0 +1,188 @@
+package worldmap
+
+import (
+	"testing"
+	"time"
+
+	"github.com/GooLuck/WorldMap/internal/worldmap/config"
+	"github.com/GooLuck/WorldMap/internal/worldmap/geo"
+)
+
+// TestNpcManagerSpawnAndDespawn 测试NPC在玩家视野附近刷新并在无人后回收
+func TestNpcManagerSpawnAndDespawn(t *testing.T) {
+	mapConfig := newTestMapConfig()
+	mapConfig.Npcs = []config.NpcConfig{
+		{NpcID: 1, MinLevel: 1, MaxLevel: 30, BaseHp: 100, BasePower: 10},
+	}
+	mapConfig.NpcSpawn = config.NpcSpawnConfig{
+		NpcID: 1,
+		LevelBands: []config.NpcLevelBand{
+			{MinLevel: 1, MaxLevel: 10, CountPerGrid: 2},
+			{MinLevel: 11, MaxLevel: 20, CountPerGrid: 1},
+		},
+		ActiveRange:       0,
+		DespawnTimeout:    60,
+		MaxSpawnPerTick:   5,
+		SpawnTickInterval: 1,
+	}
+	wm := NewWorldMap(mapConfig)
+	npcMgr := wm.GetNpcManager()
+
+	// 视野覆盖 2x2 个grid，共需要 12 个NPC
+	wm.GetObserverManager().AddObserver(1, geo.NewRectangle(0, 0, 200, 200), 0)
+
+	now := time.Now()
+	npcMgr.Update(now)
+	if npcMgr.GetNpcCount() != 5 {
+		t.Errorf("每Tick刷新数量应该受限：期望 5, 得到 %d", npcMgr.GetNpcCount())
+	}
+
+	for i := 1; i <= 3; i++ {
+		npcMgr.Update(now.Add(time.Duration(i) * time.Second))
+	}
+	if npcMgr.GetNpcCount() != 12 {
+		t.Errorf("NPC数量错误：期望 12, 得到 %d", npcMgr.GetNpcCount())
+	}
+	if npcMgr.GetGridNpcCount(0, 0) != 2 || npcMgr.GetGridNpcCount(0, 1) != 1 {
+		t.Error("每个grid内各等级段数量应该达到目标值")
+	}
+	for _, unit := range wm.GetUnitManager().GetUnitByType(MapUnitType_Npc) {
+		npc := unit.(*NpcUnit)
+		if npc.GetLevel() < 1 || npc.GetLevel() > 20 {
+			t.Errorf("NPC等级超出等级段范围：%d", npc.GetLevel())
+		}
+	}
+
+	// 玩家离开后超时回收
+	wm.GetObserverManager().RemoveObserver(1)
+	npcMgr.Update(now.Add(30 * time.Second))
+	if npcMgr.GetNpcCount() != 12 {
+		t.Error("未超时前不应该回收NPC")
+	}
+	npcMgr.Update(now.Add(2 * time.Minute))
+	if npcMgr.GetNpcCount() != 0 || len(wm.GetUnitManager().GetUnitByType(MapUnitType_Npc)) != 0 {
+		t.Error("超时后应该回收所有NPC")
+	}
+}
+
+// TestNpcManagerNpcMoved 测试NPC移动到其他grid后按新grid计数
+func TestNpcManagerNpcMoved(t *testing.T) {
+	mapConfig := newTestMapConfig()
+	mapConfig.Npcs = []config.NpcConfig{{NpcID: 1, MinLevel: 1, MaxLevel: 10, BaseHp: 100}}
+	mapConfig.NpcSpawn = config.NpcSpawnConfig{
+		NpcID:             1,
+		LevelBands:        []config.NpcLevelBand{{MinLevel: 1, MaxLevel: 10, CountPerGrid: 2}},
+		DespawnTimeout:    60,
+		MaxSpawnPerTick:   10,
+		SpawnTickInterval: 1,
+	}
+	wm := NewWorldMap(mapConfig)
+	npcMgr := wm.GetNpcManager()
+	wm.GetObserverManager().AddObserver(1, geo.NewRectangle(0, 0, 100, 100), 0)
+
+	now := time.Now()
+	npcMgr.Update(now)
+	if npcMgr.GetGridNpcCount(0, 0) != 2 {
+		t.Fatalf("grid内NPC数量错误：期望 2, 得到 %d", npcMgr.GetGridNpcCount(0, 0))
+	}
+
+	// 移动到视野外的grid，原grid补充新的NPC
+	var npc Unit
+	for _, unit := range wm.GetUnitManager().GetUnitByType(MapUnitType_Npc) {
+		npc = unit
+		break
+	}
+	wm.MoveUnit(npc, geo.NewCoord(550, 50))
+	if npcMgr.GetGridNpcCount(0, 0) != 1 || npcMgr.GetGridNpcCount(5, 0) != 1 {
+		t.Errorf("移动后计数错误：原grid %d, 新grid %d", npcMgr.GetGridNpcCount(0, 0), npcMgr.GetGridNpcCount(5, 0))
+	}
+	npcMgr.Update(now.Add(time.Second))
+	if npcMgr.GetGridNpcCount(0, 0) != 2 || npcMgr.GetNpcCount() != 3 {
+		t.Errorf("原grid应该补充NPC：得到 %d, 总数 %d", npcMgr.GetGridNpcCount(0, 0), npcMgr.GetNpcCount())
+	}
+
+	// 离开视野的NPC随所在grid超时回收
+	npcMgr.Update(now.Add(2 * time.Minute))
+	if npcMgr.GetGridNpcCount(5, 0) != 0 || wm.GetUnit(npc.GetId()) != nil {
+		t.Error("移动到无人grid的NPC应该超时回收")
+	}
+}
+
+// TestNpcManagerCalcLevel 测试等级按离地图中心的距离缩放
+func TestNpcManagerCalcLevel(t *testing.T) {
+	wm := NewWorldMap(newTestMapConfig())
+	band := &config.NpcLevelBand{MinLevel: 1, MaxLevel: 10}
+
+	if level := wm.GetNpcManager().calcLevel(wm.GetMapCenter(), band); level != 10 {
+		t.Errorf("地图中心等级错误：期望 10, 得到 %d", level)
+	}
+	if level := wm.GetNpcManager().calcLevel(geo.NewCoord(0, 0), band); level != 1 {
+		t.Errorf("地图角落等级错误：期望 1, 得到 %d", level)
+	}
+}
+
+// TestNpcBehaviorWanderAndChase 测试NPC游荡不超出拴绳范围，发现敌方部队后追击并在目标离开后返回
+func TestNpcBehaviorWanderAndChase(t *testing.T) {
+	mapConfig := newTestMapConfig()
+	mapConfig.Npcs = []config.NpcConfig{
+		{NpcID: 1, MinLevel: 1, MaxLevel: 10, BaseHp: 100},
+	}
+	wm := NewWorldMap(mapConfig)
+	wm.GetNpcManager().RegisterBehavior(1, NewNpcStateMachineFactory(&NpcStateMachineConfig{
+		LeashRadius:  2,
+		VisionRange:  3,
+		IdleDuration: time.Hour,
+		StepInterval: time.Second,
+	}))
+
+	home := geo.NewHexCoord(3, 3)
+	unit := wm.NewNpcTroop(1, 1, wm.hexToCoord(home))
+	if unit == nil {
+		t.Fatal("创建NPC失败")
+	}
+	npc := unit.(*NpcUnit)
+	behavior := wm.GetNpcManager().GetBehavior(npc.GetId())
+	if behavior == nil {
+		t.Fatal("注册了行为的NPC应该挂载行为控制器")
+	}
+
+	now := time.Now()
+	for i := 0; i < 30; i++ {
+		now = now.Add(time.Second)
+		wm.Update(now)
+		if home.DistanceTo(npc.GetHexCoord()) > 2 {
+			t.Fatalf("游荡超出拴绳范围：%s", npc.GetHexCoord())
+		}
+	}
+
+	// 视野内出现敌方部队
+	troopHex := geo.NewHexCoord(4, 3)
+	troop := &TestUnit{
+		id:       GetIDGenerator().GenerateNewID(),
+		coord:    *wm.hexToCoord(troopHex),
+		hexCoord: troopHex,
+		unitType: MapUnitType_PlayerTroop,
+		owner:    NewPlayerOwner(1),
+	}
+	wm.AddUnit(troop)
+	now = now.Add(time.Second)
+	wm.Update(now)
+	if behavior.GetState() != NpcState_Chase {
+		t.Fatalf("发现敌方部队后应该追击：当前状态 %d", behavior.GetState())
+	}
+
+	// 目标离开拴绳范围后返回
+	wm.MoveUnitToHex(troop, geo.NewHexCoord(0, 5))
+	now = now.Add(time.Second)
+	wm.Update(now)
+	if behavior.GetState() != NpcState_Return {
+		t.Fatalf("目标离开拴绳范围后应该返回：当前状态 %d", behavior.GetState())
+	}
+	for i := 0; i < 5; i++ {
+		now = now.Add(time.Second)
+		wm.Update(now)
+	}
+	if behavior.GetState() != NpcState_Idle || !npc.GetHexCoord().Equal(home) {
+		t.Errorf("返回后应该回到拴绳中心：期望 %s, 得到 %s", home, npc.GetHexCoord())
+	}
+}
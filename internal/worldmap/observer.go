@@ -4,9 +4,14 @@ import "github.com/GooLuck/WorldMap/internal/worldmap/geo"
 
 // 观察者
 type Observer struct {
-	Id         int64          // 观察者id
-	ViewWindow *geo.Rectangle // 观察窗口
-	Lod        int32          // 观察等级
+	Id         int64           // 观察者id
+	ViewWindow *geo.Rectangle  // 观察窗口
+	Lod        int32           // 观察等级
+	lodRules   *LodRules       // 观察等级规则
+	fog        *PlayerFog      // 战争迷雾（未启用时为nil）
+	views      []*ObserverView // 订阅的view
+	known      map[int64]Unit  // 已推送给客户端的单位
+	events     []*AoiEvent     // 待推送的AOI事件
 }
 
 func NewObserver(id int64, viewWindow *geo.Rectangle, lod int32) *Observer {
@@ -14,6 +19,7 @@ func NewObserver(id int64, viewWindow *geo.Rectangle, lod int32) *Observer {
 		Id:         id,
 		ViewWindow: viewWindow,
 		Lod:        lod,
+		known:      make(map[int64]Unit),
 	}
 }
 
@@ -21,9 +27,31 @@ func (o *Observer) ChangeLod(lod int32) {
 	o.Lod = lod
 }
 
-// 指定实体对玩家是否可见
+// 单位是否在观察者的AOI内（已推送进入事件且未离开）
+func (o *Observer) IsWatching(unitId int64) bool {
+	_, exists := o.known[unitId]
+	return exists
+}
+
+// 取出所有待推送的AOI事件
+func (o *Observer) PopEvents() []*AoiEvent {
+	events := o.events
+	o.events = nil
+	return events
+}
+
+// 获取战争迷雾
+func (o *Observer) GetFog() *PlayerFog {
+	return o.fog
+}
+
+// 指定实体对玩家是否可见：必须满足当前观察等级的规则，启用战争迷雾时单位所在六边形必须在玩家视野内
 func (o *Observer) IsVisible(unit Unit) bool {
-	// observerOwner := NewPlayerOwner(o.Id)
-	// relation := GetOwnerRelation(observerOwner, unit.GetOwner())
-	return true
+	if !o.lodRules.GetRule(o.Lod).IsVisible(unit) {
+		return false
+	}
+	if o.fog == nil {
+		return true
+	}
+	return o.fog.IsUnitVisible(unit)
 }
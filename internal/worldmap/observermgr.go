@@ -1,20 +1,51 @@
 package worldmap
 
-import "github.com/GooLuck/WorldMap/internal/worldmap/geo"
+import (
+	"slices"
+
+	"github.com/GooLuck/WorldMap/internal/worldmap/geo"
+)
+
+// 行军轨迹变化通知
+type MarchChangeNotifier func(observer *Observer, troop *TroopUnit)
+
+// 关系变化通知
+type RelationChangeNotifier func(observer *Observer, change *RelationChange)
 
 // 观察者管理器
 
 type ObserverManager struct {
-	worldMap  *WorldMap              // 所属地图指针
-	observers map[int64]*Observer    // 观察者
-	views     [][]*ObserverView      // 视野管理
-	marching  map[int64][]*geo.Coord // 所有的行军
+	worldMap          *WorldMap                // 所属地图指针
+	observers         map[int64]*Observer      // 观察者
+	views             [][]*ObserverView        // 视野管理
+	marching          map[int64][]*geo.Coord   // 所有的行军
+	marchNotifiers    []MarchChangeNotifier    // 行军轨迹变化通知
+	relationNotifiers []RelationChangeNotifier // 关系变化通知
+	lodRules          *LodRules                // 观察等级规则
 }
 
 func NewObserverManager(worldMap *WorldMap) *ObserverManager {
-	return &ObserverManager{
+	om := &ObserverManager{
 		worldMap:  worldMap,
 		observers: make(map[int64]*Observer),
+		marching:  make(map[int64][]*geo.Coord),
+		lodRules:  NewLodRules(worldMap.GetConfig().LodRules),
+	}
+	om.initViews()
+	return om
+}
+
+// 按grid大小划分view
+func (om *ObserverManager) initViews() {
+	mapSize := om.worldMap.GetMapSize()
+	maxX, maxY := om.GetMaxViewSize()
+	om.views = make([][]*ObserverView, maxX)
+	for x := int32(0); x < maxX; x++ {
+		om.views[x] = make([]*ObserverView, maxY)
+		for y := int32(0); y < maxY; y++ {
+			coord := geo.NewCoord(x*mapSize.GridWidth, y*mapSize.GridHeight)
+			om.views[x][y] = NewObserverView(coord, mapSize.GridWidth, mapSize.GridHeight)
+		}
 	}
 }
 
@@ -23,12 +54,38 @@ func (om *ObserverManager) GetObserver(id int64) *Observer {
 }
 
 func (om *ObserverManager) AddObserver(playerId int64, viewWindow *geo.Rectangle, lod int32) *Observer {
+	om.RemoveObserver(playerId)
+
 	observer := NewObserver(playerId, viewWindow, lod)
+	observer.lodRules = om.lodRules
+	if fogMgr := om.worldMap.fogMgr; fogMgr != nil && fogMgr.IsEnabled() {
+		observer.fog = fogMgr.AddPlayer(playerId)
+	}
 	om.observers[observer.Id] = observer
+	om.subscribe(observer)
+	om.refreshObserver(observer)
 
 	return observer
 }
 
+func (om *ObserverManager) RemoveObserver(playerId int64) {
+	observer, exists := om.observers[playerId]
+	if !exists {
+		return
+	}
+	om.unsubscribe(observer)
+	delete(om.observers, playerId)
+}
+
+// 遍历所有观察者
+func (om *ObserverManager) RangeObservers(f func(observer *Observer) bool) {
+	for _, observer := range om.observers {
+		if !f(observer) {
+			break
+		}
+	}
+}
+
 func (om *ObserverManager) GetObserverViewByIndex(x, y int32) (*ObserverView, bool) {
 	maxX, maxY := om.GetMaxViewSize()
 	if x < 0 || x >= maxX || y < 0 || y >= maxY {
@@ -39,8 +96,8 @@ func (om *ObserverManager) GetObserverViewByIndex(x, y int32) (*ObserverView, bo
 
 // 地图划分后的最大单边视口数量
 func (om *ObserverManager) GetMaxViewSize() (int32, int32) {
-	mapConfig := om.worldMap.GetConfig()
-	return (mapConfig.MapSize.Width - 1) / mapConfig.MapSize.GridWidth, (mapConfig.MapSize.Height - 1) / mapConfig.MapSize.GridHeight
+	maxX, maxY := MaxGridXY(om.worldMap.GetMapSize())
+	return maxX + 1, maxY + 1
 }
 
 // 坐标到视野索引
@@ -49,6 +106,65 @@ func (om *ObserverManager) CoordToViewIndex(coord *geo.Coord) (int32, int32) {
 	return coord.X / mapConfig.MapSize.GridWidth, coord.Y / mapConfig.MapSize.GridHeight
 }
 
+// 添加行军，记录行军路径经过的坐标
+func (om *ObserverManager) AddMarching(troop *TroopUnit) {
+	path := troop.GetPath()
+	coords := make([]*geo.Coord, 0, len(path))
+	for _, hex := range path {
+		coords = append(coords, om.worldMap.hexToCoord(hex))
+	}
+	om.marching[troop.GetId()] = coords
+}
+
+// 注册关系变化通知
+func (om *ObserverManager) AddRelationChangeNotifier(notifier RelationChangeNotifier) {
+	om.relationNotifiers = append(om.relationNotifiers, notifier)
+}
+
+// 关系变化时重新计算受影响观察者的AOI并通知：成员变化的玩家本人，以及受影响联盟的成员（默认立场变化影响所有观察者）
+func (om *ObserverManager) OnRelationChanged(change *RelationChange) {
+	for _, observer := range om.observers {
+		alliance := om.worldMap.relations.GetPlayerAlliance(observer.Id)
+		if !change.IsDefaultStance() && observer.Id != change.PlayerId && (alliance == 0 || !slices.Contains(change.AllianceIds, alliance)) {
+			continue
+		}
+		om.refreshObserver(observer)
+		for _, notifier := range om.relationNotifiers {
+			notifier(observer, change)
+		}
+	}
+}
+
+// 注册行军轨迹变化通知
+func (om *ObserverManager) AddMarchChangeNotifier(notifier MarchChangeNotifier) {
+	om.marchNotifiers = append(om.marchNotifiers, notifier)
+}
+
+// 更新行军路径，通知旧路径或新路径经过其观察窗口的观察者
+func (om *ObserverManager) UpdateMarching(troop *TroopUnit) {
+	oldCoords := om.marching[troop.GetId()]
+	om.AddMarching(troop)
+	newCoords := om.marching[troop.GetId()]
+
+	for _, observer := range om.observers {
+		if observer.ViewWindow == nil || !observer.IsVisible(troop) {
+			continue
+		}
+		if !isPathCrossRect(oldCoords, observer.ViewWindow) && !isPathCrossRect(newCoords, observer.ViewWindow) {
+			continue
+		}
+		for _, notifier := range om.marchNotifiers {
+			notifier(observer, troop)
+		}
+	}
+}
+
+// 移除行军
+func (om *ObserverManager) RemoveMarching(unitId int64) {
+	delete(om.marching, unitId)
+}
+
+// 获取行军路线经过矩形区域的行军单位
 func (om *ObserverManager) GetMarchingUnits(playerId int64, rect *geo.Rectangle) map[int64]Unit {
 	retUnits := make(map[int64]Unit)
 	observer := om.GetObserver(playerId)
@@ -56,27 +172,42 @@ func (om *ObserverManager) GetMarchingUnits(playerId int64, rect *geo.Rectangle)
 		return retUnits
 	}
 
+	for unitId, coords := range om.marching {
+		if !isPathCrossRect(coords, rect) {
+			continue
+		}
+		unit := om.worldMap.GetUnit(unitId)
+		if unit != nil && observer.IsVisible(unit) {
+			retUnits[unitId] = unit
+		}
+	}
 	return retUnits
 }
 
-// 获取矩形区域覆盖的view
-func (om *ObserverManager) GetCoverViews(rect *geo.Rectangle) []*ObserverView {
-	retViews := make([]*ObserverView, 0)
-
-	mapSize := om.worldMap.GetMapSize()
-	if rect.X == 0 && rect.Y == 0 && rect.Width == mapSize.Width && rect.Height == mapSize.Height {
-		// 最大的区域
-		for i := range om.views {
-			retViews = append(retViews, om.views[i]...)
+// 行军路线是否经过矩形区域（按每段线段的包围盒判断）
+func isPathCrossRect(coords []*geo.Coord, rect *geo.Rectangle) bool {
+	for i, coord := range coords {
+		if rect.IsCoordInRect(coord) {
+			return true
+		}
+		if i == 0 {
+			continue
+		}
+		prev := coords[i-1]
+		minX, maxX := min(prev.X, coord.X), max(prev.X, coord.X)
+		minY, maxY := min(prev.Y, coord.Y), max(prev.Y, coord.Y)
+		if rect.Intersects(geo.NewRectangle(minX, minY, maxX-minX+1, maxY-minY+1)) {
+			return true
 		}
-		return retViews
 	}
+	return false
+}
 
-	leftX := rect.X / mapSize.GridWidth
-	rightX := (rect.X + rect.Width) / mapSize.GridWidth
-	leftY := rect.Y / mapSize.GridHeight
-	rightY := (rect.Y + rect.Height) / mapSize.GridHeight
+// 获取矩形区域覆盖的view
+func (om *ObserverManager) GetCoverViews(rect *geo.Rectangle) []*ObserverView {
+	retViews := make([]*ObserverView, 0)
 
+	leftX, rightX, leftY, rightY := RectToGrid(om.worldMap.GetMapSize(), rect)
 	for x := leftX; x <= rightX; x++ {
 		for y := leftY; y <= rightY; y++ {
 			view, ok := om.GetObserverViewByIndex(x, y)
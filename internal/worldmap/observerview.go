@@ -33,3 +33,17 @@ func (ov *ObserverView) AddMarching(unit Unit) {
 func (ov *ObserverView) RemoveMarching(unit Unit) {
 	ov.marching.Delete(unit)
 }
+
+// 是否被观察者订阅
+func (ov *ObserverView) HasObserver(playerId int64) bool {
+	return ov.observers[playerId]
+}
+
+// 获取订阅该view的观察者id
+func (ov *ObserverView) GetObservers() []int64 {
+	ids := make([]int64, 0, len(ov.observers))
+	for playerId := range ov.observers {
+		ids = append(ids, playerId)
+	}
+	return ids
+}
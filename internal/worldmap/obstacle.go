@@ -9,18 +9,19 @@ import (
 
 // ObstacleUnit 障碍物单位实现
 type ObstacleUnit struct {
-	id       int64
-	configId int32
-	coord    geo.Coord
-	hexCoord *geo.HexCoord
-	width    int32
-	height   int32
-	owner    *Owner
-	config   *config.ObstacleConfig
-	rect     geo.Rectangle
-}
-
-// NewObstacleUnit 创建新的障碍物单位
+	id        int64
+	configId  int32
+	coord     geo.Coord
+	hexCoord  *geo.HexCoord
+	width     int32
+	height    int32
+	owner     *Owner
+	config    *config.ObstacleConfig
+	rect      geo.Rectangle
+	footprint *Footprint // 占地范围（障碍物矩形）
+}
+
+// NewObstacleUnit 创建新的障碍物单位，六边形坐标由障碍物管理器按地图坐标系换算
 func NewObstacleUnit(id int64, configId int32, coord geo.Coord, width, height int32, config *config.ObstacleConfig) *ObstacleUnit {
 	rect := geo.Rectangle{
 		Coord:  coord,
@@ -28,19 +29,16 @@ func NewObstacleUnit(id int64, configId int32, coord geo.Coord, width, height in
 		Height: height,
 	}
 
-	// 根据矩形坐标计算六边形坐标（假设原点在左上角）
-	hexCoord := geo.NewHexCoord(coord.X, coord.Y)
-
 	return &ObstacleUnit{
-		id:       id,
-		configId: configId,
-		coord:    coord,
-		hexCoord: hexCoord,
-		width:    width,
-		height:   height,
-		owner:    NewOwner(0, OwnerType_System), // 系统所有
-		config:   config,
-		rect:     rect,
+		id:        id,
+		configId:  configId,
+		coord:     coord,
+		width:     width,
+		height:    height,
+		owner:     NewOwner(0, OwnerType_System), // 系统所有
+		config:    config,
+		rect:      rect,
+		footprint: NewRectFootprint(width, height),
 	}
 }
 
@@ -101,6 +99,11 @@ func (o *ObstacleUnit) GetHeight() int32 {
 	return o.height
 }
 
+// GetFootprint 获取占地范围，障碍物注册到矩形覆盖的所有网格
+func (o *ObstacleUnit) GetFootprint() *Footprint {
+	return o.footprint
+}
+
 // GetRect 获取矩形区域
 func (o *ObstacleUnit) GetRect() *geo.Rectangle {
 	return &o.rect
@@ -118,7 +121,12 @@ func (o *ObstacleUnit) CanBuildAt(x, y int32, buildingRadius int32) bool {
 
 	// 如果配置了阻挡半径，使用阻挡半径，否则使用障碍物本身区域
 	if blockRadius > 0 {
-		return distance > blockRadius
+		return distance > blockRadius+buildingRadius
+	}
+
+	// 建筑自身有占地半径时，占地范围不能与障碍物重叠
+	if buildingRadius > 0 {
+		return distance > buildingRadius
 	}
 
 	// 检查是否在障碍物矩形区域内
@@ -174,28 +182,28 @@ func (o *ObstacleUnit) GetEffectStrength() float32 {
 
 // calculateDistanceToPoint 计算点到障碍物边缘的最短距离
 func (o *ObstacleUnit) calculateDistanceToPoint(x, y int32) int32 {
-	// 计算点到矩形边缘的最短距离
-	rect := o.rect
+	// 计算点到占地矩形边缘的最短距离
+	rect := o.footprint.GetRect(&o.coord)
 
 	// 如果点在矩形内，距离为0
 	if o.isPointInRect(x, y) {
 		return 0
 	}
 
-	// 计算到四条边的最短距离
-	dx := max(rect.Coord.X-x, x-(rect.Coord.X+rect.Width), 0)
-	dy := max(rect.Coord.Y-y, y-(rect.Coord.Y+rect.Height), 0)
+	// 计算到四条边的最短距离（矩形左闭右开，最右一列为 X+Width-1）
+	dx := max(rect.X-x, x-(rect.X+rect.Width-1), 0)
+	dy := max(rect.Y-y, y-(rect.Y+rect.Height-1), 0)
 
 	// 欧几里得距离
 	distance := int32(math.Sqrt(float64(dx*dx + dy*dy)))
 	return distance
 }
 
-// isPointInRect 检查点是否在矩形区域内
+// isPointInRect 检查点是否在占地矩形内（左闭右开，与占地登记的网格一致）
 func (o *ObstacleUnit) isPointInRect(x, y int32) bool {
-	rect := o.rect
-	return x >= rect.Coord.X && x <= rect.Coord.X+rect.Width &&
-		y >= rect.Coord.Y && y <= rect.Coord.Y+rect.Height
+	rect := o.footprint.GetRect(&o.coord)
+	return x >= rect.X && x < rect.X+rect.Width &&
+		y >= rect.Y && y < rect.Y+rect.Height
 }
 
 // GetTerrainEffect 获取地形效果（用于障碍物区域）
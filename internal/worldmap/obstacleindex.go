@@ -0,0 +1,126 @@
+package worldmap
+
+import "github.com/GooLuck/WorldMap/internal/worldmap/config"
+
+// obstacleIndex 障碍物空间索引：按 GridManager 的网格分桶，障碍物和障碍物区域按影响范围登记到覆盖的所有网格
+// 查询时只检查查询范围覆盖的网格中的候选项
+type obstacleIndex struct {
+	mapSize   *config.MapSize
+	cols      int32
+	rows      int32
+	obstacles [][]*ObstacleUnit              // 网格索引 -> 影响范围覆盖该网格的障碍物
+	zones     [][]*config.ObstacleZoneConfig // 网格索引 -> 覆盖该网格的障碍物区域
+}
+
+func newObstacleIndex(gridMgr *GridManager) *obstacleIndex {
+	return &obstacleIndex{
+		mapSize:   gridMgr.mapSize,
+		cols:      gridMgr.gridCols,
+		rows:      gridMgr.gridRows,
+		obstacles: make([][]*ObstacleUnit, gridMgr.gridCols*gridMgr.gridRows),
+		zones:     make([][]*config.ObstacleZoneConfig, gridMgr.gridCols*gridMgr.gridRows),
+	}
+}
+
+// obstacleInfluenceRect 障碍物的影响范围：障碍物矩形按最大阻挡半径外扩（边界包含在内，再多扩1）
+func obstacleInfluenceRect(obstacle *ObstacleUnit) (minX, minY, maxX, maxY int32) {
+	obstacleConfig := obstacle.GetConfig()
+	radius := max(obstacleConfig.BuildingRadius, obstacleConfig.ResourceRadius, obstacleConfig.MonsterRadius, 0) + 1
+	rect := obstacle.GetRect()
+	return rect.X - radius, rect.Y - radius, rect.X + rect.Width + radius, rect.Y + rect.Height + radius
+}
+
+// gridRange 范围覆盖的网格索引区间（超出地图的部分归入边缘网格）
+func (idx *obstacleIndex) gridRange(minX, minY, maxX, maxY int32) (minCol, maxCol, minRow, maxRow int32) {
+	clamp := func(v, limit int32) int32 {
+		return min(max(v, 0), limit-1)
+	}
+	minCol = clamp(floorDiv(minX, idx.mapSize.GridWidth), idx.cols)
+	maxCol = clamp(floorDiv(maxX, idx.mapSize.GridWidth), idx.cols)
+	minRow = clamp(floorDiv(minY, idx.mapSize.GridHeight), idx.rows)
+	maxRow = clamp(floorDiv(maxY, idx.mapSize.GridHeight), idx.rows)
+	return
+}
+
+// addObstacle 登记障碍物
+func (idx *obstacleIndex) addObstacle(obstacle *ObstacleUnit) {
+	minCol, maxCol, minRow, maxRow := idx.gridRange(obstacleInfluenceRect(obstacle))
+	for row := minRow; row <= maxRow; row++ {
+		for col := minCol; col <= maxCol; col++ {
+			index := row*idx.cols + col
+			idx.obstacles[index] = append(idx.obstacles[index], obstacle)
+		}
+	}
+}
+
+// removeObstacle 删除障碍物
+func (idx *obstacleIndex) removeObstacle(obstacle *ObstacleUnit) {
+	minCol, maxCol, minRow, maxRow := idx.gridRange(obstacleInfluenceRect(obstacle))
+	for row := minRow; row <= maxRow; row++ {
+		for col := minCol; col <= maxCol; col++ {
+			index := row*idx.cols + col
+			bucket := idx.obstacles[index]
+			for i, o := range bucket {
+				if o == obstacle {
+					idx.obstacles[index] = append(bucket[:i], bucket[i+1:]...)
+					break
+				}
+			}
+		}
+	}
+}
+
+// addZone 登记障碍物区域
+func (idx *obstacleIndex) addZone(zoneConfig *config.ObstacleZoneConfig) {
+	minCol, maxCol, minRow, maxRow := idx.gridRange(zoneConfig.MinX, zoneConfig.MinY, zoneConfig.MaxX, zoneConfig.MaxY)
+	for row := minRow; row <= maxRow; row++ {
+		for col := minCol; col <= maxCol; col++ {
+			index := row*idx.cols + col
+			idx.zones[index] = append(idx.zones[index], zoneConfig)
+		}
+	}
+}
+
+// rangeObstacles 遍历影响范围与查询范围所在网格相交的障碍物（每个障碍物只遍历一次），f 返回 false 停止遍历
+func (idx *obstacleIndex) rangeObstacles(minX, minY, maxX, maxY int32, f func(obstacle *ObstacleUnit) bool) {
+	minCol, maxCol, minRow, maxRow := idx.gridRange(minX, minY, maxX, maxY)
+	var visited map[int64]bool
+	if minCol != maxCol || minRow != maxRow {
+		visited = make(map[int64]bool)
+	}
+
+	for row := minRow; row <= maxRow; row++ {
+		for col := minCol; col <= maxCol; col++ {
+			for _, obstacle := range idx.obstacles[row*idx.cols+col] {
+				if visited != nil {
+					if visited[obstacle.GetId()] {
+						continue
+					}
+					visited[obstacle.GetId()] = true
+				}
+				if !f(obstacle) {
+					return
+				}
+			}
+		}
+	}
+}
+
+// rangeZonesAt 遍历点所在网格中的障碍物区域，f 返回 false 停止遍历
+func (idx *obstacleIndex) rangeZonesAt(x, y int32, f func(zoneConfig *config.ObstacleZoneConfig) bool) {
+	col, _, row, _ := idx.gridRange(x, y, x, y)
+	for _, zoneConfig := range idx.zones[row*idx.cols+col] {
+		if !f(zoneConfig) {
+			return
+		}
+	}
+}
+
+// floorDiv 向下取整的整数除法（负坐标也按网格对齐）
+func floorDiv(a, b int32) int32 {
+	q := a / b
+	if a%b != 0 && (a < 0) != (b < 0) {
+		q--
+	}
+	return q
+}
@@ -11,15 +11,19 @@ type ObstacleManager struct {
 	obstacleZones  map[int32]*config.ObstacleZoneConfig // 障碍物区域ID -> 配置
 	nextObstacleId int64
 	gridMgr        *GridManager
+	coords         *CoordSystem   // 地图坐标系
+	index          *obstacleIndex // 按网格分桶的空间索引
 }
 
 // NewObstacleManager 创建障碍物管理器
-func NewObstacleManager(gridMgr *GridManager) *ObstacleManager {
+func NewObstacleManager(gridMgr *GridManager, coords *CoordSystem) *ObstacleManager {
 	return &ObstacleManager{
 		obstacles:      make(map[int64]*ObstacleUnit),
 		obstacleZones:  make(map[int32]*config.ObstacleZoneConfig),
 		nextObstacleId: 2000,
 		gridMgr:        gridMgr,
+		coords:         coords,
+		index:          newObstacleIndex(gridMgr),
 	}
 }
 
@@ -36,123 +40,124 @@ func (om *ObstacleManager) LoadConfig(mapConfig *config.MapConfig) {
 			obstacleConfig.Height,
 			&obstacleConfig,
 		)
-		om.obstacles[om.nextObstacleId] = obstacle
+		om.addObstacle(obstacle)
 		om.nextObstacleId++
-
-		// 将障碍物单位添加到网格
-		if grid := om.gridMgr.GetGridByCoord(&coord); grid != nil {
-			grid.AddUnit(obstacle)
-		}
 	}
 
 	// 加载障碍物区域
 	for _, zoneConfig := range mapConfig.ObstacleZones {
 		om.obstacleZones[zoneConfig.ZoneID] = &zoneConfig
+		om.index.addZone(&zoneConfig)
 		// 障碍物区域可以动态生成障碍物，这里先只存储配置
 	}
 }
 
+// addObstacle 添加障碍物到管理器、网格和空间索引
+func (om *ObstacleManager) addObstacle(obstacle *ObstacleUnit) {
+	obstacle.SetHexCoord(om.coords.CoordToHex(obstacle.GetCoord()))
+	om.obstacles[obstacle.GetId()] = obstacle
+	om.index.addObstacle(obstacle)
+
+	// 将障碍物单位添加到矩形覆盖的所有网格
+	om.gridMgr.AddUnit(obstacle)
+}
+
 // CanBuildAt 检查指定位置是否可以建造建筑
 func (om *ObstacleManager) CanBuildAt(x, y int32, buildingRadius int32) bool {
-	// 检查所有障碍物
-	for _, obstacle := range om.obstacles {
-		if !obstacle.CanBuildAt(x, y, buildingRadius) {
-			return false
-		}
+	// 检查附近的障碍物（建筑半径外扩查询范围，阻挡半径已包含在障碍物的影响范围中）
+	canBuild := true
+	om.index.rangeObstacles(x-buildingRadius, y-buildingRadius, x+buildingRadius, y+buildingRadius, func(obstacle *ObstacleUnit) bool {
+		canBuild = obstacle.CanBuildAt(x, y, buildingRadius)
+		return canBuild
+	})
+	if !canBuild {
+		return false
 	}
 
 	// 检查障碍物区域
-	for _, zoneConfig := range om.obstacleZones {
-		if zoneConfig.BlockBuilding && om.isPointInZone(x, y, zoneConfig) {
-			return false
-		}
-	}
-
-	return true
+	return !om.anyZoneAt(x, y, func(zoneConfig *config.ObstacleZoneConfig) bool {
+		return zoneConfig.BlockBuilding
+	})
 }
 
 // CanSpawnResourceAt 检查指定位置是否可以刷新资源
 func (om *ObstacleManager) CanSpawnResourceAt(x, y int32) bool {
-	// 检查所有障碍物
-	for _, obstacle := range om.obstacles {
-		if !obstacle.CanSpawnResourceAt(x, y) {
-			return false
-		}
+	// 检查附近的障碍物
+	if om.anyObstacleAt(x, y, func(obstacle *ObstacleUnit) bool {
+		return !obstacle.CanSpawnResourceAt(x, y)
+	}) {
+		return false
 	}
 
 	// 检查障碍物区域
-	for _, zoneConfig := range om.obstacleZones {
-		if zoneConfig.BlockResource && om.isPointInZone(x, y, zoneConfig) {
-			return false
-		}
-	}
-
-	return true
+	return !om.anyZoneAt(x, y, func(zoneConfig *config.ObstacleZoneConfig) bool {
+		return zoneConfig.BlockResource
+	})
 }
 
 // CanSpawnMonsterAt 检查指定位置是否可以刷新怪物
 func (om *ObstacleManager) CanSpawnMonsterAt(x, y int32) bool {
-	// 检查所有障碍物
-	for _, obstacle := range om.obstacles {
-		if !obstacle.CanSpawnMonsterAt(x, y) {
-			return false
-		}
+	// 检查附近的障碍物
+	if om.anyObstacleAt(x, y, func(obstacle *ObstacleUnit) bool {
+		return !obstacle.CanSpawnMonsterAt(x, y)
+	}) {
+		return false
 	}
 
 	// 检查障碍物区域
-	for _, zoneConfig := range om.obstacleZones {
-		if zoneConfig.BlockMonster && om.isPointInZone(x, y, zoneConfig) {
-			return false
-		}
-	}
-
-	return true
+	return !om.anyZoneAt(x, y, func(zoneConfig *config.ObstacleZoneConfig) bool {
+		return zoneConfig.BlockMonster
+	})
 }
 
 // CanMarchThrough 检查是否可以行军通过指定位置
 func (om *ObstacleManager) CanMarchThrough(x, y int32) bool {
-	// 检查所有障碍物（只检查是否在障碍物区域内）
-	for _, obstacle := range om.obstacles {
-		rect := obstacle.GetRect()
-		if om.isPointInRect(x, y, rect) && !obstacle.CanMarchThrough() {
-			return false
-		}
+	// 检查附近的障碍物（只检查是否在障碍物区域内）
+	if om.anyObstacleAt(x, y, func(obstacle *ObstacleUnit) bool {
+		return obstacle.isPointInRect(x, y) && !obstacle.CanMarchThrough()
+	}) {
+		return false
 	}
 
 	// 检查障碍物区域
-	for _, zoneConfig := range om.obstacleZones {
-		if !zoneConfig.AllowMarch && om.isPointInZone(x, y, zoneConfig) {
-			return false
-		}
-	}
+	return !om.anyZoneAt(x, y, func(zoneConfig *config.ObstacleZoneConfig) bool {
+		return !zoneConfig.AllowMarch
+	})
+}
 
-	return true
+// CanMarchThroughRect 检查矩形范围内是否没有阻挡行军的障碍物（障碍物占地矩形与范围相交即阻挡）
+func (om *ObstacleManager) CanMarchThroughRect(rect *geo.Rectangle) bool {
+	blocked := false
+	om.index.rangeObstacles(rect.X, rect.Y, rect.X+rect.Width-1, rect.Y+rect.Height-1, func(obstacle *ObstacleUnit) bool {
+		blocked = !obstacle.CanMarchThrough() && unitFootprintRect(obstacle, obstacle.GetCoord()).Intersects(rect)
+		return !blocked
+	})
+	return !blocked
 }
 
 // GetTerrainEffect 获取指定位置的地形效果
 func (om *ObstacleManager) GetTerrainEffect(x, y int32, effectName string) (float32, bool) {
 	// 检查障碍物区域
-	for _, zoneConfig := range om.obstacleZones {
-		if om.isPointInZone(x, y, zoneConfig) {
-			if effectValue, exists := zoneConfig.TerrainEffects[effectName]; exists {
-				return effectValue, true
-			}
-		}
-	}
-
-	return 0, false
+	var effectValue float32
+	found := om.anyZoneAt(x, y, func(zoneConfig *config.ObstacleZoneConfig) bool {
+		var exists bool
+		effectValue, exists = zoneConfig.TerrainEffects[effectName]
+		return exists
+	})
+	return effectValue, found
 }
 
 // GetObstaclesInArea 获取区域内的障碍物
 func (om *ObstacleManager) GetObstaclesInArea(minX, minY, maxX, maxY int32) []*ObstacleUnit {
 	result := make([]*ObstacleUnit, 0)
 
-	for _, obstacle := range om.obstacles {
+	om.index.rangeObstacles(minX, minY, maxX, maxY, func(obstacle *ObstacleUnit) bool {
 		coord := obstacle.GetCoord()
 		if coord.X >= minX && coord.X <= maxX && coord.Y >= minY && coord.Y <= maxY {
 			result = append(result, obstacle)
 		}
-	}
+		return true
+	})
 
 	return result
 }
@@ -170,13 +175,31 @@ func (om *ObstacleManager) RemoveObstacle(obstacleId int64) {
 	}
 
 	// 从网格中移除
-	coord := obstacle.GetCoord()
-	if grid := om.gridMgr.GetGridByCoord(coord); grid != nil {
-		grid.RemoveUnit(obstacle)
-	}
+	om.gridMgr.RemoveUnit(obstacle)
 
-	// 从管理器中移除
+	// 从管理器和空间索引中移除
 	delete(om.obstacles, obstacleId)
+	om.index.removeObstacle(obstacle)
+}
+
+// anyObstacleAt 点附近是否有满足条件的障碍物
+func (om *ObstacleManager) anyObstacleAt(x, y int32, match func(obstacle *ObstacleUnit) bool) bool {
+	found := false
+	om.index.rangeObstacles(x, y, x, y, func(obstacle *ObstacleUnit) bool {
+		found = match(obstacle)
+		return !found
+	})
+	return found
+}
+
+// anyZoneAt 点所在的障碍物区域中是否有满足条件的区域
+func (om *ObstacleManager) anyZoneAt(x, y int32, match func(zoneConfig *config.ObstacleZoneConfig) bool) bool {
+	found := false
+	om.index.rangeZonesAt(x, y, func(zoneConfig *config.ObstacleZoneConfig) bool {
+		found = om.isPointInZone(x, y, zoneConfig) && match(zoneConfig)
+		return !found
+	})
+	return found
 }
 
 // isPointInZone 检查点是否在障碍物区域内
@@ -185,12 +208,6 @@ func (om *ObstacleManager) isPointInZone(x, y int32, zoneConfig *config.Obstacle
 		y >= zoneConfig.MinY && y <= zoneConfig.MaxY
 }
 
-// isPointInRect 检查点是否在矩形区域内
-func (om *ObstacleManager) isPointInRect(x, y int32, rect *geo.Rectangle) bool {
-	return x >= rect.Coord.X && x <= rect.Coord.X+rect.Width &&
-		y >= rect.Coord.Y && y <= rect.Coord.Y+rect.Height
-}
-
 // GenerateZoneObstacles 生成障碍物区域内的障碍物（按需生成）
 func (om *ObstacleManager) GenerateZoneObstacles(zoneId int32) {
 	zoneConfig, exists := om.obstacleZones[zoneId]
@@ -240,11 +257,7 @@ func (om *ObstacleManager) GenerateZoneObstacles(zoneId int32) {
 			height,
 			&obstacleConfig,
 		)
-		om.obstacles[om.nextObstacleId] = obstacle
+		om.addObstacle(obstacle)
 		om.nextObstacleId++
-
-		if grid := om.gridMgr.GetGridByCoord(&coord); grid != nil {
-			grid.AddUnit(obstacle)
-		}
 	}
 }
@@ -0,0 +1,80 @@
+package worldmap
+
+import (
+	"testing"
+
+	"github.com/GooLuck/WorldMap/internal/worldmap/config"
+	"github.com/GooLuck/WorldMap/internal/worldmap/geo"
+)
+
+// TestObstacleIndex 测试障碍物空间索引的查询结果与逐个检查一致
+func TestObstacleIndex(t *testing.T) {
+	mapConfig := newTestMapConfig()
+	mapConfig.Obstacles = []config.ObstacleConfig{
+		{ObstacleID: 1, X: 90, Y: 90, Width: 20, Height: 20, BlockBuilding: true, BuildingRadius: 30},
+		{ObstacleID: 2, X: 400, Y: 150, Width: 50, Height: 10, BlockResource: true, ResourceRadius: 60},
+		{ObstacleID: 3, X: 700, Y: 700, Width: 100, Height: 100, BlockMonster: true, BlockBuilding: true},
+		{ObstacleID: 4, X: 295, Y: 595, Width: 10, Height: 10, AllowMarch: false},
+		{ObstacleID: 5, X: 980, Y: 980, Width: 40, Height: 40, BlockBuilding: true, BuildingRadius: 50},
+	}
+	mapConfig.ObstacleZones = []config.ObstacleZoneConfig{
+		{ZoneID: 1, MinX: 150, MinY: 450, MaxX: 250, MaxY: 520, BlockResource: true, AllowMarch: true,
+			TerrainEffects: map[string]float32{"slow": 0.5}},
+	}
+	wm := NewWorldMap(mapConfig)
+	obstacleMgr := wm.GetObstacleManager()
+
+	// 逐个检查的结果作为期望值
+	linear := func(x, y int32, check func(obstacle *ObstacleUnit) bool) bool {
+		for _, obstacle := range obstacleMgr.obstacles {
+			if !check(obstacle) {
+				return false
+			}
+		}
+		return true
+	}
+
+	for x := int32(-50); x <= 1050; x += 7 {
+		for y := int32(-50); y <= 1050; y += 7 {
+			if expect := linear(x, y, func(o *ObstacleUnit) bool { return o.CanBuildAt(x, y, 15) }); obstacleMgr.CanBuildAt(x, y, 15) != expect {
+				t.Fatalf("(%d,%d) 建造检查错误：期望 %v", x, y, expect)
+			}
+			if expect := linear(x, y, func(o *ObstacleUnit) bool { return o.CanSpawnMonsterAt(x, y) }); obstacleMgr.CanSpawnMonsterAt(x, y) != expect {
+				t.Fatalf("(%d,%d) 怪物刷新检查错误：期望 %v", x, y, expect)
+			}
+			inZone := x >= 150 && x <= 250 && y >= 450 && y <= 520
+			if expect := !inZone && linear(x, y, func(o *ObstacleUnit) bool { return o.CanSpawnResourceAt(x, y) }); obstacleMgr.CanSpawnResourceAt(x, y) != expect {
+				t.Fatalf("(%d,%d) 资源刷新检查错误：期望 %v", x, y, expect)
+			}
+		}
+	}
+
+	if obstacleMgr.CanMarchThrough(300, 600) || !obstacleMgr.CanMarchThrough(200, 500) {
+		t.Error("行军检查错误")
+	}
+
+	// 阻挡范围与占地登记的网格一致：矩形左闭右开，X+Width 列不属于障碍物
+	gridMgr := wm.GetGridManager()
+	if obstacleMgr.CanMarchThrough(304, 604) || !obstacleMgr.CanMarchThrough(305, 600) || !obstacleMgr.CanMarchThrough(300, 605) {
+		t.Error("障碍物边缘的行军检查错误")
+	}
+	if units := gridMgr.GetRectUnits(geo.NewRectangle(304, 604, 1, 1), false, nil); len(units) != 1 {
+		t.Errorf("障碍物最后一列应该在占地范围内：得到 %d", len(units))
+	}
+	if units := gridMgr.GetRectUnits(geo.NewRectangle(305, 600, 1, 1), false, nil); len(units) != 0 {
+		t.Errorf("障碍物右边界外不应该在占地范围内：得到 %d", len(units))
+	}
+	if effect, ok := obstacleMgr.GetTerrainEffect(200, 500, "slow"); !ok || effect != 0.5 {
+		t.Errorf("地形效果错误：期望 0.5, 得到 %v", effect)
+	}
+	if obstacles := obstacleMgr.GetObstaclesInArea(0, 0, 500, 500); len(obstacles) != 2 {
+		t.Errorf("区域内障碍物数量错误：期望 2, 得到 %d", len(obstacles))
+	}
+
+	// 移除后不再阻挡
+	obstacle := obstacleMgr.GetObstaclesInArea(290, 590, 300, 600)[0]
+	obstacleMgr.RemoveObstacle(obstacle.GetId())
+	if !obstacleMgr.CanMarchThrough(300, 600) {
+		t.Error("移除障碍物后应该可以行军通过")
+	}
+}
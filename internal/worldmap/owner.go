@@ -24,15 +24,10 @@ func NewNpcOwner(id int64) *Owner {
 	return NewOwner(id, OwnerType_Npc)
 }
 
-func (w *Owner) GetRelation(other Owner) Relation {
-	if other.Id == w.Id {
-		return Relation_Self
+// 获取与另一个owner的关系，联盟成员和联盟立场由关系注册表判断（registry 为nil时不考虑联盟，自己和系统以外都是敌人）
+func (w *Owner) GetRelation(registry *RelationRegistry, other *Owner) Relation {
+	if registry == nil {
+		registry = emptyRelations
 	}
-
-	if w.Type == OwnerType_Union && other.Type == OwnerType_Union &&
-		other.Id == w.Id {
-		return Relation_Union
-	}
-
-	return Relation_Enemy
+	return registry.GetRelation(w, other)
 }
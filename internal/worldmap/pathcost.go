@@ -0,0 +1,120 @@
+package worldmap
+
+import (
+	"math"
+
+	"github.com/GooLuck/WorldMap/internal/worldmap/geo"
+)
+
+// PathCostFunc 寻路成本函数：从 from 走到相邻的 to 的成本，passable 为 false 表示 to 不可进入
+type PathCostFunc func(from, to *geo.HexCoord) (cost float64, passable bool)
+
+// PathCostModel 寻路成本模型
+type PathCostModel struct {
+	StepCost    PathCostFunc // 每一步的成本（nil 表示每步成本为 1）
+	MinStepCost float64      // 每步成本的下限，用于A*估价，估价不超过实际成本才能保证最短路径（<=0 表示不估价）
+	MaxCost     float64      // 成本预算，总成本超出预算的路径被剪枝（<=0 表示不限）
+}
+
+// stepCost 计算一步的成本（nil 模型每步成本为 1）
+func (m *PathCostModel) stepCost(from, to *geo.HexCoord) (float64, bool) {
+	if m == nil || m.StepCost == nil {
+		return 1, true
+	}
+	return m.StepCost(from, to)
+}
+
+// minStepCost 估价使用的每步成本下限（nil 模型每步成本为 1）
+func (m *PathCostModel) minStepCost() float64 {
+	if m == nil {
+		return 1
+	}
+	return math.Max(m.MinStepCost, 0)
+}
+
+// estimate 估算从 hex 到 end 的成本
+func (m *PathCostModel) estimate(hex, end *geo.HexCoord) float64 {
+	return float64(hex.DistanceTo(end)) * m.minStepCost()
+}
+
+// withinBudget 总成本是否在预算内
+func (m *PathCostModel) withinBudget(cost float64) bool {
+	return m == nil || m.MaxCost <= 0 || cost <= m.MaxCost
+}
+
+// PathResult 寻路结果
+type PathResult struct {
+	Path      []*geo.HexCoord // 路径上的六边形（包含起点和终点）
+	StepCosts []float64       // 走到 Path[i] 的那一步的成本（与 Path 对齐，起点为0）
+	TotalCost float64         // 总成本
+}
+
+// GetPath 获取路径（nil 结果返回 nil）
+func (r *PathResult) GetPath() []*geo.HexCoord {
+	if r == nil {
+		return nil
+	}
+	return r.Path
+}
+
+// marchBlockingTypes 敌对时会阻挡行军的单位类型（资源点等系统单位不阻挡）
+var marchBlockingTypes = []MapUnitType{MapUnitType_PlayerCity, MapUnitType_PlayerTroop, MapUnitType_Npc, MapUnitType_Watchtower}
+
+// NewMarchCostModel 创建行军寻路成本模型
+// 在地形成本的基础上，不允许行军通过的障碍物和区域、与 owner 敌对（IsHostile，包括NPC）的主城、部队和瞭望塔占据的六边形都不可进入
+// 目标六边形上的敌对单位不阻挡（行军的目的通常就是它）；owner 为nil时不考虑单位阻挡；需要限制成本时设置返回值的 MaxCost
+func (wm *WorldMap) NewMarchCostModel(owner *Owner, target *geo.HexCoord) *PathCostModel {
+	model := &PathCostModel{MinStepCost: 1}
+	terrain := wm.terrainCostModel()
+	if terrain != nil {
+		model.MinStepCost = terrain.MinStepCost
+	}
+	blockingFilter := wm.newMarchBlockingFilter(owner)
+
+	model.StepCost = func(from, to *geo.HexCoord) (float64, bool) {
+		cost, passable := terrain.stepCost(from, to)
+		if !passable || !wm.canMarchInto(to) {
+			return 0, false
+		}
+		if blockingFilter != nil && (target == nil || !to.Equal(target)) {
+			if grid := wm.hexGridMgr.GetGrid(to); grid != nil && hexGridHasUnit(grid, blockingFilter) {
+				return 0, false
+			}
+		}
+		return cost, true
+	}
+	return model
+}
+
+// newMarchBlockingFilter 阻挡 owner 行军的单位：与 owner 敌对的主城、部队和瞭望塔（owner 为nil时返回nil）
+func (wm *WorldMap) newMarchBlockingFilter(owner *Owner) *UnitFilter {
+	if owner == nil {
+		return nil
+	}
+	return NewUnitFilter(nil, nil).WithUnitTypes(marchBlockingTypes...).WithPredicate(func(unit Unit) bool {
+		return wm.IsHostile(owner, unit.GetOwner())
+	})
+}
+
+// canMarchInto 六边形是否可以行军进入
+func (wm *WorldMap) canMarchInto(hex *geo.HexCoord) bool {
+	return wm.canMarchIntoCell(wm.hexToCoord(hex), wm.coords.HexRect(hex))
+}
+
+// canMarchIntoCell 格子是否可以行军进入：中心不在禁止行军的障碍物区域内，格子范围内没有阻挡行军的障碍物
+func (wm *WorldMap) canMarchIntoCell(center *geo.Coord, rect *geo.Rectangle) bool {
+	if !wm.obstacleMgr.CanMarchThrough(center.X, center.Y) {
+		return false
+	}
+	return wm.obstacleMgr.CanMarchThroughRect(rect)
+}
+
+// hexGridHasUnit 六边形网格上是否有满足过滤条件的单位
+func hexGridHasUnit(grid *HexGrid, filter *UnitFilter) bool {
+	found := false
+	grid.RangeUnits(func(unit Unit) bool {
+		found = filter.Match(unit)
+		return !found
+	})
+	return found
+}
@@ -0,0 +1,162 @@
+package worldmap
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/GooLuck/WorldMap/internal/worldmap/config"
+	"github.com/GooLuck/WorldMap/internal/worldmap/geo"
+)
+
+// pathContains 路径是否经过指定六边形
+func pathContains(path []*geo.HexCoord, hex *geo.HexCoord) bool {
+	for _, step := range path {
+		if step.Equal(hex) {
+			return true
+		}
+	}
+	return false
+}
+
+// TestPathCost 测试寻路成本模型：小数成本、不可通行地形、成本预算、障碍物和敌方单位阻挡以及行军用时
+func TestPathCost(t *testing.T) {
+	wm := NewWorldMap(newTestMapConfig())
+	hexGridMgr := wm.GetHexGridManager()
+	terrainMap := NewTerrainMap(hexGridMgr.GetBounds())
+	terrainMap.SetTerrain(geo.NewHexCoord(2, 1), TerrainType_Forest)
+	wm.SetTerrainMap(terrainMap)
+
+	// 森林的成本 1.5 保留小数，穿过森林比绕路便宜
+	start, end := geo.NewHexCoord(1, 1), geo.NewHexCoord(4, 1)
+	result := hexGridMgr.FindPathWithCost(start, end, terrainMap.PathCostModel())
+	if result == nil {
+		t.Fatal("地形寻路失败")
+	}
+	expectCosts := []float64{0, 1.5, 1, 1}
+	if len(result.StepCosts) != len(expectCosts) || len(result.Path) != len(expectCosts) {
+		t.Fatalf("路径步数错误：期望 %d, 得到 %d", len(expectCosts), len(result.Path))
+	}
+	for i, cost := range expectCosts {
+		if result.StepCosts[i] != cost {
+			t.Errorf("第 %d 步成本错误：期望 %.1f, 得到 %.1f", i, cost, result.StepCosts[i])
+		}
+	}
+	if result.TotalCost != 3.5 {
+		t.Errorf("总成本错误：期望 3.5, 得到 %.1f", result.TotalCost)
+	}
+
+	// 成本预算
+	model := terrainMap.PathCostModel()
+	model.MaxCost = 3.5
+	if hexGridMgr.FindPathWithCost(start, end, model) == nil {
+		t.Error("预算内的路径应该找到")
+	}
+	model.MaxCost = 3.4
+	if hexGridMgr.FindPathWithCost(start, end, model) != nil {
+		t.Error("超出预算的路径应该返回 nil")
+	}
+
+	// 不可通行的地形被排除，而不是按高成本经过
+	water := geo.NewHexCoord(3, 1)
+	terrainMap.SetTerrain(water, TerrainType_Water)
+	result = hexGridMgr.FindPathWithCost(start, end, terrainMap.PathCostModel())
+	if result == nil || pathContains(result.Path, water) {
+		t.Fatal("路径不应该经过水域")
+	}
+	total := 0.0
+	for _, cost := range result.StepCosts {
+		total += cost
+	}
+	if math.Abs(total-result.TotalCost) > 1e-9 {
+		t.Errorf("每步成本之和与总成本不一致：%.2f != %.2f", total, result.TotalCost)
+	}
+	if hexGridMgr.FindPathWithCost(start, water, terrainMap.PathCostModel()) != nil {
+		t.Error("终点不可通行时应该返回 nil")
+	}
+}
+
+// TestMarchCostModel 测试行军成本模型的障碍物、敌方单位阻挡和按成本计算的行军用时
+func TestMarchCostModel(t *testing.T) {
+	// 不允许行军通过的障碍物（覆盖六边形的一部分，但不覆盖中心）
+	wall := geo.NewHexCoord(2, 1)
+	wallCoord := NewWorldMap(newTestMapConfig()).GetCoordSystem().HexToCoord(wall)
+	mapConfig := newTestMapConfig()
+	mapConfig.Obstacles = []config.ObstacleConfig{{ObstacleID: 1, X: wallCoord.X + 10, Y: wallCoord.Y + 10, Width: 10, Height: 10, AllowMarch: false}}
+	wm := NewWorldMap(mapConfig)
+	hexGridMgr := wm.GetHexGridManager()
+
+	// 敌方主城
+	enemyHex := geo.NewHexCoord(3, 1)
+	enemy := &TestUnit{id: 1, coord: *wm.hexToCoord(enemyHex), hexCoord: enemyHex, unitType: MapUnitType_PlayerCity, owner: NewPlayerOwner(2)}
+	wm.AddUnit(enemy)
+
+	owner := NewPlayerOwner(1)
+	start, end := geo.NewHexCoord(1, 1), geo.NewHexCoord(4, 1)
+	result := hexGridMgr.FindPathWithCost(start, end, wm.NewMarchCostModel(owner, end))
+	if result == nil {
+		t.Fatal("行军寻路失败")
+	}
+	if pathContains(result.Path, wall) || pathContains(result.Path, enemyHex) {
+		t.Error("行军路径不应该经过障碍物和敌方单位")
+	}
+	if _, passable := wm.NewMarchCostModel(owner, wall).StepCost(start, wall); passable {
+		t.Error("障碍物覆盖的六边形不应该可以进入")
+	}
+
+	// 目标六边形上的敌方单位不阻挡
+	result = hexGridMgr.FindPathWithCost(start, enemyHex, wm.NewMarchCostModel(owner, enemyHex))
+	if result == nil || !result.Path[len(result.Path)-1].Equal(enemyHex) {
+		t.Error("应该可以行军到敌方单位所在的目标六边形")
+	}
+
+	// NPC 敌对时阻挡，资源点不阻挡
+	npcHex, resourceHex := geo.NewHexCoord(1, 3), geo.NewHexCoord(2, 3)
+	wm.AddUnit(&TestUnit{id: 2, coord: *wm.hexToCoord(npcHex), unitType: MapUnitType_Npc, owner: NewNpcOwner(1)})
+	wm.AddUnit(&TestUnit{id: 3, coord: *wm.hexToCoord(resourceHex), unitType: MapUnitType_Resource, owner: NewOwner(0, OwnerType_System)})
+	model := wm.NewMarchCostModel(owner, end)
+	if _, passable := model.StepCost(start, npcHex); passable {
+		t.Error("NPC所在的六边形不应该可以通过")
+	}
+	if _, passable := model.StepCost(start, resourceHex); !passable {
+		t.Error("资源点所在的六边形应该可以通过")
+	}
+
+	// 同盟的单位不阻挡
+	wm.GetRelationRegistry().SetPlayerAlliance(1, 100)
+	wm.GetRelationRegistry().SetPlayerAlliance(2, 100)
+	if _, passable := wm.NewMarchCostModel(owner, end).StepCost(end, enemyHex); !passable {
+		t.Error("同盟单位所在的六边形应该可以通过")
+	}
+
+	// 行军用时按每一步的成本系数计算
+	wm = NewWorldMap(newTestMapConfig())
+	terrainMap := NewTerrainMap(wm.GetHexGridManager().GetBounds())
+	terrainMap.SetTerrain(geo.NewHexCoord(2, 1), TerrainType_Forest)
+	wm.SetTerrainMap(terrainMap)
+	troopMgr := wm.GetTroopManager()
+	troop := troopMgr.CreateTroop(1, owner, wm.hexToCoord(start))
+	stepLen := math.Sqrt(3) * wm.GetHexGridManager().GetLayout().Radius
+	now := time.Now()
+
+	troopMgr.SetMaxMarchCost(3)
+	if troopMgr.MarchTo(troop, end, stepLen, now) {
+		t.Fatal("超出成本预算时不应该出发")
+	}
+	troopMgr.SetMaxMarchCost(0)
+	if !troopMgr.MarchTo(troop, end, stepLen, now) {
+		t.Fatal("行军失败")
+	}
+	if got := troop.GetArriveTime().Sub(now); math.Abs(got.Seconds()-3.5) > 0.001 {
+		t.Errorf("到达时间错误：期望 3.5s, 得到 %v", got)
+	}
+
+	// 森林段走了 2/3 时召回，原路返回同样按森林的成本计算
+	recallTime := now.Add(time.Second)
+	if !troopMgr.Recall(troop, recallTime) {
+		t.Fatal("召回失败")
+	}
+	if got := troop.GetArriveTime().Sub(recallTime); math.Abs(got.Seconds()-1) > 0.001 {
+		t.Errorf("召回到达时间错误：期望 1s, 得到 %v", got)
+	}
+}
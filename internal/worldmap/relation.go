@@ -1,6 +1,195 @@
 package worldmap
 
-// 获取两个owner之间的关系
+// 关系变化
+type RelationChange struct {
+	PlayerId    int64          // 联盟成员变化的玩家id（立场变化时为0）
+	AllianceIds []int64        // 受影响的联盟id（默认立场变化时为空）
+	Stance      AllianceStance // 变化后的立场（成员变化时为None）
+}
+
+// 是否为默认立场变化（影响所有玩家）
+func (c *RelationChange) IsDefaultStance() bool {
+	return c.PlayerId == 0 && len(c.AllianceIds) == 0
+}
+
+// 关系变化监听
+type RelationChangeListener func(change *RelationChange)
+
+// 联盟立场的键（小id在前）
+type stanceKey struct {
+	allianceA int64
+	allianceB int64
+}
+
+func newStanceKey(allianceA, allianceB int64) stanceKey {
+	if allianceA > allianceB {
+		allianceA, allianceB = allianceB, allianceA
+	}
+	return stanceKey{allianceA: allianceA, allianceB: allianceB}
+}
+
+// 关系注册表：玩家所属联盟、联盟之间的立场，地图上所有关系判断都通过它
+type RelationRegistry struct {
+	playerAlliances map[int64]int64              // 玩家id -> 联盟id
+	stances         map[stanceKey]AllianceStance // 联盟之间的立场
+	defaultStance   AllianceStance               // 未设置立场时的默认立场（包括无联盟的玩家）
+	listeners       map[int64]RelationChangeListener
+	nextListenerId  int64
+}
+
+func NewRelationRegistry() *RelationRegistry {
+	return &RelationRegistry{
+		playerAlliances: make(map[int64]int64),
+		stances:         make(map[stanceKey]AllianceStance),
+		defaultStance:   AllianceStance_War,
+		listeners:       make(map[int64]RelationChangeListener),
+	}
+}
+
+// 注册关系变化监听，返回监听id
+func (rr *RelationRegistry) AddListener(listener RelationChangeListener) int64 {
+	rr.nextListenerId++
+	rr.listeners[rr.nextListenerId] = listener
+	return rr.nextListenerId
+}
+
+// 移除关系变化监听
+func (rr *RelationRegistry) RemoveListener(listenerId int64) {
+	delete(rr.listeners, listenerId)
+}
+
+// 设置玩家所属联盟（0表示退出联盟）
+func (rr *RelationRegistry) SetPlayerAlliance(playerId, allianceId int64) {
+	oldAllianceId := rr.playerAlliances[playerId]
+	if oldAllianceId == allianceId {
+		return
+	}
+
+	if allianceId == 0 {
+		delete(rr.playerAlliances, playerId)
+	} else {
+		rr.playerAlliances[playerId] = allianceId
+	}
+
+	change := &RelationChange{PlayerId: playerId, AllianceIds: make([]int64, 0, 2)}
+	for _, id := range []int64{oldAllianceId, allianceId} {
+		if id != 0 {
+			change.AllianceIds = append(change.AllianceIds, id)
+		}
+	}
+	rr.notify(change)
+}
+
+// 获取玩家所属联盟
+func (rr *RelationRegistry) GetPlayerAlliance(playerId int64) int64 {
+	return rr.playerAlliances[playerId]
+}
+
+// 设置两个联盟之间的立场
+func (rr *RelationRegistry) SetStance(allianceA, allianceB int64, stance AllianceStance) {
+	if allianceA == 0 || allianceB == 0 || allianceA == allianceB {
+		return
+	}
+
+	key := newStanceKey(allianceA, allianceB)
+	if rr.stances[key] == stance {
+		return
+	}
+	if stance == AllianceStance_None {
+		delete(rr.stances, key)
+	} else {
+		rr.stances[key] = stance
+	}
+	rr.notify(&RelationChange{AllianceIds: []int64{allianceA, allianceB}, Stance: stance})
+}
+
+// 获取两个联盟之间的立场（未设置时返回默认立场）
+func (rr *RelationRegistry) GetStance(allianceA, allianceB int64) AllianceStance {
+	if stance, exists := rr.stances[newStanceKey(allianceA, allianceB)]; exists {
+		return stance
+	}
+	return rr.defaultStance
+}
+
+// 设置默认立场，变化时通知所有监听
+func (rr *RelationRegistry) SetDefaultStance(stance AllianceStance) {
+	if rr.defaultStance == stance {
+		return
+	}
+	rr.defaultStance = stance
+	rr.notify(&RelationChange{Stance: stance})
+}
+
+// 获取owner所属联盟：玩家为其加入的联盟，联盟为自身
+func (rr *RelationRegistry) GetOwnerAlliance(owner *Owner) int64 {
+	if owner == nil {
+		return 0
+	}
+	switch owner.Type {
+	case OwnerType_Player:
+		return rr.playerAlliances[owner.Id]
+	case OwnerType_Union:
+		return owner.Id
+	}
+	return 0
+}
+
+// 获取两个owner之间的关系（系统和NPC共用同一个owner，彼此之间也是系统关系而不是自己）
+func (rr *RelationRegistry) GetRelation(owner1 *Owner, owner2 *Owner) Relation {
+	if isSystemOwner(owner1) || isSystemOwner(owner2) {
+		return Relation_System
+	}
+	if owner1.Type == owner2.Type && owner1.Id == owner2.Id {
+		return Relation_Self
+	}
+
+	alliance1 := rr.GetOwnerAlliance(owner1)
+	alliance2 := rr.GetOwnerAlliance(owner2)
+	if alliance1 != 0 && alliance1 == alliance2 {
+		return Relation_Union
+	}
+
+	stance := rr.defaultStance
+	if alliance1 != 0 && alliance2 != 0 {
+		stance = rr.GetStance(alliance1, alliance2)
+	}
+	return stanceToRelation(stance)
+}
+
+// 两个owner是否敌对（宣战的玩家/联盟，或系统/NPC单位）
+func (rr *RelationRegistry) IsHostile(owner1 *Owner, owner2 *Owner) bool {
+	relation := rr.GetRelation(owner1, owner2)
+	return relation == Relation_Enemy || relation == Relation_System
+}
+
+// 获取两个owner之间的关系，只按系统和自己判断，其他都是敌人
+//
+// Deprecated: 联盟成员和立场由关系注册表判断，使用 WorldMap.GetOwnerRelation 或 RelationRegistry.GetRelation
 func GetOwnerRelation(owner1 *Owner, owner2 *Owner) Relation {
+	return emptyRelations.GetRelation(owner1, owner2)
+}
+
+// 没有联盟成员和立场的注册表，未指定注册表时使用（不添加成员、立场和监听）
+var emptyRelations = NewRelationRegistry()
+
+func (rr *RelationRegistry) notify(change *RelationChange) {
+	for _, listener := range rr.listeners {
+		listener(change)
+	}
+}
+
+func isSystemOwner(owner *Owner) bool {
+	return owner == nil || owner.Type == OwnerType_System || owner.Type == OwnerType_Npc || owner.Type == OwnerType_None
+}
+
+func stanceToRelation(stance AllianceStance) Relation {
+	switch stance {
+	case AllianceStance_Ally:
+		return Relation_Ally
+	case AllianceStance_Nap:
+		return Relation_Nap
+	case AllianceStance_Neutral:
+		return Relation_Neutral
+	}
 	return Relation_Enemy
 }
@@ -0,0 +1,192 @@
+package worldmap
+
+import (
+	"testing"
+
+	"github.com/GooLuck/WorldMap/internal/worldmap/geo"
+)
+
+// TestRelationRegistry 测试联盟成员和联盟立场决定的关系
+func TestRelationRegistry(t *testing.T) {
+	registry := NewRelationRegistry()
+	registry.SetPlayerAlliance(1, 100)
+	registry.SetPlayerAlliance(2, 100)
+	registry.SetPlayerAlliance(3, 200)
+	registry.SetPlayerAlliance(4, 300)
+
+	player1 := NewPlayerOwner(1)
+	cases := []struct {
+		name     string
+		other    *Owner
+		stance   AllianceStance
+		expected Relation
+	}{
+		{"自己", NewPlayerOwner(1), AllianceStance_None, Relation_Self},
+		{"同联盟成员", NewPlayerOwner(2), AllianceStance_None, Relation_Union},
+		{"所属联盟", NewUnionOwner(100), AllianceStance_None, Relation_Union},
+		{"默认立场", NewPlayerOwner(3), AllianceStance_None, Relation_Enemy},
+		{"结盟", NewPlayerOwner(3), AllianceStance_Ally, Relation_Ally},
+		{"互不侵犯", NewPlayerOwner(3), AllianceStance_Nap, Relation_Nap},
+		{"中立", NewPlayerOwner(3), AllianceStance_Neutral, Relation_Neutral},
+		{"无联盟玩家", NewPlayerOwner(5), AllianceStance_None, Relation_Enemy},
+		{"系统", NewOwner(0, OwnerType_System), AllianceStance_None, Relation_System},
+	}
+	for _, c := range cases {
+		registry.SetStance(100, 200, c.stance)
+		if got := player1.GetRelation(registry, c.other); got != c.expected {
+			t.Errorf("%s关系错误：期望 %d, 得到 %d", c.name, c.expected, got)
+		}
+	}
+
+	// 退出联盟后不再是同盟
+	registry.SetPlayerAlliance(2, 0)
+	if got := registry.GetRelation(player1, NewPlayerOwner(2)); got != Relation_Enemy {
+		t.Errorf("退出联盟后关系错误：期望 %d, 得到 %d", Relation_Enemy, got)
+	}
+	if !registry.IsHostile(player1, NewOwner(0, OwnerType_System)) || registry.IsHostile(player1, NewUnionOwner(100)) {
+		t.Error("敌对判断错误")
+	}
+
+	// 系统和NPC之间不是自己
+	system := NewOwner(0, OwnerType_System)
+	if got := registry.GetRelation(system, NewOwner(0, OwnerType_System)); got != Relation_System {
+		t.Errorf("系统之间的关系错误：期望 %d, 得到 %d", Relation_System, got)
+	}
+	if got := registry.GetRelation(NewNpcOwner(1), NewNpcOwner(1)); got != Relation_System {
+		t.Errorf("NPC之间的关系错误：期望 %d, 得到 %d", Relation_System, got)
+	}
+}
+
+// TestOwnerRelationWithoutRegistry 测试没有关系注册表时的关系判断
+func TestOwnerRelationWithoutRegistry(t *testing.T) {
+	player1, player2 := NewPlayerOwner(1), NewPlayerOwner(2)
+	if got := player1.GetRelation(nil, player2); got != Relation_Enemy {
+		t.Errorf("没有注册表时的关系错误：期望 %d, 得到 %d", Relation_Enemy, got)
+	}
+	if got := player1.GetRelation(nil, NewPlayerOwner(1)); got != Relation_Self {
+		t.Errorf("没有注册表时自己的关系错误：期望 %d, 得到 %d", Relation_Self, got)
+	}
+	if got := GetOwnerRelation(player1, NewNpcOwner(1)); got != Relation_System {
+		t.Errorf("与NPC的关系错误：期望 %d, 得到 %d", Relation_System, got)
+	}
+	if got := GetOwnerRelation(player1, player2); got != Relation_Enemy {
+		t.Errorf("与其他玩家的关系错误：期望 %d, 得到 %d", Relation_Enemy, got)
+	}
+}
+
+// TestRelationChangeNotify 测试关系变化通知受影响的观察者
+func TestRelationChangeNotify(t *testing.T) {
+	wm := NewWorldMap(newTestMapConfig())
+	registry := wm.GetRelationRegistry()
+	registry.SetPlayerAlliance(1, 100)
+	registry.SetPlayerAlliance(2, 200)
+	registry.SetPlayerAlliance(3, 300)
+
+	notified := make(map[int64]int)
+	observerMgr := wm.GetObserverManager()
+	for _, playerId := range []int64{1, 2, 3} {
+		observerMgr.AddObserver(playerId, geo.NewRectangle(0, 0, 100, 100), 0)
+	}
+	observerMgr.AddRelationChangeNotifier(func(observer *Observer, change *RelationChange) {
+		notified[observer.Id]++
+	})
+
+	registry.SetStance(100, 200, AllianceStance_Ally)
+	if notified[1] != 1 || notified[2] != 1 || notified[3] != 0 {
+		t.Errorf("立场变化通知错误：得到 %v", notified)
+	}
+	if wm.GetOwnerRelation(NewPlayerOwner(1), NewPlayerOwner(2)) != Relation_Ally {
+		t.Error("地图关系判断应该通过关系注册表")
+	}
+
+	// 默认立场变化影响所有观察者，立场不变时不通知
+	registry.SetDefaultStance(AllianceStance_Neutral)
+	registry.SetDefaultStance(AllianceStance_Neutral)
+	if notified[1] != 2 || notified[2] != 2 || notified[3] != 1 {
+		t.Errorf("默认立场变化通知错误：得到 %v", notified)
+	}
+	if wm.GetOwnerRelation(NewPlayerOwner(1), NewPlayerOwner(3)) != Relation_Neutral {
+		t.Error("未设置立场的联盟应该使用新的默认立场")
+	}
+	registry.SetDefaultStance(AllianceStance_War)
+	clear(notified)
+
+	// 共享注册表后，原注册表的变化不再通知本地图
+	shared := NewRelationRegistry()
+	wm.SetRelationRegistry(shared)
+	registry.SetStance(100, 300, AllianceStance_War)
+	shared.SetPlayerAlliance(3, 400)
+	if notified[1] != 0 || notified[3] != 1 {
+		t.Errorf("切换注册表后通知错误：得到 %v", notified)
+	}
+}
+
+// TestRelationFilteredQueries 测试按关系、类型和自定义条件过滤的空间查询
+func TestRelationFilteredQueries(t *testing.T) {
+	wm := NewWorldMap(newTestMapConfig())
+	registry := wm.GetRelationRegistry()
+	registry.SetPlayerAlliance(1, 100)
+	registry.SetPlayerAlliance(2, 100)
+	registry.SetPlayerAlliance(3, 200)
+	registry.SetPlayerAlliance(4, 300)
+	registry.SetStance(100, 300, AllianceStance_Ally)
+
+	addUnit := func(id int64, hex *geo.HexCoord, unitType MapUnitType, owner *Owner) {
+		wm.AddUnit(&TestUnit{id: id, coord: *wm.hexToCoord(hex), hexCoord: hex, unitType: unitType, owner: owner})
+	}
+	addUnit(1, geo.NewHexCoord(3, 3), MapUnitType_PlayerCity, NewPlayerOwner(1))
+	addUnit(2, geo.NewHexCoord(3, 2), MapUnitType_PlayerCity, NewPlayerOwner(2))
+	addUnit(3, geo.NewHexCoord(4, 3), MapUnitType_PlayerTroop, NewPlayerOwner(3))
+	addUnit(4, geo.NewHexCoord(2, 3), MapUnitType_PlayerCity, NewPlayerOwner(4))
+	addUnit(5, geo.NewHexCoord(3, 4), MapUnitType_Npc, NewOwner(0, OwnerType_System))
+
+	viewer := NewPlayerOwner(1)
+	collectIds := func(units []Unit) map[int64]bool {
+		ids := make(map[int64]bool)
+		for _, unit := range units {
+			ids[unit.GetId()] = true
+		}
+		return ids
+	}
+
+	// 1格内的敌人
+	enemyFilter := wm.NewUnitFilter(viewer).WithRelations(Relation_Enemy, Relation_System)
+	ids := collectIds(wm.GetHexGridManager().GetUnitsInRadius(geo.NewHexCoord(3, 3), 1, enemyFilter))
+	if len(ids) != 2 || !ids[3] || !ids[5] {
+		t.Errorf("敌人查询错误：得到 %v", ids)
+	}
+
+	// Relation_All 表示不按关系过滤
+	for _, relations := range [][]Relation{{Relation_All}, {Relation_Enemy, Relation_All}} {
+		allFilter := wm.NewUnitFilter(viewer).WithRelations(relations...)
+		if units := wm.GetHexGridManager().GetUnitsInRadius(geo.NewHexCoord(3, 3), 1, allFilter); len(units) != 5 {
+			t.Errorf("任意关系查询数量错误：期望 5, 得到 %d", len(units))
+		}
+	}
+
+	// 矩形内的友方主城
+	allyCityFilter := wm.NewUnitFilter(viewer).
+		WithUnitTypes(MapUnitType_PlayerCity).
+		WithRelations(Relation_Self, Relation_Union, Relation_Ally)
+	ids = collectIds(wm.GetGridManager().GetRectUnits(geo.NewRectangle(0, 0, 1000, 1000), true, allyCityFilter))
+	if len(ids) != 3 || !ids[1] || !ids[2] || !ids[4] {
+		t.Errorf("友方主城查询错误：得到 %v", ids)
+	}
+
+	// 自定义条件
+	allyCityFilter.WithPredicate(func(unit Unit) bool { return unit.GetId() != 1 })
+	ids = collectIds(wm.GetGridManager().GetRectUnits(geo.NewRectangle(0, 0, 1000, 1000), true, allyCityFilter))
+	if len(ids) != 2 || ids[1] {
+		t.Errorf("自定义条件查询错误：得到 %v", ids)
+	}
+	allyCityFilter.WithPredicate(func(unit Unit) bool { return unit.GetId() != 2 })
+	ids = collectIds(wm.GetGridManager().GetRectUnits(geo.NewRectangle(0, 0, 1000, 1000), true, allyCityFilter))
+	if len(ids) != 1 || !ids[4] {
+		t.Errorf("多个自定义条件应该同时满足：得到 %v", ids)
+	}
+
+	// 不过滤
+	if units := wm.GetHexGridManager().GetUnitsInRadius(geo.NewHexCoord(3, 3), 1, nil); len(units) != 5 {
+		t.Errorf("不过滤查询数量错误：期望 5, 得到 %d", len(units))
+	}
+}
@@ -19,15 +19,16 @@ type ResourceUnit struct {
 	lastRefreshTime time.Time
 	currentAmount   int32
 	isActive        bool
+	occupierId      int64  // 占领（采集）中的部队id，0表示未被占领
+	occupierOwner   *Owner // 占领部队的所有者
 }
 
-// NewResourceUnit 创建新的资源点单位（指定 ID）
+// NewResourceUnit 创建新的资源点单位（指定 ID），六边形坐标在加入地图时由地图坐标系换算
 func NewResourceUnit(id int64, configId int32, coord geo.Coord, config *config.EnhancedResourcePointConfig) *ResourceUnit {
 	return &ResourceUnit{
 		id:              id,
 		configId:        configId,
 		coord:           coord,
-		hexCoord:        geo.NewHexCoord(coord.X, coord.Y),
 		owner:           NewOwner(0, OwnerType_System),
 		config:          config,
 		currentAmount:   config.CurrentAmount,
@@ -45,7 +46,6 @@ func NewResourceUnitWithGeneratedID(configId int32, coord geo.Coord, config *con
 		id:              id,
 		configId:        configId,
 		coord:           coord,
-		hexCoord:        geo.NewHexCoord(coord.X, coord.Y),
 		owner:           NewOwner(0, OwnerType_System),
 		config:          config,
 		currentAmount:   config.CurrentAmount,
@@ -90,16 +90,48 @@ func (r *ResourceUnit) GetType() MapUnitType {
 	return MapUnitType_Resource
 }
 
-// GetOwner 获取所有者
+// GetOwner 获取所有者（资源点始终属于系统，占领者通过 GetOccupierOwner 获取）
 func (r *ResourceUnit) GetOwner() *Owner {
 	return r.owner
 }
 
+// IsOccupied 是否被部队占领
+func (r *ResourceUnit) IsOccupied() bool {
+	return r.occupierId != 0
+}
+
+// GetOccupierId 获取占领部队id
+func (r *ResourceUnit) GetOccupierId() int64 {
+	return r.occupierId
+}
+
+// GetOccupierOwner 获取占领部队的所有者
+func (r *ResourceUnit) GetOccupierOwner() *Owner {
+	return r.occupierOwner
+}
+
+// occupy 被部队占领
+func (r *ResourceUnit) occupy(troop *TroopUnit) {
+	r.occupierId = troop.GetId()
+	r.occupierOwner = troop.GetOwner()
+}
+
+// release 解除占领
+func (r *ResourceUnit) release() {
+	r.occupierId = 0
+	r.occupierOwner = nil
+}
+
 // GetResourceType 获取资源类型
 func (r *ResourceUnit) GetResourceType() string {
 	return r.config.ResourceType
 }
 
+// GetLevel 获取资源点等级
+func (r *ResourceUnit) GetLevel() int32 {
+	return r.config.Level
+}
+
 // GetCurrentAmount 获取当前资源量
 func (r *ResourceUnit) GetCurrentAmount() int32 {
 	return r.currentAmount
@@ -107,6 +139,11 @@ func (r *ResourceUnit) GetCurrentAmount() int32 {
 
 // Harvest 采集资源
 func (r *ResourceUnit) Harvest(amount int32) int32 {
+	return r.harvestAt(amount, time.Now())
+}
+
+// harvestAt 在指定时间采集资源
+func (r *ResourceUnit) harvestAt(amount int32, now time.Time) int32 {
 	if amount <= 0 || !r.isActive {
 		return 0
 	}
@@ -117,7 +154,7 @@ func (r *ResourceUnit) Harvest(amount int32) int32 {
 	}
 
 	r.currentAmount -= harvested
-	r.lastHarvestTime = time.Now()
+	r.lastHarvestTime = now
 
 	if r.currentAmount <= 0 {
 		r.currentAmount = 0
@@ -14,22 +14,35 @@ type ResourceManager struct {
 	resourceZones   map[int32]*config.ResourceZoneConfig // 资源区域ID -> 配置
 	globalConfig    *config.GlobalRefreshConfig
 	lastRefreshTime time.Time
-	gridMgr         *GridManager
-	obstacleMgr     *ObstacleManager // 障碍物管理器
+	worldMap        *WorldMap                // 所属地图
+	gathers         map[int64]*gatherSession // 资源点ID -> 采集
+	troopGathers    map[int64]int64          // 部队ID -> 采集中的资源点ID
+	gatherCallbacks []GatherFinishCallback
 }
 
 // NewResourceManager 创建资源管理器
-func NewResourceManager(gridMgr *GridManager, obstacleMgr *ObstacleManager, globalConfig *config.GlobalRefreshConfig) *ResourceManager {
+func NewResourceManager(worldMap *WorldMap, globalConfig *config.GlobalRefreshConfig) *ResourceManager {
 	return &ResourceManager{
 		resources:       make(map[int64]*ResourceUnit),
 		resourceZones:   make(map[int32]*config.ResourceZoneConfig),
 		globalConfig:    globalConfig,
 		lastRefreshTime: time.Now(),
-		gridMgr:         gridMgr,
-		obstacleMgr:     obstacleMgr,
+		worldMap:        worldMap,
+		gathers:         make(map[int64]*gatherSession),
+		troopGathers:    make(map[int64]int64),
+		gatherCallbacks: make([]GatherFinishCallback, 0),
 	}
 }
 
+// addResource 登记资源点并加入地图（六边形坐标由地图坐标系换算），坐标不在地图内时不登记
+func (rm *ResourceManager) addResource(resource *ResourceUnit) bool {
+	if !rm.worldMap.AddUnit(resource) {
+		return false
+	}
+	rm.resources[resource.GetId()] = resource
+	return true
+}
+
 // LoadConfig 加载资源配置
 func (rm *ResourceManager) LoadConfig(mapConfig *config.MapConfig) {
 	// 加载增强资源点
@@ -40,12 +53,9 @@ func (rm *ResourceManager) LoadConfig(mapConfig *config.MapConfig) {
 			// 如果ID生成失败，使用配置ID作为回退（不推荐，但保证功能）
 			resource = NewResourceUnit(int64(pointConfig.PointID), pointConfig.PointID, coord, &pointConfig)
 		}
-		rm.resources[resource.GetId()] = resource
 
-		// 将资源单位添加到网格
-		if grid := rm.gridMgr.GetGridByCoord(&coord); grid != nil {
-			grid.AddUnit(resource)
-		}
+		// 将资源单位添加到地图
+		rm.addResource(resource)
 	}
 
 	// 加载资源区域
@@ -77,16 +87,15 @@ func (rm *ResourceManager) LoadConfig(mapConfig *config.MapConfig) {
 			// 如果ID生成失败，使用配置ID作为回退
 			resource = NewResourceUnit(int64(oldPoint.PointID), oldPoint.PointID, coord, &enhancedConfig)
 		}
-		rm.resources[resource.GetId()] = resource
-
-		if grid := rm.gridMgr.GetGridByCoord(&coord); grid != nil {
-			grid.AddUnit(resource)
-		}
+		rm.addResource(resource)
 	}
 }
 
 // Update 更新所有资源点状态
 func (rm *ResourceManager) Update(now time.Time) {
+	// 推进部队采集
+	rm.updateGathers(now)
+
 	// 更新现有资源点
 	for _, resource := range rm.resources {
 		resource.Update(now)
@@ -211,7 +220,7 @@ func (rm *ResourceManager) spawnResourceInZone(zoneConfig *config.ResourceZoneCo
 		}
 
 		// 检查是否在障碍物阻挡区域内
-		if rm.obstacleMgr != nil && !rm.obstacleMgr.CanSpawnResourceAt(x, y) {
+		if !rm.worldMap.obstacleMgr.CanSpawnResourceAt(x, y) {
 			continue
 		}
 
@@ -241,12 +250,9 @@ func (rm *ResourceManager) spawnResourceInZone(zoneConfig *config.ResourceZoneCo
 
 		// 更新配置中的PointID为生成的ID（转换为int32）
 		config.PointID = int32(resource.GetId())
-		rm.resources[resource.GetId()] = resource
 
-		// 添加到网格
-		if grid := rm.gridMgr.GetGridByCoord(&coord); grid != nil {
-			grid.AddUnit(resource)
-		}
+		// 添加到地图
+		rm.addResource(resource)
 
 		break
 	}
@@ -363,18 +369,24 @@ func (rm *ResourceManager) HarvestResource(resourceId int64, amount int32, playe
 	return resource.Harvest(amount)
 }
 
-// RemoveResource 移除资源点
+// RemoveResource 移除资源点，采集中的部队按当前时间结算
 func (rm *ResourceManager) RemoveResource(resourceId int64) {
+	rm.RemoveResourceAt(resourceId, time.Now())
+}
+
+// RemoveResourceAt 移除资源点，采集中的部队按 now 结算
+func (rm *ResourceManager) RemoveResourceAt(resourceId int64, now time.Time) {
 	resource, exists := rm.resources[resourceId]
 	if !exists {
 		return
 	}
 
-	// 从网格中移除
-	coord := resource.GetCoord()
-	if grid := rm.gridMgr.GetGridByCoord(coord); grid != nil {
-		grid.RemoveUnit(resource)
+	// 结束采集并从地图中移除
+	if session, exists := rm.gathers[resourceId]; exists {
+		rm.settleGather(session, now)
+		rm.finishGather(session, now)
 	}
+	rm.worldMap.RemoveUnit(resource)
 
 	// 从管理器中移除
 	delete(rm.resources, resourceId)
@@ -0,0 +1,177 @@
+package worldmap
+
+import (
+	"slices"
+	"sort"
+	"time"
+
+	"github.com/GooLuck/WorldMap/internal/worldmap/geo"
+)
+
+// ScoutLevel 侦察等级，等级越高报告越详细
+type ScoutLevel int32
+
+const (
+	ScoutLevel_None   ScoutLevel = iota
+	ScoutLevel_Basic             // 单位类型、位置、所属者
+	ScoutLevel_Detail            // 增加等级、资源量、兵力、负重
+	ScoutLevel_Full              // 增加主城驻军明细
+)
+
+// ScoutGarrison 主城中的驻军
+type ScoutGarrison struct {
+	TroopId  int64 // 部队id
+	ConfigId int32 // 部队配置id
+	Owner    Owner // 部队所属者
+	Load     int32 // 负重
+}
+
+// ScoutUnitInfo 侦察到的单位（只包含值类型，可直接序列化保存）
+type ScoutUnitInfo struct {
+	UnitSnapshot
+	Relation     Relation         // 与侦察方的关系
+	AllianceId   int64            // 所属联盟id
+	Level        int32            // 等级（Detail）
+	ResourceType string           // 资源类型（Detail，资源点和采集中的部队）
+	Amount       int32            // 资源量或部队负重（Detail）
+	Hp           int32            // 生命值（Detail，NPC）
+	Power        int32            // 战力（Detail，NPC）
+	Garrison     []*ScoutGarrison // 驻军（Full，主城）
+}
+
+// ScoutReport 侦察报告：侦察部队到达时目标周围的情报快照
+type ScoutReport struct {
+	ScoutId int64        // 侦察部队id（直接生成报告时为0）
+	Scouter Owner        // 侦察方
+	Level   ScoutLevel   // 侦察等级
+	Time    time.Time    // 侦察时间
+	Center  geo.HexCoord // 侦察中心
+	Radius  int32        // 侦察半径（六边形步数）
+	Units   []*ScoutUnitInfo
+}
+
+// ScoutReportCallback 侦察报告回调，由游戏服保存报告
+type ScoutReportCallback func(troop *TroopUnit, report *ScoutReport)
+
+// NewScoutResolver 创建侦察到达结算器：生成目标周围的侦察报告（不含侦察部队自身）后返回
+func NewScoutResolver(radius int32, level ScoutLevel, callback ScoutReportCallback) MarchResolver {
+	return MarchResolverFunc(func(wm *WorldMap, troop *TroopUnit, target Unit, now time.Time) MarchOutcome {
+		report := wm.GenerateScoutReport(troop.GetOwner(), troop.GetHexCoord(), radius, level, now)
+		report.ScoutId = troop.GetId()
+		report.Units = slices.DeleteFunc(report.Units, func(info *ScoutUnitInfo) bool {
+			return info.Id == troop.GetId()
+		})
+		if callback != nil {
+			callback(troop, report)
+		}
+		return MarchOutcome_Return
+	})
+}
+
+// GenerateScoutReport 生成以 center 为中心、radius 范围内的侦察报告，单位按id排序
+// 六边形网格和矩形网格中的单位都会被收集（只注册在矩形网格中的障碍物等）
+func (wm *WorldMap) GenerateScoutReport(scouter *Owner, center *geo.HexCoord, radius int32, level ScoutLevel, now time.Time) *ScoutReport {
+	report := &ScoutReport{
+		Level:  level,
+		Time:   now,
+		Center: *center,
+		Radius: radius,
+		Units:  make([]*ScoutUnitInfo, 0),
+	}
+	if scouter != nil {
+		report.Scouter = *scouter
+	}
+	if level == ScoutLevel_None {
+		return report
+	}
+
+	units := make(map[int64]Unit)
+	for _, unit := range wm.hexGridMgr.GetUnitsInRadius(center, radius, nil) {
+		units[unit.GetId()] = unit
+	}
+	wm.gridMgr.RangeRectUnits(wm.hexRadiusRect(center, radius), false, nil, func(unit Unit) bool {
+		if _, exists := units[unit.GetId()]; !exists && wm.coordToHex(unit.GetCoord()).DistanceTo(center) <= radius {
+			units[unit.GetId()] = unit
+		}
+		return true
+	})
+
+	for _, unit := range units {
+		report.Units = append(report.Units, wm.scoutUnit(scouter, unit, level))
+	}
+	sort.Slice(report.Units, func(i, j int) bool {
+		return report.Units[i].Id < report.Units[j].Id
+	})
+	return report
+}
+
+// scoutUnit 按侦察等级记录单位信息
+func (wm *WorldMap) scoutUnit(scouter *Owner, unit Unit, level ScoutLevel) *ScoutUnitInfo {
+	hex := unit.GetHexCoord()
+	if hex == nil {
+		hex = wm.coordToHex(unit.GetCoord())
+	}
+	info := &ScoutUnitInfo{
+		UnitSnapshot: *NewUnitSnapshot(unit, hex),
+		Relation:     wm.relations.GetRelation(scouter, unit.GetOwner()),
+		AllianceId:   wm.relations.GetOwnerAlliance(unit.GetOwner()),
+	}
+	if level < ScoutLevel_Detail {
+		return info
+	}
+
+	if leveled, ok := unit.(LevelUnit); ok {
+		info.Level = leveled.GetLevel()
+	}
+	switch u := unit.(type) {
+	case *ResourceUnit:
+		info.ResourceType = u.GetResourceType()
+		info.Amount = u.GetCurrentAmount()
+	case *TroopUnit:
+		info.ResourceType, info.Amount = u.GetLoad()
+	case *NpcUnit:
+		info.Hp = u.GetHp()
+		info.Power = u.GetPower()
+	}
+
+	if level >= ScoutLevel_Full && unit.GetType() == MapUnitType_PlayerCity {
+		info.Garrison = wm.scoutGarrison(hex)
+	}
+	return info
+}
+
+// scoutGarrison 主城所在六边形中未出征的部队
+func (wm *WorldMap) scoutGarrison(hex *geo.HexCoord) []*ScoutGarrison {
+	garrison := make([]*ScoutGarrison, 0)
+	grid := wm.hexGridMgr.GetGrid(hex)
+	if grid == nil {
+		return garrison
+	}
+
+	for _, unit := range grid.GetUnitsByType(MapUnitType_PlayerTroop) {
+		troop, ok := unit.(*TroopUnit)
+		if !ok || troop.IsMarching() {
+			continue
+		}
+		entry := &ScoutGarrison{TroopId: troop.GetId(), ConfigId: troop.GetConfigId()}
+		if owner := troop.GetOwner(); owner != nil {
+			entry.Owner = *owner
+		}
+		_, entry.Load = troop.GetLoad()
+		garrison = append(garrison, entry)
+	}
+	sort.Slice(garrison, func(i, j int) bool {
+		return garrison[i].TroopId < garrison[j].TroopId
+	})
+	return garrison
+}
+
+// hexRadiusRect 六边形范围的矩形包围盒（矩形坐标）
+func (wm *WorldMap) hexRadiusRect(center *geo.HexCoord, radius int32) *geo.Rectangle {
+	layout := wm.hexGridMgr.GetLayout()
+	x, y := layout.HexToWorld(center)
+	extent := (float64(radius) + 1) * 2 * layout.Radius
+	minCoord := wm.worldToCoord(x-extent, y-extent)
+	maxCoord := wm.worldToCoord(x+extent, y+extent)
+	return geo.NewRectangle(minCoord.X, minCoord.Y, maxCoord.X-minCoord.X+1, maxCoord.Y-minCoord.Y+1)
+}
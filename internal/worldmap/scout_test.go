@@ -0,0 +1,71 @@
+package worldmap
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/GooLuck/WorldMap/internal/worldmap/config"
+	"github.com/GooLuck/WorldMap/internal/worldmap/geo"
+)
+
+// TestScoutReport 测试按侦察等级生成侦察报告
+func TestScoutReport(t *testing.T) {
+	wm := NewWorldMap(newTestMapConfig())
+	troopMgr := wm.GetTroopManager()
+
+	cityHex := geo.NewHexCoord(3, 3)
+	wm.AddUnit(&TestUnit{id: 1, coord: *wm.hexToCoord(cityHex), hexCoord: cityHex, unitType: MapUnitType_PlayerCity, owner: NewPlayerOwner(2)})
+	garrison := troopMgr.CreateTroop(1, NewPlayerOwner(2), wm.hexToCoord(cityHex))
+
+	resourceHex := geo.NewHexCoord(4, 3)
+	resource := NewResourceUnit(3, 1, *wm.hexToCoord(resourceHex), &config.EnhancedResourcePointConfig{
+		ResourceType:  "gold",
+		Level:         3,
+		CurrentAmount: 500,
+	})
+	resource.SetHexCoord(resourceHex)
+	wm.AddUnit(resource)
+	farHex := geo.NewHexCoord(6, 0)
+	wm.AddUnit(&TestUnit{id: 4, coord: *wm.hexToCoord(farHex), hexCoord: farHex, unitType: MapUnitType_PlayerCity, owner: NewPlayerOwner(3)})
+
+	now := time.Now()
+	scouter := NewPlayerOwner(1)
+	basic := wm.GenerateScoutReport(scouter, cityHex, 1, ScoutLevel_Basic, now)
+	if len(basic.Units) != 3 {
+		t.Fatalf("侦察单位数量错误：期望 3, 得到 %d", len(basic.Units))
+	}
+	if basic.Units[0].Id != 1 || basic.Units[0].Relation != Relation_Enemy || basic.Units[0].Garrison != nil {
+		t.Error("基础侦察只包含单位和关系")
+	}
+	if basic.Units[1].Id != 3 || basic.Units[1].Amount != 0 {
+		t.Error("基础侦察不应该包含资源量")
+	}
+
+	full := wm.GenerateScoutReport(scouter, cityHex, 1, ScoutLevel_Full, now)
+	city, res := full.Units[0], full.Units[1]
+	if len(city.Garrison) != 1 || city.Garrison[0].TroopId != garrison.GetId() {
+		t.Error("完整侦察应该包含主城驻军")
+	}
+	if res.Id != 3 || res.Amount != 500 || res.Level != 3 || res.ResourceType != "gold" {
+		t.Errorf("资源点信息错误：资源量 %d, 等级 %d", res.Amount, res.Level)
+	}
+
+	// 侦察部队到达后生成报告并返回
+	var report *ScoutReport
+	wm.RegisterMarchResolver(MarchIntent_Scout, NewScoutResolver(1, ScoutLevel_Detail, func(troop *TroopUnit, r *ScoutReport) {
+		report = r
+	}))
+	stepLen := math.Sqrt(3) * wm.GetHexGridManager().GetLayout().Radius
+	scout := troopMgr.CreateTroop(1, scouter, wm.hexToCoord(geo.NewHexCoord(1, 3)))
+	if !troopMgr.DispatchToHex(scout, MarchIntent_Scout, geo.NewHexCoord(2, 3), stepLen, now) {
+		t.Fatal("派遣侦察失败")
+	}
+	wm.Update(now.Add(time.Second))
+	if report == nil || report.ScoutId != scout.GetId() || len(report.Units) != 2 {
+		t.Fatal("侦察部队到达后应该生成报告")
+	}
+	if !scout.IsReturning() {
+		t.Error("侦察后部队应该返回")
+	}
+}
@@ -0,0 +1,295 @@
+package worldmap
+
+import (
+	"container/heap"
+
+	"github.com/GooLuck/WorldMap/internal/worldmap/config"
+	"github.com/GooLuck/WorldMap/internal/worldmap/geo"
+)
+
+// squareCell 正方形格子（GridManager 的网格索引）
+type squareCell struct {
+	col int32
+	row int32
+}
+
+// 正方形格子的八个相邻方向
+var squareDirections = [8]squareCell{
+	{1, 0}, {1, -1}, {0, -1}, {-1, -1}, {-1, 0}, {-1, 1}, {0, 1}, {1, 1},
+}
+
+// distanceTo 两个格子之间的步数（八方向移动，切比雪夫距离）
+func (c squareCell) distanceTo(other squareCell) int32 {
+	return max(absInt32(c.col-other.col), absInt32(c.row-other.row))
+}
+
+// squareTopology 正方形拓扑，以 GridManager 的网格为格子，可以八方向移动
+type squareTopology struct {
+	worldMap *WorldMap
+	gridMgr  *GridManager
+	terrains map[squareCell]TerrainType // 格子地形（未设置的格子为平原），正方形地图上行军、NPC和迷雾也使用这份地形
+}
+
+func newSquareTopology(worldMap *WorldMap) *squareTopology {
+	return &squareTopology{
+		worldMap: worldMap,
+		gridMgr:  worldMap.gridMgr,
+		terrains: make(map[squareCell]TerrainType),
+	}
+}
+
+func (t *squareTopology) GetType() config.TopologyType {
+	return config.TopologyType_Square
+}
+
+// cellOf 坐标所在格子
+func (t *squareTopology) cellOf(coord *geo.Coord) squareCell {
+	return squareCell{
+		col: floorDiv(coord.X, t.gridMgr.mapSize.GridWidth),
+		row: floorDiv(coord.Y, t.gridMgr.mapSize.GridHeight),
+	}
+}
+
+// contains 格子是否在地图内
+func (t *squareTopology) contains(cell squareCell) bool {
+	return cell.col >= 0 && cell.col < t.gridMgr.gridCols && cell.row >= 0 && cell.row < t.gridMgr.gridRows
+}
+
+// center 格子中心（地图边缘不完整的格子限制在地图范围内）
+func (t *squareTopology) center(cell squareCell) *geo.Coord {
+	mapSize := t.gridMgr.mapSize
+	return geo.NewCoord(
+		min(cell.col*mapSize.GridWidth+mapSize.GridWidth/2, mapSize.Width-1),
+		min(cell.row*mapSize.GridHeight+mapSize.GridHeight/2, mapSize.Height-1),
+	)
+}
+
+// rect 格子的矩形范围（地图边缘不完整的格子限制在地图范围内）
+func (t *squareTopology) rect(cell squareCell) *geo.Rectangle {
+	mapSize := t.gridMgr.mapSize
+	x, y := cell.col*mapSize.GridWidth, cell.row*mapSize.GridHeight
+	return geo.NewRectangle(x, y, min(mapSize.GridWidth, mapSize.Width-x), min(mapSize.GridHeight, mapSize.Height-y))
+}
+
+// index 格子在 GridManager 中的索引
+func (t *squareTopology) index(cell squareCell) int32 {
+	return cell.row*t.gridMgr.gridCols + cell.col
+}
+
+func (t *squareTopology) CellOf(coord *geo.Coord) *geo.Coord {
+	return t.center(t.cellOf(coord))
+}
+
+func (t *squareTopology) Distance(a, b *geo.Coord) int32 {
+	return t.cellOf(a).distanceTo(t.cellOf(b))
+}
+
+func (t *squareTopology) GetNeighbors(coord *geo.Coord) []*geo.Coord {
+	cell := t.cellOf(coord)
+	neighbors := make([]*geo.Coord, 0, len(squareDirections))
+	for _, direction := range squareDirections {
+		neighbor := squareCell{col: cell.col + direction.col, row: cell.row + direction.row}
+		if t.contains(neighbor) {
+			neighbors = append(neighbors, t.center(neighbor))
+		}
+	}
+	return neighbors
+}
+
+// rangeRing 遍历与中心格子距离为 ring 的一圈格子中已创建的网格
+func (t *squareTopology) rangeRing(center squareCell, ring int32, f func(grid *Grid)) {
+	for row := max(center.row-ring, 0); row <= min(center.row+ring, t.gridMgr.gridRows-1); row++ {
+		for col := max(center.col-ring, 0); col <= min(center.col+ring, t.gridMgr.gridCols-1); col++ {
+			cell := squareCell{col: col, row: row}
+			if cell.distanceTo(center) != ring {
+				continue
+			}
+			if grid := t.gridMgr.grids[t.index(cell)]; grid != nil {
+				f(grid)
+			}
+		}
+	}
+}
+
+// maxRing 中心格子到地图最远格子的步数
+func (t *squareTopology) maxRing(center squareCell) int32 {
+	return max(center.col, t.gridMgr.gridCols-1-center.col, center.row, t.gridMgr.gridRows-1-center.row, 0)
+}
+
+func (t *squareTopology) GetUnitsInRange(center *geo.Coord, radius int32, filter *UnitFilter) []Unit {
+	retUnits := make([]Unit, 0)
+	var visited footprintVisited
+	cell := t.cellOf(center)
+	for ring := int32(0); ring <= min(radius, t.maxRing(cell)); ring++ {
+		t.rangeRing(cell, ring, func(grid *Grid) {
+			for _, unit := range grid.GetUnits() {
+				if filter.Match(unit) && visited.firstVisit(unit) {
+					retUnits = append(retUnits, unit)
+				}
+			}
+		})
+	}
+	return retUnits
+}
+
+func (t *squareTopology) GetNearestUnits(center *geo.Coord, k int32, maxDistance int32, filter *UnitFilter) []Unit {
+	if k <= 0 {
+		return make([]Unit, 0)
+	}
+	collector := newNearestCollector(k, float64(maxDistance))
+	var visited footprintVisited
+	cell := t.cellOf(center)
+	for ring := int32(0); ring <= t.maxRing(cell); ring++ {
+		t.rangeRing(cell, ring, func(grid *Grid) {
+			for _, unit := range grid.GetUnits() {
+				if filter.Match(unit) && visited.firstVisit(unit) {
+					collector.add(unit, float64(ring))
+				}
+			}
+		})
+		if collector.done(float64(ring + 1)) {
+			break
+		}
+	}
+	return collector.result()
+}
+
+// squarePathNode 正方形格子A*寻路节点
+type squarePathNode struct {
+	cell   squareCell
+	gCost  float64
+	fCost  float64
+	parent *squarePathNode
+}
+
+// squarePathHeap 按 fCost 排序的优先队列
+type squarePathHeap []*squarePathNode
+
+func (h squarePathHeap) Len() int           { return len(h) }
+func (h squarePathHeap) Less(i, j int) bool { return h[i].fCost < h[j].fCost }
+func (h squarePathHeap) Swap(i, j int)      { h[i], h[j] = h[j], h[i] }
+func (h *squarePathHeap) Push(x interface{}) {
+	*h = append(*h, x.(*squarePathNode))
+}
+func (h *squarePathHeap) Pop() interface{} {
+	old := *h
+	n := len(old)
+	node := old[n-1]
+	old[n-1] = nil
+	*h = old[:n-1]
+	return node
+}
+
+// terrainOf 格子的地形
+func (t *squareTopology) terrainOf(cell squareCell) TerrainType {
+	if !t.contains(cell) {
+		return TerrainType_None
+	}
+	if terrain, exists := t.terrains[cell]; exists {
+		return terrain
+	}
+	return TerrainType_Plain
+}
+
+func (t *squareTopology) SetTerrain(coord *geo.Coord, terrainType TerrainType) {
+	if cell := t.cellOf(coord); t.contains(cell) {
+		t.terrains[cell] = terrainType
+	}
+}
+
+func (t *squareTopology) GetTerrain(coord *geo.Coord) TerrainType {
+	return t.terrainOf(t.cellOf(coord))
+}
+
+func (t *squareTopology) FindPath(from, to *geo.Coord) []*geo.Coord {
+	return t.findPath(nil, from, to)
+}
+
+func (t *squareTopology) FindMarchPath(owner *Owner, from, to *geo.Coord) []*geo.Coord {
+	return t.findPath(owner, from, to)
+}
+
+// hasUnit 格子的网格上是否有满足过滤条件的单位
+func (t *squareTopology) hasUnit(cell squareCell, filter *UnitFilter) bool {
+	grid := t.gridMgr.grids[t.index(cell)]
+	if grid == nil {
+		return false
+	}
+	for _, unit := range grid.GetUnits() {
+		if filter.Match(unit) {
+			return true
+		}
+	}
+	return false
+}
+
+// findPath A*寻路，成本模型与六边形的行军寻路相同：每一步的成本为进入的格子的地形移动成本系数，
+// 不可通行的地形和禁止行军的障碍物不可进入，owner 不为nil时与其敌对的单位占据的格子不可进入（终点除外）
+func (t *squareTopology) findPath(owner *Owner, from, to *geo.Coord) []*geo.Coord {
+	start, end := t.cellOf(from), t.cellOf(to)
+	if !t.contains(start) || !t.contains(end) {
+		return nil
+	}
+
+	blockingFilter := t.worldMap.newMarchBlockingFilter(owner)
+	minStepCost := minPassableMoveCost()
+	estimate := func(cell squareCell) float64 {
+		return float64(cell.distanceTo(end)) * minStepCost
+	}
+
+	openSet := &squarePathHeap{{cell: start, fCost: estimate(start)}}
+	bestCost := map[squareCell]float64{start: 0}
+	closedSet := make(map[squareCell]bool)
+	for openSet.Len() > 0 {
+		current := heap.Pop(openSet).(*squarePathNode)
+		if closedSet[current.cell] {
+			continue
+		}
+		closedSet[current.cell] = true
+
+		if current.cell == end {
+			path := make([]*geo.Coord, 0)
+			for node := current; node != nil; node = node.parent {
+				path = append(path, t.center(node.cell))
+			}
+			for i, j := 0, len(path)-1; i < j; i, j = i+1, j-1 {
+				path[i], path[j] = path[j], path[i]
+			}
+			return path
+		}
+
+		for _, direction := range squareDirections {
+			neighbor := squareCell{col: current.cell.col + direction.col, row: current.cell.row + direction.row}
+			if !t.contains(neighbor) || closedSet[neighbor] {
+				continue
+			}
+			cost, passable := terrainPathCost(GetTerrainConfig(t.terrainOf(neighbor)))
+			if !passable || !t.worldMap.canMarchIntoCell(t.center(neighbor), t.rect(neighbor)) {
+				continue
+			}
+			if blockingFilter != nil && neighbor != end && t.hasUnit(neighbor, blockingFilter) {
+				continue
+			}
+			gCost := current.gCost + cost
+			if cost, exists := bestCost[neighbor]; exists && cost <= gCost {
+				continue
+			}
+			bestCost[neighbor] = gCost
+			heap.Push(openSet, &squarePathNode{
+				cell:   neighbor,
+				gCost:  gCost,
+				fCost:  gCost + estimate(neighbor),
+				parent: current,
+			})
+		}
+	}
+	return nil
+}
+
+func (t *squareTopology) MoveUnit(unit Unit, coord *geo.Coord) bool {
+	cell := t.cellOf(coord)
+	if !t.contains(cell) {
+		return false
+	}
+	return t.worldMap.MoveUnit(unit, t.center(cell))
+}
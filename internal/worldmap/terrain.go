@@ -1,6 +1,8 @@
 package worldmap
 
 import (
+	"math"
+
 	"github.com/GooLuck/WorldMap/internal/worldmap/geo"
 )
 
@@ -25,6 +27,7 @@ type TerrainConfig struct {
 	MoveCost     float32     // 移动成本系数（1.0 为正常，越高越难通行）
 	DefenseBonus float32     // 防御加成（0.0-1.0）
 	Visible      bool        // 是否可见（用于战争迷雾）
+	VisionBonus  int32       // 视野加成（位于该地形的单位视野半径增减）
 	Passable     bool        // 是否可通行
 }
 
@@ -49,6 +52,7 @@ var DefaultTerrainConfigs = map[TerrainType]*TerrainConfig{
 		MoveCost:     1.5,
 		DefenseBonus: 0.3,
 		Visible:      true,
+		VisionBonus:  -1,
 		Passable:     true,
 	},
 	TerrainType_Mountain: {
@@ -56,6 +60,7 @@ var DefaultTerrainConfigs = map[TerrainType]*TerrainConfig{
 		MoveCost:     3.0,
 		DefenseBonus: 0.5,
 		Visible:      true,
+		VisionBonus:  1,
 		Passable:     true,
 	},
 	TerrainType_Swamp: {
@@ -106,11 +111,11 @@ func GetTerrainConfig(terrainType TerrainType) *TerrainConfig {
 // TerrainMap 地形地图
 type TerrainMap struct {
 	terrains map[uint64]TerrainType // hex hash -> terrain type
-	bounds   *geo.HexRectangle      // 边界范围
+	bounds   *geo.HexMapBounds      // 边界范围
 }
 
 // NewTerrainMap 创建地形地图
-func NewTerrainMap(bounds *geo.HexRectangle) *TerrainMap {
+func NewTerrainMap(bounds *geo.HexMapBounds) *TerrainMap {
 	return &TerrainMap{
 		terrains: make(map[uint64]TerrainType),
 		bounds:   bounds,
@@ -151,6 +156,11 @@ func (tm *TerrainMap) IsPassable(hex *geo.HexCoord) bool {
 	return tm.GetTerrainConfig(hex).Passable
 }
 
+// GetVisionBonus 获取六边形视野加成
+func (tm *TerrainMap) GetVisionBonus(hex *geo.HexCoord) int32 {
+	return tm.GetTerrainConfig(hex).VisionBonus
+}
+
 // GetDefenseBonus 获取六边形防御加成
 func (tm *TerrainMap) GetDefenseBonus(hex *geo.HexCoord) float32 {
 	return tm.GetTerrainConfig(hex).DefenseBonus
@@ -167,9 +177,46 @@ func (tm *TerrainMap) TerrainCostFunc() TerrainCostFunc {
 	}
 }
 
+// PathCostFunc 创建地形寻路成本函数：按进入的六边形的移动成本系数计算，不可通行的地形不可进入
+func (tm *TerrainMap) PathCostFunc() PathCostFunc {
+	return func(from, to *geo.HexCoord) (float64, bool) {
+		return terrainPathCost(tm.GetTerrainConfig(to))
+	}
+}
+
+// terrainPathCost 进入该地形的一步的寻路成本，不可通行的地形不可进入
+func terrainPathCost(config *TerrainConfig) (float64, bool) {
+	if !config.Passable {
+		return 0, false
+	}
+	return float64(config.MoveCost), true
+}
+
+// PathCostModel 创建地形寻路成本模型
+func (tm *TerrainMap) PathCostModel() *PathCostModel {
+	return &PathCostModel{
+		StepCost:    tm.PathCostFunc(),
+		MinStepCost: minPassableMoveCost(),
+	}
+}
+
+// minPassableMoveCost 可通行地形中最小的移动成本系数
+func minPassableMoveCost() float64 {
+	minCost := math.Inf(1)
+	for _, config := range DefaultTerrainConfigs {
+		if config.Passable {
+			minCost = math.Min(minCost, float64(config.MoveCost))
+		}
+	}
+	if math.IsInf(minCost, 1) {
+		return 0
+	}
+	return minCost
+}
+
 // FindPathWithTerrain 考虑地形的路径查找
 func (tm *TerrainMap) FindPathWithTerrain(hgm *HexGridManager, start, end *geo.HexCoord) []*geo.HexCoord {
-	return hgm.FindPath(start, end, tm.TerrainCostFunc())
+	return hgm.FindPathWithCost(start, end, tm.PathCostModel()).GetPath()
 }
 
 // TerrainGenerator 地形生成器
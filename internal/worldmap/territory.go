@@ -0,0 +1,319 @@
+package worldmap
+
+import (
+	"sort"
+
+	"github.com/GooLuck/WorldMap/internal/worldmap/geo"
+)
+
+// TerritoryBuildingType 产生领土的建筑类型
+type TerritoryBuildingType int32
+
+const (
+	TerritoryBuildingType_None     TerritoryBuildingType = iota
+	TerritoryBuildingType_City                           // 联盟成员主城
+	TerritoryBuildingType_Flag                           // 联盟旗帜（必须与已有领土相邻）
+	TerritoryBuildingType_Fortress                       // 联盟要塞
+)
+
+// TerritorySource 领土来源建筑
+type TerritorySource struct {
+	BuildingId   int64                 // 建筑id
+	AllianceId   int64                 // 所属联盟id
+	BuildingType TerritoryBuildingType // 建筑类型
+	Hex          *geo.HexCoord         // 建筑所在六边形
+	Radius       int32                 // 领土半径（六边形步数）
+	seq          int64                 // 放置顺序，用于争夺时判定先来后到
+}
+
+// TerritoryManager 联盟领土管理器
+// 争夺规则：同一六边形被多个联盟的建筑覆盖时，距离最近的建筑所属联盟获得；距离相同时先放置的建筑获得
+type TerritoryManager struct {
+	worldMap      *WorldMap
+	sources       map[int64]*TerritorySource         // 建筑id -> 领土来源
+	coverage      map[uint64][]*TerritorySource      // 六边形 -> 覆盖该六边形的所有来源
+	owners        map[uint64]*TerritorySource        // 六边形 -> 获得该六边形的来源
+	allianceHexes map[int64]map[uint64]*geo.HexCoord // 联盟id -> 领土六边形
+	cities        map[int64]Unit                     // 地图上的玩家主城（单位id -> 主城），主城的领土来源建筑id为单位id
+	nextSeq       int64
+}
+
+// NewTerritoryManager 创建领土管理器
+func NewTerritoryManager(worldMap *WorldMap) *TerritoryManager {
+	return &TerritoryManager{
+		worldMap:      worldMap,
+		sources:       make(map[int64]*TerritorySource),
+		coverage:      make(map[uint64][]*TerritorySource),
+		owners:        make(map[uint64]*TerritorySource),
+		allianceHexes: make(map[int64]map[uint64]*geo.HexCoord),
+		cities:        make(map[int64]Unit),
+	}
+}
+
+// GetBuildingRadius 获取建筑类型的领土半径
+func (tm *TerritoryManager) GetBuildingRadius(buildingType TerritoryBuildingType) int32 {
+	config := tm.worldMap.GetConfig().Territory
+	switch buildingType {
+	case TerritoryBuildingType_City:
+		return config.CityRadius
+	case TerritoryBuildingType_Flag:
+		return config.FlagRadius
+	case TerritoryBuildingType_Fortress:
+		return config.FortressRadius
+	}
+	return 0
+}
+
+// AddBuilding 添加产生领土的建筑，半径取配置
+func (tm *TerritoryManager) AddBuilding(buildingId, allianceId int64, buildingType TerritoryBuildingType, hex *geo.HexCoord) bool {
+	return tm.AddBuildingWithRadius(buildingId, allianceId, buildingType, hex, tm.GetBuildingRadius(buildingType))
+}
+
+// AddBuildingWithRadius 添加产生领土的建筑并指定半径（建筑升级等场景）
+func (tm *TerritoryManager) AddBuildingWithRadius(buildingId, allianceId int64, buildingType TerritoryBuildingType, hex *geo.HexCoord, radius int32) bool {
+	if allianceId == 0 || radius < 0 || !tm.worldMap.hexGridMgr.Contains(hex) {
+		return false
+	}
+	if _, exists := tm.sources[buildingId]; exists {
+		return false
+	}
+	if buildingType == TerritoryBuildingType_Flag && !tm.CanPlaceFlag(allianceId, hex) {
+		return false
+	}
+
+	tm.nextSeq++
+	source := &TerritorySource{
+		BuildingId:   buildingId,
+		AllianceId:   allianceId,
+		BuildingType: buildingType,
+		Hex:          hex.Clone(),
+		Radius:       radius,
+		seq:          tm.nextSeq,
+	}
+	tm.sources[buildingId] = source
+
+	for _, grid := range tm.worldMap.hexGridMgr.GetHexGridsInRadius(hex, radius) {
+		key := grid.GetCoord().Hash()
+		tm.coverage[key] = append(tm.coverage[key], source)
+		tm.refreshOwner(grid.GetCoord())
+	}
+	return true
+}
+
+// RemoveBuilding 移除建筑，被覆盖的六边形重新判定归属
+func (tm *TerritoryManager) RemoveBuilding(buildingId int64) {
+	source, exists := tm.sources[buildingId]
+	if !exists {
+		return
+	}
+	delete(tm.sources, buildingId)
+
+	for _, grid := range tm.worldMap.hexGridMgr.GetHexGridsInRadius(source.Hex, source.Radius) {
+		key := grid.GetCoord().Hash()
+		covers := tm.coverage[key]
+		for i, cover := range covers {
+			if cover == source {
+				covers = append(covers[:i], covers[i+1:]...)
+				break
+			}
+		}
+		if len(covers) == 0 {
+			delete(tm.coverage, key)
+		} else {
+			tm.coverage[key] = covers
+		}
+		tm.refreshOwner(grid.GetCoord())
+	}
+}
+
+// OnCityAdded 玩家主城加入地图，已加入联盟的玩家的主城为所属联盟产生领土（半径取配置）
+func (tm *TerritoryManager) OnCityAdded(city Unit) {
+	owner := city.GetOwner()
+	if owner == nil || owner.Type != OwnerType_Player {
+		return
+	}
+	tm.cities[city.GetId()] = city
+	tm.addCity(city)
+}
+
+// OnCityRemoved 玩家主城离开地图，移除它产生的领土
+func (tm *TerritoryManager) OnCityRemoved(city Unit) {
+	if _, exists := tm.cities[city.GetId()]; !exists {
+		return
+	}
+	delete(tm.cities, city.GetId())
+	tm.RemoveBuilding(city.GetId())
+}
+
+// OnCityMoved 迁城后领土跟随主城
+func (tm *TerritoryManager) OnCityMoved(city Unit) {
+	if _, exists := tm.cities[city.GetId()]; !exists {
+		return
+	}
+	tm.RemoveBuilding(city.GetId())
+	tm.addCity(city)
+}
+
+// OnRelationChanged 玩家的联盟变化时，其主城的领土转到新联盟（退出联盟后不再产生领土）
+func (tm *TerritoryManager) OnRelationChanged(change *RelationChange) {
+	if change.PlayerId == 0 {
+		return
+	}
+	tm.refreshCities(func(city Unit) bool {
+		return city.GetOwner().Id == change.PlayerId
+	})
+}
+
+// refreshCities 按所有者当前的联盟重新放置满足条件的主城领土（关系注册表替换时重新放置全部主城）
+func (tm *TerritoryManager) refreshCities(match func(city Unit) bool) {
+	cities := make(map[int64]Unit)
+	for id, city := range tm.cities {
+		if match == nil || match(city) {
+			cities[id] = city
+		}
+	}
+	// 按id顺序重新放置，争夺时的先后顺序稳定
+	for _, id := range sortedUnitIds(cities) {
+		tm.RemoveBuilding(id)
+		tm.addCity(cities[id])
+	}
+}
+
+// addCity 按主城所有者当前的联盟添加主城领土
+func (tm *TerritoryManager) addCity(city Unit) {
+	allianceId := tm.worldMap.relations.GetPlayerAlliance(city.GetOwner().Id)
+	if allianceId == 0 || city.GetHexCoord() == nil {
+		return
+	}
+	tm.AddBuilding(city.GetId(), allianceId, TerritoryBuildingType_City, city.GetHexCoord())
+}
+
+// GetBuilding 获取领土来源建筑
+func (tm *TerritoryManager) GetBuilding(buildingId int64) *TerritorySource {
+	return tm.sources[buildingId]
+}
+
+// refreshOwner 按争夺规则重新判定六边形归属
+func (tm *TerritoryManager) refreshOwner(hex *geo.HexCoord) {
+	key := hex.Hash()
+	var winner *TerritorySource
+	for _, source := range tm.coverage[key] {
+		if winner == nil || tm.isStronger(source, winner, hex) {
+			winner = source
+		}
+	}
+
+	old := tm.owners[key]
+	if old != nil && (winner == nil || old.AllianceId != winner.AllianceId) {
+		delete(tm.allianceHexes[old.AllianceId], key)
+		if len(tm.allianceHexes[old.AllianceId]) == 0 {
+			delete(tm.allianceHexes, old.AllianceId)
+		}
+	}
+
+	if winner == nil {
+		delete(tm.owners, key)
+		return
+	}
+	tm.owners[key] = winner
+	hexes, exists := tm.allianceHexes[winner.AllianceId]
+	if !exists {
+		hexes = make(map[uint64]*geo.HexCoord)
+		tm.allianceHexes[winner.AllianceId] = hexes
+	}
+	hexes[key] = hex
+}
+
+// isStronger 来源 a 对六边形的控制是否强于 b：距离近者优先，距离相同先放置者优先
+func (tm *TerritoryManager) isStronger(a, b *TerritorySource, hex *geo.HexCoord) bool {
+	distA, distB := a.Hex.DistanceTo(hex), b.Hex.DistanceTo(hex)
+	if distA != distB {
+		return distA < distB
+	}
+	return a.seq < b.seq
+}
+
+// GetOwner 获取六边形所属联盟id，0表示无主
+func (tm *TerritoryManager) GetOwner(hex *geo.HexCoord) int64 {
+	if source, exists := tm.owners[hex.Hash()]; exists {
+		return source.AllianceId
+	}
+	return 0
+}
+
+// GetOwnerByCoord 获取矩形坐标所属联盟id
+func (tm *TerritoryManager) GetOwnerByCoord(coord *geo.Coord) int64 {
+	return tm.GetOwner(tm.worldMap.coordToHex(coord))
+}
+
+// IsInTerritory 六边形是否属于指定联盟
+func (tm *TerritoryManager) IsInTerritory(allianceId int64, hex *geo.HexCoord) bool {
+	return allianceId != 0 && tm.GetOwner(hex) == allianceId
+}
+
+// CanBuild 联盟成员是否可以在六边形建造（无主或属于本联盟）
+func (tm *TerritoryManager) CanBuild(allianceId int64, hex *geo.HexCoord) bool {
+	owner := tm.GetOwner(hex)
+	return owner == 0 || owner == allianceId
+}
+
+// CanPlaceFlag 是否可以放置旗帜：不能在其他联盟领土内，且必须位于本联盟领土内或与之相邻
+func (tm *TerritoryManager) CanPlaceFlag(allianceId int64, hex *geo.HexCoord) bool {
+	if allianceId == 0 || !tm.worldMap.hexGridMgr.Contains(hex) {
+		return false
+	}
+
+	owner := tm.GetOwner(hex)
+	if owner == allianceId {
+		return true
+	}
+	if owner != 0 {
+		return false
+	}
+	for _, neighbor := range hex.GetAllNeighbors() {
+		if tm.GetOwner(neighbor) == allianceId {
+			return true
+		}
+	}
+	return false
+}
+
+// GetTerritorySize 获取联盟领土六边形数量
+func (tm *TerritoryManager) GetTerritorySize(allianceId int64) int32 {
+	return int32(len(tm.allianceHexes[allianceId]))
+}
+
+// GetTerritoryHexes 获取联盟所有领土六边形（按坐标排序）
+func (tm *TerritoryManager) GetTerritoryHexes(allianceId int64) []*geo.HexCoord {
+	hexes := make([]*geo.HexCoord, 0, len(tm.allianceHexes[allianceId]))
+	for _, hex := range tm.allianceHexes[allianceId] {
+		hexes = append(hexes, hex)
+	}
+	sortHexes(hexes)
+	return hexes
+}
+
+// GetBorderHexes 获取联盟领土的边界六边形：至少有一个地图内的邻居不属于该联盟
+func (tm *TerritoryManager) GetBorderHexes(allianceId int64) []*geo.HexCoord {
+	border := make([]*geo.HexCoord, 0)
+	for _, hex := range tm.allianceHexes[allianceId] {
+		for _, neighbor := range hex.GetAllNeighbors() {
+			if tm.worldMap.hexGridMgr.Contains(neighbor) && tm.GetOwner(neighbor) != allianceId {
+				border = append(border, hex)
+				break
+			}
+		}
+	}
+	sortHexes(border)
+	return border
+}
+
+// sortHexes 按 (q, r) 排序，保证查询结果稳定
+func sortHexes(hexes []*geo.HexCoord) {
+	sort.Slice(hexes, func(i, j int) bool {
+		if hexes[i].Q != hexes[j].Q {
+			return hexes[i].Q < hexes[j].Q
+		}
+		return hexes[i].R < hexes[j].R
+	})
+}
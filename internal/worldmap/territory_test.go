@@ -0,0 +1,143 @@
+package worldmap
+
+import (
+	"testing"
+
+	"github.com/GooLuck/WorldMap/internal/worldmap/geo"
+)
+
+// TestTerritoryContest 测试领土计算和争夺规则
+func TestTerritoryContest(t *testing.T) {
+	mapConfig := newTestMapConfig()
+	mapConfig.Territory.CityRadius = 2
+	mapConfig.Territory.FlagRadius = 1
+	wm := NewWorldMap(mapConfig)
+	territoryMgr := wm.GetTerritoryManager()
+
+	if !territoryMgr.AddBuilding(1, 1, TerritoryBuildingType_City, geo.NewHexCoord(1, 2)) ||
+		!territoryMgr.AddBuilding(2, 2, TerritoryBuildingType_City, geo.NewHexCoord(4, 2)) {
+		t.Fatal("添加主城领土失败")
+	}
+
+	cases := []struct {
+		hex      *geo.HexCoord
+		expected int64
+	}{
+		{geo.NewHexCoord(2, 2), 1}, // 距离联盟1更近
+		{geo.NewHexCoord(3, 2), 2}, // 距离联盟2更近
+		{geo.NewHexCoord(3, 1), 1}, // 距离相同，先放置的联盟1获得
+		{geo.NewHexCoord(6, 5), 0}, // 无人覆盖
+	}
+	for _, c := range cases {
+		if got := territoryMgr.GetOwner(c.hex); got != c.expected {
+			t.Errorf("六边形 %s 归属错误：期望 %d, 得到 %d", c.hex, c.expected, got)
+		}
+	}
+	if got := territoryMgr.GetOwnerByCoord(wm.hexToCoord(geo.NewHexCoord(2, 2))); got != 1 {
+		t.Errorf("坐标归属错误：期望 1, 得到 %d", got)
+	}
+
+	total := territoryMgr.GetTerritorySize(1) + territoryMgr.GetTerritorySize(2)
+	covered := make(map[uint64]bool)
+	for _, center := range []*geo.HexCoord{geo.NewHexCoord(1, 2), geo.NewHexCoord(4, 2)} {
+		for _, grid := range wm.GetHexGridManager().GetHexGridsInRadius(center, 2) {
+			covered[grid.GetCoord().Hash()] = true
+		}
+	}
+	if int(total) != len(covered) {
+		t.Errorf("领土总数错误：期望 %d, 得到 %d", len(covered), total)
+	}
+
+	// 移除联盟1主城后，争夺的六边形归联盟2
+	territoryMgr.RemoveBuilding(1)
+	if got := territoryMgr.GetOwner(geo.NewHexCoord(3, 1)); got != 2 {
+		t.Errorf("移除后归属错误：期望 2, 得到 %d", got)
+	}
+	if territoryMgr.GetTerritorySize(1) != 0 {
+		t.Error("移除后联盟1不应该有领土")
+	}
+}
+
+// TestTerritoryFlagAndBorder 测试旗帜相邻要求和边界查询
+func TestTerritoryFlagAndBorder(t *testing.T) {
+	mapConfig := newTestMapConfig()
+	mapConfig.Territory.CityRadius = 1
+	mapConfig.Territory.FlagRadius = 1
+	wm := NewWorldMap(mapConfig)
+	territoryMgr := wm.GetTerritoryManager()
+
+	territoryMgr.AddBuilding(1, 1, TerritoryBuildingType_City, geo.NewHexCoord(1, 2))
+	if size := territoryMgr.GetTerritorySize(1); size != 7 {
+		t.Errorf("领土大小错误：期望 7, 得到 %d", size)
+	}
+	border := territoryMgr.GetBorderHexes(1)
+	if len(border) != 6 {
+		t.Errorf("边界数量错误：期望 6, 得到 %d", len(border))
+	}
+	for _, hex := range border {
+		if hex.Equal(geo.NewHexCoord(1, 2)) {
+			t.Error("中心六边形不应该是边界")
+		}
+	}
+
+	// 与领土不相邻的旗帜不能放置
+	if territoryMgr.AddBuilding(10, 1, TerritoryBuildingType_Flag, geo.NewHexCoord(5, 2)) {
+		t.Error("不相邻的旗帜不应该可以放置")
+	}
+	// (3,2) 与领土边界 (2,2) 相邻
+	if !territoryMgr.AddBuilding(11, 1, TerritoryBuildingType_Flag, geo.NewHexCoord(3, 2)) {
+		t.Fatal("相邻的旗帜应该可以放置")
+	}
+	if territoryMgr.GetOwner(geo.NewHexCoord(4, 2)) != 1 {
+		t.Error("旗帜应该扩展领土")
+	}
+
+	// 其他联盟不能在本联盟领土内放旗，也不能建造
+	if territoryMgr.CanPlaceFlag(2, geo.NewHexCoord(2, 2)) || territoryMgr.CanBuild(2, geo.NewHexCoord(2, 2)) {
+		t.Error("其他联盟不应该可以在领土内放旗或建造")
+	}
+	if !territoryMgr.CanBuild(1, geo.NewHexCoord(2, 2)) || !territoryMgr.CanBuild(2, geo.NewHexCoord(6, 5)) {
+		t.Error("本联盟领土和无主六边形应该可以建造")
+	}
+}
+
+// TestTerritoryFromCities 测试主城加入、迁城、移除和玩家更换联盟时领土自动更新
+func TestTerritoryFromCities(t *testing.T) {
+	mapConfig := newTestMapConfig()
+	mapConfig.Territory.CityRadius = 1
+	wm := NewWorldMap(mapConfig)
+	territoryMgr := wm.GetTerritoryManager()
+	registry := wm.GetRelationRegistry()
+	registry.SetPlayerAlliance(1, 100)
+
+	cityHex := geo.NewHexCoord(2, 2)
+	city := &TestUnit{id: 1, coord: *wm.hexToCoord(cityHex), unitType: MapUnitType_PlayerCity, owner: NewPlayerOwner(1)}
+	wm.AddUnit(city)
+	if territoryMgr.GetOwner(cityHex) != 100 || territoryMgr.GetTerritorySize(100) != 7 {
+		t.Fatalf("主城加入后应该产生领土：得到 %d", territoryMgr.GetTerritorySize(100))
+	}
+
+	// 更换联盟后领土转到新联盟
+	registry.SetPlayerAlliance(1, 200)
+	if territoryMgr.GetTerritorySize(100) != 0 || territoryMgr.GetOwner(cityHex) != 200 || territoryMgr.GetTerritorySize(200) != 7 {
+		t.Error("更换联盟后领土应该转到新联盟")
+	}
+	registry.SetPlayerAlliance(1, 0)
+	if territoryMgr.GetOwner(cityHex) != 0 || territoryMgr.GetTerritorySize(200) != 0 {
+		t.Error("退出联盟后不应该产生领土")
+	}
+	registry.SetPlayerAlliance(1, 100)
+
+	// 迁城后领土跟随主城
+	newHex := geo.NewHexCoord(5, 2)
+	wm.MoveUnitToHex(city, newHex)
+	if territoryMgr.GetOwner(cityHex) != 0 || territoryMgr.GetOwner(newHex) != 100 || territoryMgr.GetTerritorySize(100) != 7 {
+		t.Error("迁城后领土应该跟随主城")
+	}
+
+	// 移除主城后领土消失
+	wm.RemoveUnit(city)
+	if territoryMgr.GetTerritorySize(100) != 0 {
+		t.Errorf("主城移除后领土大小错误：期望 0, 得到 %d", territoryMgr.GetTerritorySize(100))
+	}
+}
@@ -0,0 +1,112 @@
+package worldmap
+
+import (
+	"github.com/GooLuck/WorldMap/internal/worldmap/config"
+	"github.com/GooLuck/WorldMap/internal/worldmap/geo"
+)
+
+// Topology 地图拓扑：正方形格子和六边形格子的统一空间接口
+// 格子用矩形坐标表示（格子中心），距离、范围、寻路和地形都按格子计算，只通过这个接口做查询和移动的逻辑可以运行在两种地图上
+// 两种拓扑使用同一套寻路成本模型：地形（按拓扑的格子保存，行军、NPC和迷雾也通过拓扑读取）、禁止行军的障碍物和敌对单位阻挡
+// 行军（TroopManager）、迷雾、领土和 NPC 行为的移动仍在内部的六边形网格上进行，把它们迁移到正方形格子需要单独的改动
+type Topology interface {
+	GetType() config.TopologyType                                                             // 拓扑类型
+	CellOf(coord *geo.Coord) *geo.Coord                                                       // 坐标所在格子的中心
+	Distance(a, b *geo.Coord) int32                                                           // 两个坐标所在格子之间的步数
+	GetNeighbors(coord *geo.Coord) []*geo.Coord                                               // 相邻格子的中心（只包含地图内的格子）
+	GetUnitsInRange(center *geo.Coord, radius int32, filter *UnitFilter) []Unit               // 步数范围内的单位
+	GetNearestUnits(center *geo.Coord, k int32, maxDistance int32, filter *UnitFilter) []Unit // 最近的k个单位，按步数排序（maxDistance<=0表示不限）
+	FindPath(from, to *geo.Coord) []*geo.Coord                                                // 寻路，避开不可通行的地形和禁止行军的障碍物，返回经过的格子中心（包含起点和终点，找不到时为nil）
+	FindMarchPath(owner *Owner, from, to *geo.Coord) []*geo.Coord                             // 行军寻路，在 FindPath 的基础上避开与 owner 敌对的单位占据的格子（终点除外），与 NewMarchCostModel 一致
+	MoveUnit(unit Unit, coord *geo.Coord) bool                                                // 移动单位到坐标所在格子的中心
+	SetTerrain(coord *geo.Coord, terrainType TerrainType)                                     // 设置坐标所在格子的地形
+	GetTerrain(coord *geo.Coord) TerrainType                                                  // 坐标所在格子的地形（未设置时为平原，地图外为 TerrainType_None）
+}
+
+// newTopology 按配置创建地图拓扑
+func newTopology(worldMap *WorldMap, topologyType config.TopologyType) Topology {
+	switch topologyType {
+	case config.TopologyType_Square:
+		return newSquareTopology(worldMap)
+	default:
+		return &hexTopology{worldMap: worldMap}
+	}
+}
+
+// hexTopology 六边形拓扑，由 HexGridManager 实现
+type hexTopology struct {
+	worldMap *WorldMap
+}
+
+func (t *hexTopology) GetType() config.TopologyType {
+	return config.TopologyType_Hex
+}
+
+func (t *hexTopology) CellOf(coord *geo.Coord) *geo.Coord {
+	return t.worldMap.hexToCoord(t.worldMap.coordToHex(coord))
+}
+
+func (t *hexTopology) Distance(a, b *geo.Coord) int32 {
+	return t.worldMap.coordToHex(a).DistanceTo(t.worldMap.coordToHex(b))
+}
+
+func (t *hexTopology) GetNeighbors(coord *geo.Coord) []*geo.Coord {
+	neighbors := make([]*geo.Coord, 0, 6)
+	for _, grid := range t.worldMap.hexGridMgr.GetNeighborGridsByCoord(t.worldMap.coordToHex(coord)) {
+		neighbors = append(neighbors, t.worldMap.hexToCoord(grid.GetCoord()))
+	}
+	return neighbors
+}
+
+func (t *hexTopology) GetUnitsInRange(center *geo.Coord, radius int32, filter *UnitFilter) []Unit {
+	return t.worldMap.hexGridMgr.GetUnitsInRadius(t.worldMap.coordToHex(center), radius, filter)
+}
+
+func (t *hexTopology) GetNearestUnits(center *geo.Coord, k int32, maxDistance int32, filter *UnitFilter) []Unit {
+	return t.worldMap.hexGridMgr.GetNearestUnits(t.worldMap.coordToHex(center), k, maxDistance, filter)
+}
+
+func (t *hexTopology) FindPath(from, to *geo.Coord) []*geo.Coord {
+	return t.findPath(nil, from, to)
+}
+
+func (t *hexTopology) FindMarchPath(owner *Owner, from, to *geo.Coord) []*geo.Coord {
+	return t.findPath(owner, from, to)
+}
+
+// findPath 按行军寻路成本模型寻路（owner 为nil时不考虑单位阻挡）
+func (t *hexTopology) findPath(owner *Owner, from, to *geo.Coord) []*geo.Coord {
+	target := t.worldMap.coordToHex(to)
+	path := t.worldMap.hexGridMgr.FindPathWithCost(t.worldMap.coordToHex(from), target, t.worldMap.NewMarchCostModel(owner, target)).GetPath()
+	if path == nil {
+		return nil
+	}
+	coords := make([]*geo.Coord, 0, len(path))
+	for _, hex := range path {
+		coords = append(coords, t.worldMap.hexToCoord(hex))
+	}
+	return coords
+}
+
+func (t *hexTopology) MoveUnit(unit Unit, coord *geo.Coord) bool {
+	return t.worldMap.MoveUnitToHex(unit, t.worldMap.coordToHex(coord))
+}
+
+// SetTerrain 设置坐标所在六边形的地形（未设置地形地图时创建一个）
+func (t *hexTopology) SetTerrain(coord *geo.Coord, terrainType TerrainType) {
+	if t.worldMap.terrainMap == nil {
+		t.worldMap.SetTerrainMap(NewTerrainMap(t.worldMap.hexGridMgr.GetBounds()))
+	}
+	t.worldMap.terrainMap.SetTerrain(t.worldMap.coordToHex(coord), terrainType)
+}
+
+func (t *hexTopology) GetTerrain(coord *geo.Coord) TerrainType {
+	hex := t.worldMap.coordToHex(coord)
+	if t.worldMap.terrainMap == nil {
+		if !t.worldMap.hexGridMgr.GetBounds().Contains(hex) {
+			return TerrainType_None
+		}
+		return TerrainType_Plain
+	}
+	return t.worldMap.terrainMap.GetTerrain(hex)
+}
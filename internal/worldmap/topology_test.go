@@ -0,0 +1,190 @@
+package worldmap
+
+import (
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/GooLuck/WorldMap/internal/worldmap/config"
+	"github.com/GooLuck/WorldMap/internal/worldmap/geo"
+)
+
+// TestTopology 测试同一套逻辑在正方形、顶点朝上和平边朝上的六边形地图上运行
+func TestTopology(t *testing.T) {
+	cases := []struct {
+		name     string
+		topology config.TopologyType
+		pointy   bool
+	}{
+		{"正方形", config.TopologyType_Square, false},
+		{"顶点朝上六边形", config.TopologyType_Hex, true},
+		{"平边朝上六边形", config.TopologyType_Hex, false},
+	}
+
+	for _, c := range cases {
+		mapConfig := newTestMapConfig()
+		mapConfig.Topology = c.topology
+		mapConfig.HexPointy = c.pointy
+		wm := NewWorldMap(mapConfig)
+		topology := wm.GetTopology()
+		if topology.GetType() != c.topology {
+			t.Fatalf("%s拓扑类型错误：期望 %d, 得到 %d", c.name, c.topology, topology.GetType())
+		}
+
+		from, to := geo.NewCoord(150, 250), geo.NewCoord(450, 350)
+		troop := &TestUnit{id: 1, coord: *from, unitType: MapUnitType_PlayerTroop}
+		target := &TestUnit{id: 2, coord: *to, unitType: MapUnitType_Resource}
+		wm.AddUnit(troop)
+		wm.AddUnit(target)
+
+		nearest := topology.GetNearestUnits(from, 1, 0, NewUnitFilter(nil, nil).WithUnitTypes(MapUnitType_Resource))
+		if len(nearest) != 1 || nearest[0] != target {
+			t.Fatalf("%s最近邻查询错误", c.name)
+		}
+
+		path := topology.FindPath(from, to)
+		if len(path) == 0 {
+			t.Fatalf("%s寻路失败", c.name)
+		}
+		if *path[0] != *topology.CellOf(from) || *path[len(path)-1] != *topology.CellOf(to) {
+			t.Errorf("%s路径起点或终点错误", c.name)
+		}
+		if int32(len(path)-1) != topology.Distance(from, to) {
+			t.Errorf("%s路径步数错误：期望 %d, 得到 %d", c.name, topology.Distance(from, to), len(path)-1)
+		}
+		for i := 1; i < len(path); i++ {
+			if topology.Distance(path[i-1], path[i]) != 1 {
+				t.Errorf("%s路径第 %d 步不相邻", c.name, i)
+			}
+		}
+
+		for _, step := range path[1:] {
+			if !topology.MoveUnit(troop, step) {
+				t.Fatalf("%s移动失败", c.name)
+			}
+		}
+		if units := topology.GetUnitsInRange(to, 0, nil); len(units) != 2 {
+			t.Errorf("%s到达后同格单位数量错误：期望 2, 得到 %d", c.name, len(units))
+		}
+		if len(topology.GetNeighbors(to)) == 0 {
+			t.Errorf("%s相邻格子不应该为空", c.name)
+		}
+	}
+
+	// 正方形地图按八方向计算步数
+	wm := NewWorldMap(&config.MapConfig{MapSize: newTestMapConfig().MapSize, Topology: config.TopologyType_Square})
+	if distance := wm.GetTopology().Distance(geo.NewCoord(0, 0), geo.NewCoord(350, 150)); distance != 3 {
+		t.Errorf("正方形步数错误：期望 3, 得到 %d", distance)
+	}
+	if neighbors := wm.GetTopology().GetNeighbors(geo.NewCoord(0, 0)); len(neighbors) != 3 {
+		t.Errorf("角落格子的相邻格子数量错误：期望 3, 得到 %d", len(neighbors))
+	}
+
+	// 正方形地图的地形按正方形格子设置，六边形地形不影响正方形寻路
+	topology := wm.GetTopology()
+	bounds := wm.GetHexGridManager().GetBounds()
+	hexTerrain := NewTerrainMap(bounds)
+	bounds.Range(func(hex *geo.HexCoord) bool {
+		hexTerrain.SetTerrain(hex, TerrainType_Water)
+		return true
+	})
+	wm.SetTerrainMap(hexTerrain)
+	from, to := geo.NewCoord(50, 50), geo.NewCoord(350, 50)
+	if path := topology.FindPath(from, to); len(path) != 4 {
+		t.Fatalf("正方形寻路不应该受六边形地形影响：期望 4, 得到 %d", len(path))
+	}
+	topology.SetTerrain(geo.NewCoord(150, 50), TerrainType_Water)
+	topology.SetTerrain(geo.NewCoord(150, 150), TerrainType_Water)
+	if terrain := topology.GetTerrain(geo.NewCoord(199, 199)); terrain != TerrainType_Water {
+		t.Errorf("格子地形错误：期望 %d, 得到 %d", TerrainType_Water, terrain)
+	}
+	if terrain := topology.GetTerrain(geo.NewCoord(250, 50)); terrain != TerrainType_Plain {
+		t.Errorf("未设置的格子地形错误：期望 %d, 得到 %d", TerrainType_Plain, terrain)
+	}
+	path := topology.FindPath(from, to)
+	if len(path) != 5 {
+		t.Fatalf("绕开水域的路径步数错误：期望 5, 得到 %d", len(path))
+	}
+	for _, step := range path {
+		if topology.GetTerrain(step) == TerrainType_Water {
+			t.Errorf("路径不应该经过水域格子 %v", step)
+		}
+	}
+}
+
+// TestTopologyMarchCost 测试两种拓扑使用同一套寻路成本模型：障碍物、敌对单位阻挡和地形
+func TestTopologyMarchCost(t *testing.T) {
+	cases := []struct {
+		name     string
+		topology config.TopologyType
+		pointy   bool
+	}{
+		{"正方形", config.TopologyType_Square, false},
+		{"顶点朝上六边形", config.TopologyType_Hex, true},
+		{"平边朝上六边形", config.TopologyType_Hex, false},
+	}
+
+	for _, c := range cases {
+		mapConfig := newTestMapConfig()
+		mapConfig.Topology = c.topology
+		mapConfig.HexPointy = c.pointy
+		mapConfig.Obstacles = []config.ObstacleConfig{{ObstacleID: 1, X: 290, Y: 0, Width: 20, Height: 600}}
+		wm := NewWorldMap(mapConfig)
+		topology := wm.GetTopology()
+
+		// 禁止行军的障碍物不可进入，路径绕过障碍物
+		from, to := geo.NewCoord(150, 350), geo.NewCoord(450, 350)
+		path := topology.FindPath(from, to)
+		if len(path) == 0 {
+			t.Fatalf("%s绕过障碍物寻路失败", c.name)
+		}
+		if int32(len(path)-1) <= topology.Distance(from, to) {
+			t.Errorf("%s路径应该绕过障碍物：步数 %d", c.name, len(path)-1)
+		}
+		for _, step := range path {
+			if step.Y < 600 && step.X >= 200 && step.X < 400 {
+				t.Errorf("%s路径不应该经过障碍物所在的格子 %v", c.name, step)
+			}
+		}
+
+		// 敌对单位占据的格子不可进入，终点上的敌对单位不阻挡
+		from, to = geo.NewCoord(150, 750), geo.NewCoord(650, 750)
+		path = topology.FindPath(from, to)
+		if len(path) < 3 {
+			t.Fatalf("%s寻路失败", c.name)
+		}
+		blocked := path[len(path)/2]
+		wm.AddUnit(&TestUnit{id: 1, coord: *blocked, unitType: MapUnitType_PlayerTroop, owner: NewPlayerOwner(2)})
+		wm.AddUnit(&TestUnit{id: 2, coord: *to, unitType: MapUnitType_PlayerCity, owner: NewPlayerOwner(2)})
+		if path := topology.FindPath(from, to); len(path) == 0 || !slices.ContainsFunc(path, func(step *geo.Coord) bool { return *step == *blocked }) {
+			t.Errorf("%s不考虑单位阻挡时路径不应该改变", c.name)
+		}
+		marchPath := topology.FindMarchPath(NewPlayerOwner(1), from, to)
+		if len(marchPath) == 0 || *marchPath[len(marchPath)-1] != *topology.CellOf(to) {
+			t.Fatalf("%s行军寻路失败", c.name)
+		}
+		if slices.ContainsFunc(marchPath, func(step *geo.Coord) bool { return *step == *blocked }) {
+			t.Errorf("%s行军路径不应该经过敌对单位 %v", c.name, blocked)
+		}
+		if path := topology.FindMarchPath(NewPlayerOwner(2), from, to); len(path) != len(topology.FindPath(from, to)) {
+			t.Errorf("%s自己的单位不应该阻挡行军", c.name)
+		}
+	}
+
+	// 正方形地图上行军按正方形格子的地形寻路
+	mapConfig := newTestMapConfig()
+	mapConfig.Topology = config.TopologyType_Square
+	wm := NewWorldMap(mapConfig)
+	for y := int32(50); y < 900; y += 100 {
+		wm.GetTopology().SetTerrain(geo.NewCoord(350, y), TerrainType_Water)
+	}
+	troop := wm.GetTroopManager().CreateTroop(1, NewPlayerOwner(1), geo.NewCoord(150, 350))
+	if !wm.GetTroopManager().MarchTo(troop, wm.coordToHex(geo.NewCoord(550, 350)), 100, time.Now()) {
+		t.Fatal("行军失败")
+	}
+	for _, hex := range troop.GetPath() {
+		if wm.GetTopology().GetTerrain(wm.hexToCoord(hex)) == TerrainType_Water {
+			t.Errorf("行军路径不应该经过水域格子 %v", hex)
+		}
+	}
+}
@@ -0,0 +1,386 @@
+package worldmap
+
+import (
+	"math"
+	"time"
+
+	"github.com/GooLuck/WorldMap/internal/worldmap/geo"
+)
+
+// TroopState 部队状态
+type TroopState int32
+
+const (
+	TroopState_Idle      TroopState = iota // 驻扎
+	TroopState_Marching                    // 行军中
+	TroopState_Gathering                   // 采集中
+	TroopState_Returning                   // 召回返程中
+)
+
+// TroopUnit 玩家部队单位
+type TroopUnit struct {
+	*BaseUnit
+	state       TroopState
+	home        *geo.HexCoord   // 驻地（召回和结算失败时返回的六边形）
+	intent      MarchIntent     // 行军意图
+	targetId    int64           // 行军目标单位id（前往空地时为0）
+	capacity    int32           // 负重上限
+	gatherRate  float64         // 采集速度（每秒）
+	loadType    string          // 携带的资源类型
+	load        int32           // 携带的资源量
+	path        []*geo.HexCoord // 行军路径（包含起点和终点）
+	stepCosts   []float64       // 走到路径上每个点的那一步的成本系数（与 path 对齐）
+	passed      []*geo.HexCoord // 加速、改道前已经走过的六边形，召回时沿其原路返回
+	passedCosts []float64       // 已经走过的六边形对应的成本系数（与 passed 对齐）
+	points      []geo.Vector2   // 路径上每个六边形中心的世界坐标
+	stepTimes   []time.Time     // 到达路径上每个点的时间
+	speed       float64         // 行军速度（世界单位/秒）
+	departTime  time.Time       // 出发时间
+	arriveTime  time.Time       // 到达时间
+}
+
+// NewTroopUnit 创建玩家部队
+func NewTroopUnit(id int64, configId int32, coord geo.Coord, hexCoord *geo.HexCoord, owner *Owner) *TroopUnit {
+	return &TroopUnit{
+		BaseUnit: NewBaseUnit(id, configId, coord, hexCoord, MapUnitType_PlayerTroop, owner),
+		state:    TroopState_Idle,
+		home:     hexCoord,
+	}
+}
+
+// GetHome 获取驻地
+func (t *TroopUnit) GetHome() *geo.HexCoord {
+	return t.home
+}
+
+// SetHome 设置驻地
+func (t *TroopUnit) SetHome(home *geo.HexCoord) {
+	t.home = home
+}
+
+// GetIntent 获取行军意图
+func (t *TroopUnit) GetIntent() MarchIntent {
+	return t.intent
+}
+
+// GetTargetId 获取行军目标单位id
+func (t *TroopUnit) GetTargetId() int64 {
+	return t.targetId
+}
+
+// setIntent 设置行军意图和目标
+func (t *TroopUnit) setIntent(intent MarchIntent, targetId int64) {
+	t.intent = intent
+	t.targetId = targetId
+}
+
+// startMarch 开始沿路径行军，按速度和每一步的成本系数计算每个路径点的到达时间
+// stepCosts 与 path 对齐（通常来自 PathResult.StepCosts），为 nil 时每一步的成本系数为 1
+func (t *TroopUnit) startMarch(path []*geo.HexCoord, stepCosts []float64, layout *geo.HexLayout, speed float64, now time.Time) {
+	t.state = TroopState_Marching
+	t.speed = speed
+	t.departTime = now
+	t.passed = nil
+	t.passedCosts = nil
+	x, y := layout.HexToWorld(path[0])
+	t.replan(geo.Vector2{X: x, Y: y}, path, stepCosts, layout, now)
+}
+
+// replan 从指定世界坐标出发，依次经过 path[1:] 的六边形中心，重新计算轨迹和到达时间
+// path[0] 为出发时所在的六边形，origin 不在其中心时 path[1] 可以与 path[0] 相同，表示先走到该六边形中心
+// 每一段的用时为距离除以速度再乘以走到该点的成本系数
+func (t *TroopUnit) replan(origin geo.Vector2, path []*geo.HexCoord, stepCosts []float64, layout *geo.HexLayout, now time.Time) {
+	t.path = path
+	t.stepCosts = make([]float64, len(path))
+	t.points = make([]geo.Vector2, len(path))
+	t.stepTimes = make([]time.Time, len(path))
+
+	elapsed := 0.0
+	for i, hex := range path {
+		if i == 0 {
+			t.points[i] = origin
+		} else {
+			x, y := layout.HexToWorld(hex)
+			t.points[i] = geo.Vector2{X: x, Y: y}
+			t.stepCosts[i] = stepCostAt(stepCosts, i)
+			elapsed += t.points[i].Sub(&t.points[i-1]).Length() / t.speed * t.stepCosts[i]
+		}
+		t.stepTimes[i] = now.Add(time.Duration(elapsed * float64(time.Second)))
+	}
+	t.arriveTime = t.stepTimes[len(t.stepTimes)-1]
+}
+
+// stepCostAt 获取第 i 步的成本系数（缺失或无效时为 1）
+func stepCostAt(stepCosts []float64, i int) float64 {
+	if i < len(stepCosts) && stepCosts[i] > 0 {
+		return stepCosts[i]
+	}
+	return 1
+}
+
+// segmentCost 指定时间所在路径段的成本系数
+func (t *TroopUnit) segmentCost(now time.Time) float64 {
+	index, _ := t.segmentAt(now)
+	return stepCostAt(t.stepCosts, index+1)
+}
+
+// routeFrom 以指定时间的插值位置为起点，构造经过 waypoints 的新路径
+// costs 与 waypoints 对齐，costs[0] 为从当前位置走到 waypoints[0] 的成本系数
+func (t *TroopUnit) routeFrom(now time.Time, waypoints []*geo.HexCoord, costs []float64, layout *geo.HexLayout) (geo.Vector2, []*geo.HexCoord, []float64) {
+	x, y := t.GetPositionAt(now)
+	origin := geo.Vector2{X: x, Y: y}
+	current := t.GetHexAt(now)
+
+	// 恰好位于第一个途经点中心时不需要重复经过
+	if len(waypoints) > 0 && waypoints[0].Equal(current) {
+		cx, cy := layout.HexToWorld(current)
+		if math.Abs(cx-x) < 1e-6 && math.Abs(cy-y) < 1e-6 {
+			return origin, waypoints, costs
+		}
+	}
+
+	path := make([]*geo.HexCoord, 0, len(waypoints)+1)
+	path = append(path, current)
+	routeCosts := make([]float64, 0, len(costs)+1)
+	routeCosts = append(routeCosts, 0)
+	return origin, append(path, waypoints...), append(routeCosts, costs...)
+}
+
+// recall 从当前位置沿原路径反向返回起点，每一段沿用去程的成本系数
+func (t *TroopUnit) recall(layout *geo.HexLayout, now time.Time) {
+	traveled, traveledCosts := appendRoute(t.passed, t.passedCosts, t.traveledPath(now), t.traveledCosts(now))
+	waypoints := make([]*geo.HexCoord, 0, len(traveled))
+	costs := make([]float64, 0, len(traveled))
+	for i := len(traveled) - 1; i >= 0; i-- {
+		waypoints = append(waypoints, traveled[i])
+		if i == len(traveled)-1 {
+			costs = append(costs, t.segmentCost(now))
+		} else {
+			costs = append(costs, stepCostAt(traveledCosts, i+1))
+		}
+	}
+
+	origin, path, pathCosts := t.routeFrom(now, waypoints, costs, layout)
+	t.state = TroopState_Returning
+	t.departTime = now
+	t.passed = nil
+	t.passedCosts = nil
+	t.replan(origin, path, pathCosts, layout, now)
+}
+
+// changeSpeed 按新的速度继续走完剩余路径
+func (t *TroopUnit) changeSpeed(speed float64, layout *geo.HexLayout, now time.Time) {
+	index, _ := t.segmentAt(now)
+	rest := min(index+1, len(t.path))
+	origin, path, costs := t.routeFrom(now, t.path[rest:], t.stepCosts[rest:], layout)
+	t.passed, t.passedCosts = appendRoute(t.passed, t.passedCosts, t.traveledPath(now), t.traveledCosts(now))
+	t.speed = speed
+	t.replan(origin, path, costs, layout, now)
+}
+
+// redirect 从当前位置改为沿新路径行军，path 从当前所在六边形开始，stepCosts 与 path 对齐
+func (t *TroopUnit) redirect(path []*geo.HexCoord, stepCosts []float64, layout *geo.HexLayout, now time.Time) {
+	costs := make([]float64, len(path))
+	copy(costs, stepCosts)
+	costs[0] = t.segmentCost(now)
+
+	origin, route, routeCosts := t.routeFrom(now, path, costs, layout)
+	t.passed, t.passedCosts = appendRoute(t.passed, t.passedCosts, t.traveledPath(now), t.traveledCosts(now))
+	t.state = TroopState_Marching
+	t.departTime = now
+	t.replan(origin, route, routeCosts, layout, now)
+}
+
+// traveledPath 当前路径中指定时间之前已经经过的六边形
+func (t *TroopUnit) traveledPath(now time.Time) []*geo.HexCoord {
+	index, _ := t.segmentAt(now)
+	return t.path[:min(index+1, len(t.path))]
+}
+
+// traveledCosts 当前路径中指定时间之前已经经过的六边形对应的成本系数
+func (t *TroopUnit) traveledCosts(now time.Time) []float64 {
+	index, _ := t.segmentAt(now)
+	return t.stepCosts[:min(index+1, len(t.stepCosts))]
+}
+
+// appendRoute 追加六边形序列及其成本系数，跳过与末尾相同的六边形
+func appendRoute(dst []*geo.HexCoord, dstCosts []float64, src []*geo.HexCoord, srcCosts []float64) ([]*geo.HexCoord, []float64) {
+	result := append(make([]*geo.HexCoord, 0, len(dst)+len(src)), dst...)
+	resultCosts := append(make([]float64, 0, len(dst)+len(src)), dstCosts...)
+	for i, hex := range src {
+		if len(result) > 0 && result[len(result)-1].Equal(hex) {
+			continue
+		}
+		result = append(result, hex)
+		resultCosts = append(resultCosts, stepCostAt(srcCosts, i))
+	}
+	return result, resultCosts
+}
+
+// SetGatherAbility 设置采集能力：负重上限和每秒采集量
+func (t *TroopUnit) SetGatherAbility(capacity int32, gatherRate float64) {
+	t.capacity = capacity
+	t.gatherRate = gatherRate
+}
+
+// GetCapacity 获取负重上限
+func (t *TroopUnit) GetCapacity() int32 {
+	return t.capacity
+}
+
+// GetGatherRate 获取每秒采集量
+func (t *TroopUnit) GetGatherRate() float64 {
+	return t.gatherRate
+}
+
+// GetLoad 获取携带的资源
+func (t *TroopUnit) GetLoad() (string, int32) {
+	return t.loadType, t.load
+}
+
+// Unload 卸下携带的资源（通常在回到驻地后由游戏服结算）
+func (t *TroopUnit) Unload() (string, int32) {
+	loadType, load := t.loadType, t.load
+	t.loadType, t.load = "", 0
+	return loadType, load
+}
+
+// startGather 开始采集指定类型的资源
+func (t *TroopUnit) startGather(loadType string) {
+	t.state = TroopState_Gathering
+	t.loadType = loadType
+}
+
+// stopGather 结束采集
+func (t *TroopUnit) stopGather() {
+	t.state = TroopState_Idle
+}
+
+// setLoad 设置携带的资源量
+func (t *TroopUnit) setLoad(load int32) {
+	t.load = load
+}
+
+// IsGathering 是否在采集中
+func (t *TroopUnit) IsGathering() bool {
+	return t.state == TroopState_Gathering
+}
+
+// startReturn 开始沿路径返回驻地
+func (t *TroopUnit) startReturn(path []*geo.HexCoord, stepCosts []float64, layout *geo.HexLayout, speed float64, now time.Time) {
+	t.startMarch(path, stepCosts, layout, speed, now)
+	t.state = TroopState_Returning
+}
+
+// stopMarch 结束行军
+func (t *TroopUnit) stopMarch() {
+	t.state = TroopState_Idle
+}
+
+// GetState 获取部队状态
+func (t *TroopUnit) GetState() TroopState {
+	return t.state
+}
+
+// IsMarching 是否在行军中（包括召回返程）
+func (t *TroopUnit) IsMarching() bool {
+	return t.state == TroopState_Marching || t.state == TroopState_Returning
+}
+
+// IsReturning 是否在召回返程中
+func (t *TroopUnit) IsReturning() bool {
+	return t.state == TroopState_Returning
+}
+
+// GetPath 获取行军路径
+func (t *TroopUnit) GetPath() []*geo.HexCoord {
+	return t.path
+}
+
+// GetStepCosts 获取走到路径上每个点的那一步的成本系数（与 GetPath 对齐）
+func (t *TroopUnit) GetStepCosts() []float64 {
+	return t.stepCosts
+}
+
+// GetSpeed 获取行军速度
+func (t *TroopUnit) GetSpeed() float64 {
+	return t.speed
+}
+
+// GetDepartTime 获取出发时间
+func (t *TroopUnit) GetDepartTime() time.Time {
+	return t.departTime
+}
+
+// GetArriveTime 获取到达时间
+func (t *TroopUnit) GetArriveTime() time.Time {
+	return t.arriveTime
+}
+
+// GetTarget 获取行军目标六边形
+func (t *TroopUnit) GetTarget() *geo.HexCoord {
+	if len(t.path) == 0 {
+		return nil
+	}
+	return t.path[len(t.path)-1]
+}
+
+// segmentAt 返回指定时间所在的路径段索引（从 path[i] 走向 path[i+1]）及段内进度
+func (t *TroopUnit) segmentAt(now time.Time) (int, float64) {
+	if len(t.path) <= 1 || !now.After(t.stepTimes[0]) {
+		return 0, 0
+	}
+	if !now.Before(t.arriveTime) {
+		return len(t.path) - 1, 0
+	}
+
+	for i := 1; i < len(t.stepTimes); i++ {
+		if now.Before(t.stepTimes[i]) {
+			total := t.stepTimes[i].Sub(t.stepTimes[i-1])
+			if total <= 0 {
+				return i, 0
+			}
+			return i - 1, float64(now.Sub(t.stepTimes[i-1])) / float64(total)
+		}
+	}
+	return len(t.path) - 1, 0
+}
+
+// GetPositionAt 获取指定时间的插值世界坐标
+func (t *TroopUnit) GetPositionAt(now time.Time) (float64, float64) {
+	if !t.IsMarching() || len(t.points) == 0 {
+		coord := t.GetCoord()
+		return float64(coord.X), float64(coord.Y)
+	}
+
+	index, progress := t.segmentAt(now)
+	if index >= len(t.points)-1 {
+		last := t.points[len(t.points)-1]
+		return last.X, last.Y
+	}
+	from := t.points[index]
+	to := t.points[index+1]
+	return from.X + (to.X-from.X)*progress, from.Y + (to.Y-from.Y)*progress
+}
+
+// GetHexAt 获取指定时间所在的六边形（超过半段即视为进入下一个六边形）
+func (t *TroopUnit) GetHexAt(now time.Time) *geo.HexCoord {
+	if !t.IsMarching() || len(t.path) == 0 {
+		return t.GetHexCoord()
+	}
+
+	index, progress := t.segmentAt(now)
+	if index < len(t.path)-1 && progress >= 0.5 {
+		index++
+	}
+	return t.path[index]
+}
+
+// GetProgress 获取行军进度（0.0-1.0）
+func (t *TroopUnit) GetProgress(now time.Time) float64 {
+	total := t.arriveTime.Sub(t.departTime)
+	if !t.IsMarching() || total <= 0 {
+		return 1
+	}
+	return math.Min(math.Max(float64(now.Sub(t.departTime))/float64(total), 0), 1)
+}
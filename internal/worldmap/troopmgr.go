@@ -0,0 +1,282 @@
+package worldmap
+
+import (
+	"time"
+
+	"github.com/GooLuck/WorldMap/internal/worldmap/geo"
+)
+
+// MarchArriveCallback 行军到达回调
+type MarchArriveCallback func(troop *TroopUnit, now time.Time)
+
+// TroopManager 部队管理器，负责部队行军的推进
+type TroopManager struct {
+	worldMap        *WorldMap
+	troops          map[int64]*TroopUnit // 部队id -> 部队
+	marching        map[int64]*TroopUnit // 行军中的部队
+	maxMarchCost    float64              // 寻路行军的成本预算（<=0 表示不限）
+	arriveCallbacks []MarchArriveCallback
+}
+
+// NewTroopManager 创建部队管理器
+func NewTroopManager(worldMap *WorldMap) *TroopManager {
+	return &TroopManager{
+		worldMap:        worldMap,
+		troops:          make(map[int64]*TroopUnit),
+		marching:        make(map[int64]*TroopUnit),
+		arriveCallbacks: make([]MarchArriveCallback, 0),
+	}
+}
+
+// AddArriveCallback 注册行军到达回调
+func (tm *TroopManager) AddArriveCallback(callback MarchArriveCallback) {
+	tm.arriveCallbacks = append(tm.arriveCallbacks, callback)
+}
+
+// SetMaxMarchCost 设置寻路行军的成本预算，超出预算的目标无法出发或改道（<=0 表示不限）
+func (tm *TroopManager) SetMaxMarchCost(maxCost float64) {
+	tm.maxMarchCost = maxCost
+}
+
+// findMarchPath 按行军成本模型为部队寻路，withBudget 表示是否受成本预算限制
+func (tm *TroopManager) findMarchPath(troop *TroopUnit, target *geo.HexCoord, withBudget bool) *PathResult {
+	model := tm.worldMap.NewMarchCostModel(troop.GetOwner(), target)
+	if withBudget {
+		model.MaxCost = tm.maxMarchCost
+	}
+	return tm.worldMap.hexGridMgr.FindPathWithCost(troop.GetHexCoord(), target, model)
+}
+
+// CreateTroop 在指定坐标创建部队
+func (tm *TroopManager) CreateTroop(configId int32, owner *Owner, coord *geo.Coord) *TroopUnit {
+	if !tm.worldMap.isCoordInMap(coord) {
+		return nil
+	}
+
+	troop := NewTroopUnit(GetIDGenerator().GenerateNewID(), configId, *coord, tm.worldMap.coordToHex(coord), owner)
+	if !tm.worldMap.AddUnit(troop) {
+		return nil
+	}
+	tm.troops[troop.GetId()] = troop
+	return troop
+}
+
+// RemoveTroop 移除部队
+func (tm *TroopManager) RemoveTroop(troopId int64) {
+	troop, exists := tm.troops[troopId]
+	if !exists {
+		return
+	}
+
+	if troop.IsMarching() {
+		tm.worldMap.observerMgr.RemoveMarching(troopId)
+	}
+	tm.worldMap.resourceMgr.onTroopRemoved(troopId)
+	tm.worldMap.RemoveUnit(troop)
+	delete(tm.marching, troopId)
+	delete(tm.troops, troopId)
+	tm.worldMap.interception.onMarchEnded(troopId)
+}
+
+// GetTroop 获取部队
+func (tm *TroopManager) GetTroop(troopId int64) *TroopUnit {
+	return tm.troops[troopId]
+}
+
+// StartMarch 沿指定路径行军，path 通常由 HexGridManager.FindPath 得到，speed 为世界单位/秒，每一步的成本系数为 1
+func (tm *TroopManager) StartMarch(troop *TroopUnit, path []*geo.HexCoord, speed float64, now time.Time) bool {
+	return tm.StartMarchWithCost(troop, &PathResult{Path: path}, speed, now)
+}
+
+// StartMarchWithCost 沿寻路结果行军，每一段的用时按 StepCosts 中的成本系数放大
+func (tm *TroopManager) StartMarchWithCost(troop *TroopUnit, result *PathResult, speed float64, now time.Time) bool {
+	path := result.GetPath()
+	if len(path) < 2 || speed <= 0 {
+		return false
+	}
+	for _, hex := range path {
+		if !tm.worldMap.hexGridMgr.Contains(hex) {
+			return false
+		}
+	}
+
+	troop.startMarch(path, result.StepCosts, tm.worldMap.hexGridMgr.GetLayout(), speed, now)
+	tm.marching[troop.GetId()] = troop
+	tm.worldMap.observerMgr.AddMarching(troop)
+	tm.worldMap.interception.onMarchChanged(troop, now)
+	return true
+}
+
+// MarchTo 按行军成本模型寻路后行军到指定六边形
+func (tm *TroopManager) MarchTo(troop *TroopUnit, target *geo.HexCoord, speed float64, now time.Time) bool {
+	return tm.StartMarchWithCost(troop, tm.findMarchPath(troop, target, true), speed, now)
+}
+
+// DispatchToUnit 以指定意图行军前往目标单位，出发前检查目标和关系
+func (tm *TroopManager) DispatchToUnit(troop *TroopUnit, intent MarchIntent, targetId int64, speed float64, now time.Time) bool {
+	target := tm.worldMap.GetUnit(targetId)
+	if target == nil || target.GetHexCoord() == nil {
+		return false
+	}
+	if tm.worldMap.marchResolvers.CheckTarget(troop, intent, target) != MarchFailReason_None {
+		return false
+	}
+	if !tm.MarchTo(troop, target.GetHexCoord(), speed, now) {
+		return false
+	}
+	troop.setIntent(intent, targetId)
+	return true
+}
+
+// DispatchToHex 以指定意图行军前往空地（侦察、占领等）
+func (tm *TroopManager) DispatchToHex(troop *TroopUnit, intent MarchIntent, target *geo.HexCoord, speed float64, now time.Time) bool {
+	if tm.worldMap.marchResolvers.CheckTarget(troop, intent, nil) != MarchFailReason_None {
+		return false
+	}
+	if !tm.MarchTo(troop, target, speed, now) {
+		return false
+	}
+	troop.setIntent(intent, 0)
+	return true
+}
+
+// ReturnHome 驻扎中的部队以原速度返回驻地
+func (tm *TroopManager) ReturnHome(troop *TroopUnit, now time.Time) bool {
+	if troop.IsMarching() || troop.GetHome() == nil || troop.GetHexCoord().Equal(troop.GetHome()) {
+		return false
+	}
+
+	// 返回驻地不受成本预算限制
+	result := tm.findMarchPath(troop, troop.GetHome(), false)
+	if len(result.GetPath()) < 2 || troop.GetSpeed() <= 0 {
+		return false
+	}
+	troop.startReturn(result.Path, result.StepCosts, tm.worldMap.hexGridMgr.GetLayout(), troop.GetSpeed(), now)
+	tm.marching[troop.GetId()] = troop
+	tm.worldMap.observerMgr.AddMarching(troop)
+	tm.worldMap.interception.onMarchChanged(troop, now)
+	return true
+}
+
+// Recall 召回行军，从当前插值位置沿原路径返回起点
+func (tm *TroopManager) Recall(troop *TroopUnit, now time.Time) bool {
+	if !tm.isMarching(troop) || troop.IsReturning() {
+		return false
+	}
+
+	tm.syncPosition(troop, now)
+	troop.recall(tm.worldMap.hexGridMgr.GetLayout(), now)
+	troop.setIntent(MarchIntent_None, 0)
+	tm.worldMap.observerMgr.UpdateMarching(troop)
+	tm.worldMap.interception.onMarchChanged(troop, now)
+	return true
+}
+
+// SpeedUp 行军加速，multiplier 为在当前速度上的倍率，剩余路程按新速度重新计算到达时间
+func (tm *TroopManager) SpeedUp(troop *TroopUnit, multiplier float64, now time.Time) bool {
+	if !tm.isMarching(troop) || multiplier <= 0 {
+		return false
+	}
+
+	tm.syncPosition(troop, now)
+	troop.changeSpeed(troop.GetSpeed()*multiplier, tm.worldMap.hexGridMgr.GetLayout(), now)
+	tm.worldMap.observerMgr.UpdateMarching(troop)
+	tm.worldMap.interception.onMarchChanged(troop, now)
+	return true
+}
+
+// Redirect 行军改道，从当前位置寻路前往新的目标六边形（返程中的部队不能改道）
+// 改道后不再前往原目标单位：不需要目标单位的意图保留，需要目标单位的意图清除为单纯移动
+func (tm *TroopManager) Redirect(troop *TroopUnit, target *geo.HexCoord, now time.Time) bool {
+	if !tm.redirect(troop, target, now) {
+		return false
+	}
+	if troop.GetTargetId() != 0 {
+		intent := troop.GetIntent()
+		if tm.worldMap.marchResolvers.CheckTarget(troop, intent, nil) != MarchFailReason_None {
+			intent = MarchIntent_None
+		}
+		troop.setIntent(intent, 0)
+	}
+	return true
+}
+
+// RedirectToUnit 行军改道，以指定意图前往新的目标单位，改道前检查目标和关系
+func (tm *TroopManager) RedirectToUnit(troop *TroopUnit, intent MarchIntent, targetId int64, now time.Time) bool {
+	if !tm.isMarching(troop) || troop.IsReturning() {
+		return false
+	}
+	target := tm.worldMap.GetUnit(targetId)
+	if target == nil || target.GetHexCoord() == nil {
+		return false
+	}
+	if tm.worldMap.marchResolvers.CheckTarget(troop, intent, target) != MarchFailReason_None {
+		return false
+	}
+	if !tm.redirect(troop, target.GetHexCoord(), now) {
+		return false
+	}
+	troop.setIntent(intent, targetId)
+	return true
+}
+
+// redirect 从当前插值位置寻路，沿新路径前往目标六边形
+func (tm *TroopManager) redirect(troop *TroopUnit, target *geo.HexCoord, now time.Time) bool {
+	if !tm.isMarching(troop) || troop.IsReturning() {
+		return false
+	}
+
+	tm.syncPosition(troop, now)
+	result := tm.findMarchPath(troop, target, true)
+	if result == nil {
+		return false
+	}
+	troop.redirect(result.Path, result.StepCosts, tm.worldMap.hexGridMgr.GetLayout(), now)
+	tm.worldMap.observerMgr.UpdateMarching(troop)
+	tm.worldMap.interception.onMarchChanged(troop, now)
+	return true
+}
+
+// isMarching 部队是否由本管理器推进行军
+func (tm *TroopManager) isMarching(troop *TroopUnit) bool {
+	if troop == nil || !troop.IsMarching() {
+		return false
+	}
+	_, exists := tm.marching[troop.GetId()]
+	return exists
+}
+
+// Update 推进所有行军，更新部队所在网格，触发到达回调并结算行军意图
+func (tm *TroopManager) Update(now time.Time) {
+	arrived := make([]*TroopUnit, 0)
+	for _, troop := range tm.marching {
+		tm.syncPosition(troop, now)
+		if !now.Before(troop.GetArriveTime()) {
+			arrived = append(arrived, troop)
+		}
+	}
+
+	for _, troop := range arrived {
+		returning := troop.IsReturning()
+		troop.stopMarch()
+		delete(tm.marching, troop.GetId())
+		tm.worldMap.observerMgr.RemoveMarching(troop.GetId())
+		tm.worldMap.interception.onMarchEnded(troop.GetId())
+		for _, callback := range tm.arriveCallbacks {
+			callback(troop, now)
+		}
+		if !returning {
+			tm.worldMap.marchResolvers.resolve(troop, now)
+		}
+	}
+}
+
+// syncPosition 按插值位置更新部队的矩形坐标和六边形（六边形取行军路径上已过半的格子）
+func (tm *TroopManager) syncPosition(troop *TroopUnit, now time.Time) {
+	tm.worldMap.moveUnit(troop, tm.worldMap.worldToCoord(troop.GetPositionAt(now)), troop.GetHexAt(now))
+}
+
+// GetMarchingTroops 获取所有行军中的部队
+func (tm *TroopManager) GetMarchingTroops() map[int64]*TroopUnit {
+	return tm.marching
+}
@@ -0,0 +1,518 @@
+package worldmap
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/GooLuck/WorldMap/internal/worldmap/config"
+	"github.com/GooLuck/WorldMap/internal/worldmap/geo"
+)
+
+// TestTroopMarch 测试部队按时间沿路径行军并触发到达回调
+func TestTroopMarch(t *testing.T) {
+	wm := NewWorldMap(newTestMapConfig())
+	troopMgr := wm.GetTroopManager()
+
+	start := geo.NewHexCoord(1, 1)
+	troop := troopMgr.CreateTroop(1, NewPlayerOwner(1), wm.hexToCoord(start))
+	if troop == nil {
+		t.Fatal("创建部队失败")
+	}
+	if !troop.GetHexCoord().Equal(start) {
+		t.Fatalf("部队六边形坐标错误：期望 %s, 得到 %s", start, troop.GetHexCoord())
+	}
+
+	arrivedCount := 0
+	troopMgr.AddArriveCallback(func(arrived *TroopUnit, now time.Time) {
+		if arrived == troop {
+			arrivedCount++
+		}
+	})
+
+	// 相邻六边形中心距离为 sqrt(3)*半径，速度设为每秒一格
+	stepLen := math.Sqrt(3) * wm.GetHexGridManager().GetLayout().Radius
+	target := geo.NewHexCoord(4, 1)
+	now := time.Now()
+	if !troopMgr.MarchTo(troop, target, stepLen, now) {
+		t.Fatal("行军失败")
+	}
+	if got := troop.GetArriveTime().Sub(now); absFloat(got.Seconds()-3) > 0.001 {
+		t.Errorf("到达时间错误：期望 3s, 得到 %v", got)
+	}
+
+	// 1.5 秒时位于第二段中点
+	mid := now.Add(1500 * time.Millisecond)
+	x, y := troop.GetPositionAt(mid)
+	fromX, fromY := wm.GetHexGridManager().GetLayout().HexToWorld(geo.NewHexCoord(2, 1))
+	toX, toY := wm.GetHexGridManager().GetLayout().HexToWorld(geo.NewHexCoord(3, 1))
+	if absFloat(x-(fromX+toX)/2) > 0.01 || absFloat(y-(fromY+toY)/2) > 0.01 {
+		t.Errorf("插值位置错误：得到 (%.2f, %.2f)", x, y)
+	}
+
+	wm.Update(now.Add(1200 * time.Millisecond))
+	if !troop.GetHexCoord().Equal(geo.NewHexCoord(2, 1)) {
+		t.Errorf("行军中六边形错误：得到 %s", troop.GetHexCoord())
+	}
+	if !wm.GetHexGridManager().GetGrid(geo.NewHexCoord(2, 1)).IsExistUnit(troop) ||
+		wm.GetHexGridManager().GetGrid(start).IsExistUnit(troop) {
+		t.Error("部队应该随行军移动到新的六边形网格")
+	}
+	if len(wm.GetObserverManager().marching) != 1 {
+		t.Error("行军中的部队应该登记到观察者管理器")
+	}
+
+	wm.Update(now.Add(3 * time.Second))
+	wm.Update(now.Add(4 * time.Second))
+	if arrivedCount != 1 {
+		t.Errorf("到达回调次数错误：期望 1, 得到 %d", arrivedCount)
+	}
+	if troop.IsMarching() || !troop.GetHexCoord().Equal(target) {
+		t.Error("到达后部队应该停在目标六边形")
+	}
+	if len(wm.GetObserverManager().marching) != 0 {
+		t.Error("到达后应该移除行军登记")
+	}
+}
+
+// TestMarchInterception 测试敌对行军相遇和经过敌方控制六边形的检测
+func TestMarchInterception(t *testing.T) {
+	wm := NewWorldMap(newTestMapConfig())
+	troopMgr := wm.GetTroopManager()
+	radius := wm.GetHexGridManager().GetLayout().Radius
+	stepLen := math.Sqrt(3) * radius
+
+	encounters := make([]*MarchEncounter, 0)
+	wm.GetInterceptionDetector().AddEncounterCallback(func(encounter *MarchEncounter) {
+		encounters = append(encounters, encounter)
+	})
+
+	straightPath := func(fromQ, toQ, r int32) []*geo.HexCoord {
+		path := make([]*geo.HexCoord, 0)
+		step := int32(1)
+		if toQ < fromQ {
+			step = -1
+		}
+		for q := fromQ; q != toQ+step; q += step {
+			path = append(path, geo.NewHexCoord(q, r))
+		}
+		return path
+	}
+
+	// 两支敌对部队在同一行相向而行，初始距离 6 格，相对速度 2 格/秒
+	a := troopMgr.CreateTroop(1, NewPlayerOwner(1), wm.hexToCoord(geo.NewHexCoord(0, 2)))
+	b := troopMgr.CreateTroop(1, NewPlayerOwner(2), wm.hexToCoord(geo.NewHexCoord(6, 2)))
+	now := time.Now()
+	if !troopMgr.StartMarch(a, straightPath(0, 6, 2), stepLen, now) ||
+		!troopMgr.StartMarch(b, straightPath(6, 0, 2), stepLen, now) {
+		t.Fatal("行军失败")
+	}
+
+	predicted := wm.GetInterceptionDetector().PredictEncounters(a.GetId())
+	if len(predicted) != 1 || predicted[0].Type != EncounterType_March {
+		t.Fatalf("预测遭遇数量错误：期望 1, 得到 %d", len(predicted))
+	}
+	expected := (6*stepLen - radius) / (2 * stepLen)
+	if got := predicted[0].Time.Sub(now).Seconds(); absFloat(got-expected) > 0.001 {
+		t.Errorf("相遇时间错误：期望 %.3f, 得到 %.3f", expected, got)
+	}
+	centerX, _ := wm.GetHexGridManager().GetLayout().HexToWorld(geo.NewHexCoord(3, 2))
+	if absFloat(predicted[0].X-centerX) > 0.01 {
+		t.Errorf("相遇位置错误：期望 %.2f, 得到 %.2f", centerX, predicted[0].X)
+	}
+
+	wm.Update(now.Add(2 * time.Second))
+	if len(encounters) != 0 {
+		t.Errorf("相遇之前不应该上报：得到 %d", len(encounters))
+	}
+	wm.Update(now.Add(2800 * time.Millisecond))
+	wm.Update(now.Add(2900 * time.Millisecond))
+	if len(encounters) != 1 {
+		t.Fatalf("相遇上报次数错误：期望 1, 得到 %d", len(encounters))
+	}
+	if encounters[0].TroopId != a.GetId() || encounters[0].OtherId != b.GetId() {
+		t.Error("相遇双方错误")
+	}
+	troopMgr.RemoveTroop(a.GetId())
+	troopMgr.RemoveTroop(b.GetId())
+
+	// 经过敌方主城所在的六边形
+	cityHex := geo.NewHexCoord(2, 4)
+	wm.GetHexGridManager().AddUnitToGrid(&TestUnit{
+		id:       1,
+		hexCoord: cityHex,
+		unitType: MapUnitType_PlayerCity,
+		owner:    NewPlayerOwner(3),
+	}, cityHex)
+	c := troopMgr.CreateTroop(1, NewPlayerOwner(1), wm.hexToCoord(geo.NewHexCoord(0, 4)))
+	now = now.Add(time.Minute)
+	if !troopMgr.StartMarch(c, straightPath(0, 4, 4), stepLen, now) {
+		t.Fatal("行军失败")
+	}
+	encounters = encounters[:0]
+	wm.Update(now.Add(4 * time.Second))
+	if len(encounters) != 1 || encounters[0].Type != EncounterType_Territory {
+		t.Fatalf("经过敌方六边形上报错误：期望 1, 得到 %d", len(encounters))
+	}
+	if !encounters[0].Hex.Equal(cityHex) {
+		t.Errorf("遭遇六边形错误：期望 %s, 得到 %s", cityHex, encounters[0].Hex)
+	}
+	if got := encounters[0].Time.Sub(now).Seconds(); absFloat(got-1.5) > 0.001 {
+		t.Errorf("进入时间错误：期望 1.5, 得到 %.3f", got)
+	}
+}
+
+// TestMarchInterceptionCache 测试行军变化和关系变化后重新计算相遇，已经过的六边形不再检查
+func TestMarchInterceptionCache(t *testing.T) {
+	wm := NewWorldMap(newTestMapConfig())
+	troopMgr := wm.GetTroopManager()
+	detector := wm.GetInterceptionDetector()
+	stepLen := math.Sqrt(3) * wm.GetHexGridManager().GetLayout().Radius
+
+	a := troopMgr.CreateTroop(1, NewPlayerOwner(1), wm.hexToCoord(geo.NewHexCoord(0, 2)))
+	b := troopMgr.CreateTroop(1, NewPlayerOwner(2), wm.hexToCoord(geo.NewHexCoord(6, 2)))
+	now := time.Now()
+	if !troopMgr.MarchTo(a, geo.NewHexCoord(6, 2), stepLen, now) || !troopMgr.MarchTo(b, geo.NewHexCoord(0, 2), stepLen, now) {
+		t.Fatal("行军失败")
+	}
+	if len(detector.PredictEncounters(a.GetId())) != 1 {
+		t.Fatal("敌对行军应该相遇")
+	}
+
+	// 结盟后不再相遇，解除后恢复
+	registry := wm.GetRelationRegistry()
+	registry.SetPlayerAlliance(1, 100)
+	registry.SetPlayerAlliance(2, 100)
+	if len(detector.PredictEncounters(a.GetId())) != 0 {
+		t.Error("同盟行军不应该相遇")
+	}
+	registry.SetPlayerAlliance(2, 0)
+	if len(detector.PredictEncounters(a.GetId())) != 1 {
+		t.Error("解除同盟后应该重新相遇")
+	}
+
+	// 召回后重新计算：b 在 a 靠近之前已经回到出发点
+	if !troopMgr.Recall(b, now.Add(time.Second)) {
+		t.Fatal("召回失败")
+	}
+	if len(detector.PredictEncounters(a.GetId())) != 0 {
+		t.Error("召回后不应该相遇")
+	}
+
+	// 六边形在部队经过之后才变为敌方控制，不再上报
+	enemyHex := geo.NewHexCoord(1, 2)
+	enemy := false
+	detector.SetHexController(func(hex *geo.HexCoord) *Owner {
+		if enemy && hex.Equal(enemyHex) {
+			return NewPlayerOwner(3)
+		}
+		return nil
+	})
+	encounters := make([]*MarchEncounter, 0)
+	detector.AddEncounterCallback(func(encounter *MarchEncounter) {
+		encounters = append(encounters, encounter)
+	})
+	wm.Update(now.Add(2 * time.Second))
+	enemy = true
+	if len(detector.PredictEncounters(a.GetId())) != 1 {
+		t.Error("预测应该包含敌方控制的六边形")
+	}
+	wm.Update(now.Add(3 * time.Second))
+	if len(encounters) != 0 {
+		t.Errorf("已经过的六边形不应该上报：得到 %d", len(encounters))
+	}
+}
+
+// TestTroopMarchControl 测试行军加速、召回和改道
+func TestTroopMarchControl(t *testing.T) {
+	wm := NewWorldMap(newTestMapConfig())
+	troopMgr := wm.GetTroopManager()
+	stepLen := math.Sqrt(3) * wm.GetHexGridManager().GetLayout().Radius
+
+	notifyCount := 0
+	wm.GetObserverManager().AddObserver(1, geo.NewRectangle(0, 0, 1000, 1000), 0)
+	wm.GetObserverManager().AddMarchChangeNotifier(func(observer *Observer, troop *TroopUnit) {
+		notifyCount++
+	})
+
+	home := geo.NewHexCoord(0, 1)
+	troop := troopMgr.CreateTroop(1, NewPlayerOwner(1), wm.hexToCoord(home))
+	now := time.Now()
+	if !troopMgr.MarchTo(troop, geo.NewHexCoord(6, 1), stepLen, now) {
+		t.Fatal("行军失败")
+	}
+
+	// 1.4 秒时位于 (1,1)->(2,1) 的 0.4 处，加速后剩余 4.6 格按每秒 2 格计算
+	if !troopMgr.SpeedUp(troop, 2, now.Add(1400*time.Millisecond)) {
+		t.Fatal("加速失败")
+	}
+	if got := troop.GetArriveTime().Sub(now).Seconds(); absFloat(got-3.7) > 0.001 {
+		t.Errorf("加速后到达时间错误：期望 3.7, 得到 %.3f", got)
+	}
+
+	// 2.0 秒时位于 (2,1)->(3,1) 的 0.6 处，召回后剩余 2.6 格
+	recallTime := now.Add(2 * time.Second)
+	x, y := troop.GetPositionAt(recallTime)
+	if !troopMgr.Recall(troop, recallTime) {
+		t.Fatal("召回失败")
+	}
+	if !troop.IsReturning() || !troop.GetTarget().Equal(home) {
+		t.Errorf("召回后应该返回起点：得到 %s", troop.GetTarget())
+	}
+	if rx, ry := troop.GetPositionAt(recallTime); absFloat(rx-x) > 0.01 || absFloat(ry-y) > 0.01 {
+		t.Error("召回应该从当前插值位置出发")
+	}
+	if got := troop.GetArriveTime().Sub(now).Seconds(); absFloat(got-3.3) > 0.001 {
+		t.Errorf("召回后到达时间错误：期望 3.3, 得到 %.3f", got)
+	}
+	if troopMgr.Recall(troop, recallTime) {
+		t.Error("返程中不应该可以再次召回")
+	}
+	if troopMgr.Redirect(troop, geo.NewHexCoord(2, 5), recallTime) || !troop.IsReturning() {
+		t.Error("返程中不应该可以改道")
+	}
+
+	wm.Update(now.Add(4 * time.Second))
+	if troop.IsMarching() || !troop.GetHexCoord().Equal(home) {
+		t.Errorf("召回后应该停在起点：得到 %s", troop.GetHexCoord())
+	}
+
+	// 2.0 秒时恰好位于 (2,3) 中心，改道到 (2,5)
+	other := troopMgr.CreateTroop(1, NewPlayerOwner(1), wm.hexToCoord(geo.NewHexCoord(0, 3)))
+	if !troopMgr.MarchTo(other, geo.NewHexCoord(6, 3), stepLen, now) {
+		t.Fatal("行军失败")
+	}
+	target := geo.NewHexCoord(2, 5)
+	if !troopMgr.Redirect(other, target, now.Add(2*time.Second)) {
+		t.Fatal("改道失败")
+	}
+	if !other.GetTarget().Equal(target) {
+		t.Errorf("改道目标错误：期望 %s, 得到 %s", target, other.GetTarget())
+	}
+	if got := other.GetArriveTime().Sub(now).Seconds(); absFloat(got-4) > 0.001 {
+		t.Errorf("改道后到达时间错误：期望 4, 得到 %.3f", got)
+	}
+
+	if notifyCount != 3 {
+		t.Errorf("轨迹变化通知次数错误：期望 3, 得到 %d", notifyCount)
+	}
+}
+
+// TestMarchResolve 测试行军到达后按意图结算以及失败返回
+func TestMarchResolve(t *testing.T) {
+	mapConfig := newTestMapConfig()
+	mapConfig.Npcs = []config.NpcConfig{{NpcID: 1, MinLevel: 1, MaxLevel: 10}}
+	wm := NewWorldMap(mapConfig)
+	troopMgr := wm.GetTroopManager()
+	stepLen := math.Sqrt(3) * wm.GetHexGridManager().GetLayout().Radius
+
+	resolved := make([]Unit, 0)
+	wm.RegisterMarchResolver(MarchIntent_Attack, MarchResolverFunc(func(wm *WorldMap, troop *TroopUnit, target Unit, now time.Time) MarchOutcome {
+		resolved = append(resolved, target)
+		return MarchOutcome_Return
+	}))
+	failReasons := make([]MarchFailReason, 0)
+	wm.GetMarchResolverRegistry().AddFailCallback(func(troop *TroopUnit, reason MarchFailReason, now time.Time) {
+		failReasons = append(failReasons, reason)
+	})
+
+	npc := wm.NewNpcTroop(1, 1, wm.hexToCoord(geo.NewHexCoord(3, 1)))
+	if npc == nil {
+		t.Fatal("创建NPC失败")
+	}
+	home := geo.NewHexCoord(0, 1)
+	troop := troopMgr.CreateTroop(1, NewPlayerOwner(1), wm.hexToCoord(home))
+
+	if troopMgr.DispatchToUnit(troop, MarchIntent_Gather, npc.GetId(), stepLen, time.Now()) {
+		t.Error("不应该可以采集NPC")
+	}
+
+	now := time.Now()
+	if !troopMgr.DispatchToUnit(troop, MarchIntent_Attack, npc.GetId(), stepLen, now) {
+		t.Fatal("出征失败")
+	}
+	wm.Update(now.Add(3 * time.Second))
+	if len(resolved) != 1 || resolved[0] != npc {
+		t.Fatalf("攻击结算次数错误：期望 1, 得到 %d", len(resolved))
+	}
+	if !troop.IsReturning() || !troop.GetTarget().Equal(home) {
+		t.Error("结算后部队应该返回驻地")
+	}
+	wm.Update(now.Add(6 * time.Second))
+	if troop.IsMarching() || !troop.GetHexCoord().Equal(home) || troop.GetIntent() != MarchIntent_None {
+		t.Error("部队应该回到驻地并清除意图")
+	}
+
+	// 目标在到达前消失
+	now = now.Add(time.Minute)
+	if !troopMgr.DispatchToUnit(troop, MarchIntent_Attack, npc.GetId(), stepLen, now) {
+		t.Fatal("出征失败")
+	}
+	wm.RemoveUnit(npc)
+	wm.Update(now.Add(3 * time.Second))
+	if len(resolved) != 1 {
+		t.Error("目标消失后不应该调用结算器")
+	}
+	if len(failReasons) != 1 || failReasons[0] != MarchFailReason_TargetGone {
+		t.Errorf("失败原因错误：得到 %v", failReasons)
+	}
+	if !troop.IsReturning() {
+		t.Error("结算失败后部队应该返回驻地")
+	}
+}
+
+// TestMarchRedirect 测试改道后按新的目标结算
+func TestMarchRedirect(t *testing.T) {
+	mapConfig := newTestMapConfig()
+	mapConfig.Npcs = []config.NpcConfig{{NpcID: 1, MinLevel: 1, MaxLevel: 10}}
+	wm := NewWorldMap(mapConfig)
+	troopMgr := wm.GetTroopManager()
+	stepLen := math.Sqrt(3) * wm.GetHexGridManager().GetLayout().Radius
+
+	resolved := make([]Unit, 0)
+	wm.RegisterMarchResolver(MarchIntent_Attack, MarchResolverFunc(func(wm *WorldMap, troop *TroopUnit, target Unit, now time.Time) MarchOutcome {
+		resolved = append(resolved, target)
+		return MarchOutcome_Stay
+	}))
+	failReasons := make([]MarchFailReason, 0)
+	wm.GetMarchResolverRegistry().AddFailCallback(func(troop *TroopUnit, reason MarchFailReason, now time.Time) {
+		failReasons = append(failReasons, reason)
+	})
+
+	npc := wm.NewNpcTroop(1, 1, wm.hexToCoord(geo.NewHexCoord(3, 1)))
+	other := wm.NewNpcTroop(1, 1, wm.hexToCoord(geo.NewHexCoord(1, 3)))
+	if npc == nil || other == nil {
+		t.Fatal("创建NPC失败")
+	}
+	troop := troopMgr.CreateTroop(1, NewPlayerOwner(1), wm.hexToCoord(geo.NewHexCoord(0, 1)))
+
+	// 改道攻击另一个NPC，到达后按新目标结算
+	now := time.Now()
+	if !troopMgr.DispatchToUnit(troop, MarchIntent_Attack, npc.GetId(), stepLen, now) {
+		t.Fatal("出征失败")
+	}
+	if troopMgr.RedirectToUnit(troop, MarchIntent_Gather, other.GetId(), now.Add(time.Second)) {
+		t.Error("不应该可以改道采集NPC")
+	}
+	if !troopMgr.RedirectToUnit(troop, MarchIntent_Attack, other.GetId(), now.Add(time.Second)) {
+		t.Fatal("改道失败")
+	}
+	if troop.GetTargetId() != other.GetId() || !troop.GetTarget().Equal(other.GetHexCoord()) {
+		t.Error("改道后应该前往新的目标单位")
+	}
+	wm.Update(now.Add(time.Minute))
+	if len(resolved) != 1 || resolved[0] != other || len(failReasons) != 0 {
+		t.Fatalf("改道后应该结算新的目标：结算 %d 次, 失败 %v", len(resolved), failReasons)
+	}
+	if troop.IsMarching() || !troop.GetHexCoord().Equal(other.GetHexCoord()) {
+		t.Error("结算后部队应该留在目标处")
+	}
+
+	// 改道前往空地，需要目标单位的意图清除为单纯移动
+	now = now.Add(time.Minute)
+	if !troopMgr.DispatchToUnit(troop, MarchIntent_Attack, npc.GetId(), stepLen, now) {
+		t.Fatal("出征失败")
+	}
+	empty := geo.NewHexCoord(1, 1)
+	if !troopMgr.Redirect(troop, empty, now.Add(time.Second)) {
+		t.Fatal("改道失败")
+	}
+	if troop.GetIntent() != MarchIntent_None || troop.GetTargetId() != 0 {
+		t.Error("改道前往空地后应该清除攻击意图和目标")
+	}
+	wm.Update(now.Add(time.Minute))
+	if len(resolved) != 1 || len(failReasons) != 0 {
+		t.Errorf("改道前往空地后不应该结算：结算 %d 次, 失败 %v", len(resolved), failReasons)
+	}
+	if troop.IsMarching() || !troop.GetHexCoord().Equal(empty) {
+		t.Error("部队应该停在改道的六边形")
+	}
+
+	// 不需要目标单位的意图改道后保留
+	now = now.Add(time.Minute)
+	if !troopMgr.DispatchToHex(troop, MarchIntent_Scout, geo.NewHexCoord(3, 3), stepLen, now) {
+		t.Fatal("出征侦察失败")
+	}
+	if !troopMgr.Redirect(troop, geo.NewHexCoord(3, 2), now.Add(time.Second)) || troop.GetIntent() != MarchIntent_Scout {
+		t.Error("改道后应该保留侦察意图")
+	}
+}
+
+// TestTroopGather 测试部队占领资源点按速度采集，负重满后释放资源点并返回驻地
+func TestTroopGather(t *testing.T) {
+	mapConfig := newTestMapConfig()
+	wm := NewWorldMap(mapConfig)
+	resourceHex := geo.NewHexCoord(3, 1)
+	coord := wm.hexToCoord(resourceHex)
+	mapConfig.EnhancedResourcePoints = []config.EnhancedResourcePointConfig{
+		{PointID: 1, X: coord.X, Y: coord.Y, ResourceType: "wood", MaxAmount: 100, CurrentAmount: 100},
+	}
+	wm.GetResourceManager().LoadConfig(mapConfig)
+	troopMgr := wm.GetTroopManager()
+	stepLen := math.Sqrt(3) * wm.GetHexGridManager().GetLayout().Radius
+
+	units := wm.GetHexGridManager().GetGrid(resourceHex).GetUnitsByType(MapUnitType_Resource)
+	if len(units) != 1 {
+		t.Fatalf("资源点应该加入六边形网格：得到 %d", len(units))
+	}
+	resource := units[0].(*ResourceUnit)
+
+	gathered := int32(0)
+	wm.GetResourceManager().AddGatherFinishCallback(func(troop *TroopUnit, resource *ResourceUnit, amount int32, now time.Time) {
+		gathered += amount
+	})
+
+	home := geo.NewHexCoord(0, 1)
+	troop := troopMgr.CreateTroop(1, NewPlayerOwner(1), wm.hexToCoord(home))
+	troop.SetGatherAbility(50, 10)
+	now := time.Now()
+	if !troopMgr.DispatchToUnit(troop, MarchIntent_Gather, resource.GetId(), stepLen, now) {
+		t.Fatal("出征采集失败")
+	}
+
+	wm.Update(now.Add(3 * time.Second))
+	if !troop.IsGathering() || resource.GetOccupierId() != troop.GetId() {
+		t.Fatal("到达后部队应该占领资源点开始采集")
+	}
+	if resource.GetOccupierOwner() != troop.GetOwner() || !isSystemOwner(resource.GetOwner()) {
+		t.Error("被占领的资源点应该显示占领者，所有者仍为系统")
+	}
+
+	// 其他部队不能前往已被占领的资源点采集
+	other := troopMgr.CreateTroop(1, NewPlayerOwner(2), wm.hexToCoord(geo.NewHexCoord(0, 3)))
+	other.SetGatherAbility(50, 10)
+	if troopMgr.DispatchToUnit(other, MarchIntent_Gather, resource.GetId(), stepLen, now) {
+		t.Error("资源点已被占领时不应该可以出征采集")
+	}
+
+	wm.Update(now.Add(5500 * time.Millisecond))
+	if _, load := troop.GetLoad(); load != 25 {
+		t.Errorf("采集量错误：期望 25, 得到 %d", load)
+	}
+
+	wm.Update(now.Add(10 * time.Second))
+	if _, load := troop.GetLoad(); load != 50 || gathered != 50 {
+		t.Errorf("负重满后采集量错误：期望 50, 得到 %d, %d", load, gathered)
+	}
+	if resource.IsOccupied() || resource.GetCurrentAmount() != 50 {
+		t.Error("采集结束后应该释放资源点并扣除资源")
+	}
+	if !troop.IsReturning() || !troop.GetTarget().Equal(home) {
+		t.Error("采集结束后部队应该返回驻地")
+	}
+
+	// 移除资源点时按传入的时间结算采集中的部队
+	later := now.Add(10 * time.Second)
+	if !troopMgr.DispatchToUnit(other, MarchIntent_Gather, resource.GetId(), stepLen, later) {
+		t.Fatal("资源点释放后应该可以出征采集")
+	}
+	arrive := other.GetArriveTime()
+	wm.Update(arrive)
+	if !other.IsGathering() {
+		t.Fatal("到达后部队应该开始采集")
+	}
+	wm.GetResourceManager().RemoveResourceAt(resource.GetId(), arrive.Add(2*time.Second))
+	if gathered != 70 {
+		t.Errorf("移除资源点时的结算错误：期望 70, 得到 %d", gathered)
+	}
+}
@@ -16,12 +16,13 @@ type Unit interface {
 
 // BaseUnit 基础单位实现，提供通用的坐标管理
 type BaseUnit struct {
-	id       int64
-	configId int32
-	coord    geo.Coord
-	hexCoord *geo.HexCoord
-	unitType MapUnitType
-	owner    *Owner
+	id        int64
+	configId  int32
+	coord     geo.Coord
+	hexCoord  *geo.HexCoord
+	unitType  MapUnitType
+	owner     *Owner
+	footprint *Footprint // 占地范围（nil表示只占一个坐标）
 }
 
 // NewBaseUnit 创建基础单位
@@ -75,3 +76,13 @@ func (b *BaseUnit) GetType() MapUnitType {
 func (b *BaseUnit) GetOwner() *Owner {
 	return b.owner
 }
+
+// GetFootprint 获取占地范围
+func (b *BaseUnit) GetFootprint() *Footprint {
+	return b.footprint
+}
+
+// SetFootprint 设置占地范围（需要在单位加入地图前设置）
+func (b *BaseUnit) SetFootprint(footprint *Footprint) {
+	b.footprint = footprint
+}
@@ -0,0 +1,76 @@
+package worldmap
+
+// UnitFilter 空间查询的过滤条件，在网格遍历中直接过滤，nil 表示不过滤
+type UnitFilter struct {
+	viewer      *Owner               // 观察者，按关系过滤时使用
+	registry    *RelationRegistry    // 关系注册表
+	relations   uint64               // 允许的关系集合（位掩码，0表示不限）
+	anyRelation bool                 // 包含 Relation_All，不按关系过滤
+	unitTypes   uint64               // 允许的单位类型集合（位掩码，0表示不限）
+	predicate   func(unit Unit) bool // 自定义条件
+}
+
+// NewUnitFilter 创建过滤条件，registry 和 viewer 用于关系过滤
+func NewUnitFilter(registry *RelationRegistry, viewer *Owner) *UnitFilter {
+	return &UnitFilter{
+		viewer:   viewer,
+		registry: registry,
+	}
+}
+
+// WithRelations 只保留与观察者为指定关系的单位（包含 Relation_All 时不按关系过滤）
+func (f *UnitFilter) WithRelations(relations ...Relation) *UnitFilter {
+	for _, relation := range relations {
+		if relation == Relation_All {
+			f.anyRelation = true
+			continue
+		}
+		f.relations |= 1 << uint(relation)
+	}
+	return f
+}
+
+// WithUnitTypes 只保留指定类型的单位
+func (f *UnitFilter) WithUnitTypes(unitTypes ...MapUnitType) *UnitFilter {
+	for _, unitType := range unitTypes {
+		f.unitTypes |= 1 << uint(unitType)
+	}
+	return f
+}
+
+// WithPredicate 追加自定义条件，与已有的自定义条件同时满足才保留
+func (f *UnitFilter) WithPredicate(predicate func(unit Unit) bool) *UnitFilter {
+	if previous := f.predicate; previous != nil {
+		f.predicate = func(unit Unit) bool {
+			return previous(unit) && predicate(unit)
+		}
+		return f
+	}
+	f.predicate = predicate
+	return f
+}
+
+// HasUnitType 过滤条件是否允许指定类型
+func (f *UnitFilter) HasUnitType(unitType MapUnitType) bool {
+	return f == nil || f.unitTypes == 0 || f.unitTypes&(1<<uint(unitType)) != 0
+}
+
+// Match 单位是否满足过滤条件（先判断开销小的类型，再判断关系和自定义条件）
+func (f *UnitFilter) Match(unit Unit) bool {
+	if f == nil {
+		return true
+	}
+	if !f.HasUnitType(unit.GetType()) {
+		return false
+	}
+	if f.relations != 0 && !f.anyRelation {
+		if f.registry == nil {
+			return false
+		}
+		relation := f.registry.GetRelation(f.viewer, unit.GetOwner())
+		if f.relations&(1<<uint(relation)) == 0 {
+			return false
+		}
+	}
+	return f.predicate == nil || f.predicate(unit)
+}